@@ -10,11 +10,13 @@ import (
 
 type Config struct {
 	App       appconfig.App
+	Tracing   appconfig.Tracing
 	HTTP      HTTP
 	GRPC      GRPC
 	Postgres  Postgres
 	Migration Migration
 	JWTConfig JWTConfig
+	Admin     Admin
 }
 
 type HTTP struct {
@@ -45,6 +47,11 @@ type JWTConfig struct {
 	RefreshTokenExpire uint
 }
 
+// Admin are the emails of the accounts promoted to the admin role when the service starts
+type Admin struct {
+	Emails []string
+}
+
 func LoadConfig(filename string) (*viper.Viper, error) {
 	v := viper.New()
 
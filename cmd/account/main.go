@@ -3,15 +3,20 @@ package main
 import (
 	"context"
 	"github.com/scul0405/saga-orchestration/cmd/account/config"
+	"github.com/scul0405/saga-orchestration/internal/account/domain/valueobject"
 	"github.com/scul0405/saga-orchestration/internal/account/infrastructure/db/postgres"
 	portgrpc "github.com/scul0405/saga-orchestration/internal/account/interface/grpc"
 	porthttp "github.com/scul0405/saga-orchestration/internal/account/interface/http"
 	"github.com/scul0405/saga-orchestration/internal/account/repository/postgres_repo"
 	customersvc "github.com/scul0405/saga-orchestration/internal/account/service/account"
 	authsvc "github.com/scul0405/saga-orchestration/internal/account/service/auth"
+	"github.com/scul0405/saga-orchestration/internal/pkg/health"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
 	"github.com/scul0405/saga-orchestration/pkg/pgconn"
 	"github.com/scul0405/saga-orchestration/pkg/sonyflake"
+	"github.com/scul0405/saga-orchestration/pkg/tracing"
+	pb "github.com/scul0405/saga-orchestration/proto"
+	grpchealth "google.golang.org/grpc/health"
 	"log"
 	"os"
 	"os/signal"
@@ -44,6 +49,17 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 	defer cancel()
 
+	// init tracing
+	tp, err := tracing.NewTracerProvider(ctx, "account_service", cfg.Tracing)
+	if err != nil {
+		apiLogger.Fatal(err)
+	}
+	defer func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			apiLogger.Errorf("Shutdown tracer provider err: %v", err)
+		}
+	}()
+
 	// connect postgres
 	psqlDB, err := pgconn.NewPsqlDB(cfg.Postgres.DnsURL)
 	if err != nil {
@@ -69,6 +85,15 @@ func main() {
 	customerRepo := postgres_repo.NewCustomerRepositoryImpl(psqlDB)
 	jwtAuthRepo := postgres_repo.NewJwtAuthRepositoryImpl(psqlDB)
 
+	// promote the configured admin accounts
+	if len(cfg.Admin.Emails) > 0 {
+		granted, err := jwtAuthRepo.GrantRole(ctx, cfg.Admin.Emails, valueobject.RoleAdmin)
+		if err != nil {
+			apiLogger.Fatal(err)
+		}
+		apiLogger.Infof("Granted the admin role to %d of %d configured accounts", granted, len(cfg.Admin.Emails))
+	}
+
 	// create sony flake
 	sf, err := sonyflake.NewSonyFlake()
 	if err != nil {
@@ -79,10 +104,14 @@ func main() {
 	customerService := customersvc.NewCustomerService(customerRepo, apiLogger)
 	authService := authsvc.NewJWTAuthService(cfg.JWTConfig, jwtAuthRepo, apiLogger, sf)
 
+	// create health checks
+	checker := health.NewChecker()
+	checker.Add("postgres", health.Postgres(psqlDB))
+
 	// create http server
 	engine := porthttp.NewEngine(cfg.HTTP)
 	router := porthttp.NewRouter(authService, customerService)
-	httpServer := porthttp.NewHTTPServer(cfg.HTTP, apiLogger, engine, router)
+	httpServer := porthttp.NewHTTPServer(cfg.HTTP, apiLogger, engine, router, checker)
 
 	// run http server
 	go func() {
@@ -92,7 +121,9 @@ func main() {
 	}()
 
 	// create grpc server
-	grpcServer := portgrpc.NewGRPCServer(cfg.GRPC, authService)
+	healthServer := grpchealth.NewServer()
+	go checker.Watch(ctx, healthServer, pb.AuthService_ServiceDesc.ServiceName)
+	grpcServer := portgrpc.NewGRPCServer(cfg.GRPC, authService, healthServer)
 
 	// run grpc server
 	go func() {
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
+	"github.com/segmentio/kafka-go"
+	"os"
+	"time"
+)
+
+func runList(args []string) error {
+	var o options
+	var showPayload bool
+
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	o.register(fs)
+	fs.BoolVar(&showPayload, "payload", true, "print the decoded payload of each dead letter")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+
+	entries, err := readEntries(ctx, &o)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		printEntry(&e, showPayload)
+	}
+	fmt.Printf("%d dead letter(s)\n", len(entries))
+
+	return nil
+}
+
+func runReplay(args []string) error {
+	var o options
+	var all, dryRun bool
+
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	o.register(fs)
+	fs.BoolVar(&all, "all", false, "replay every dead letter when no filter is given")
+	fs.BoolVar(&dryRun, "dry-run", false, "print the dead letters which would be replayed without publishing them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if !o.hasFilter() && !all {
+		return errors.New("refusing to replay the whole queue, give a filter or -all")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+
+	entries, err := readEntries(ctx, &o)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		for _, e := range entries {
+			printEntry(&e, false)
+		}
+		fmt.Printf("%d dead letter(s) would be replayed\n", len(entries))
+		return nil
+	}
+
+	w := kafkaClient.NewKafkaWriter(o.brokerList())
+	defer w.Close()
+
+	for _, e := range entries {
+		// The original message keeps its key and headers, only the dead letter headers are dropped
+		msg := kafka.Message{
+			Topic:   e.deadLetter.Message.Topic,
+			Key:     e.deadLetter.Message.Key,
+			Value:   e.deadLetter.Message.Value,
+			Headers: e.deadLetter.Message.Headers,
+		}
+		if err = w.WriteMessages(ctx, msg); err != nil {
+			return fmt.Errorf("replay partition %d offset %d: %w", e.partition, e.offset, err)
+		}
+		fmt.Printf("replayed partition %d offset %d to %s\n", e.partition, e.offset, msg.Topic)
+	}
+	fmt.Printf("%d dead letter(s) replayed\n", len(entries))
+
+	return nil
+}
+
+func printEntry(e *entry, showPayload bool) {
+	dl := e.deadLetter
+
+	fmt.Printf("[partition %d offset %d] purchase %d\n", e.partition, e.offset, e.purchaseID)
+	fmt.Printf("  source:    %s/%d/%d\n", dl.SourceTopic, dl.SourcePartition, dl.SourceOffset)
+	fmt.Printf("  worker:    %s\n", dl.Worker)
+	fmt.Printf("  attempts:  %d\n", dl.Attempts)
+	fmt.Printf("  failed at: %s\n", dl.FailedAt.Format(time.RFC3339))
+	fmt.Printf("  error:     %s\n", dl.Error)
+	for _, header := range dl.Message.Headers {
+		fmt.Printf("  header:    %s=%s\n", header.Key, string(header.Value))
+	}
+
+	if !showPayload {
+		return
+	}
+
+	if e.payload == nil {
+		fmt.Printf("  payload (undecodable): %s\n", string(dl.Message.Value))
+		return
+	}
+
+	payload, err := json.MarshalIndent(e.payload, "  ", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  payload: %v\n", err)
+		return
+	}
+	fmt.Printf("  payload: %s\n", string(payload))
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/scul0405/saga-orchestration/internal/common"
+	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
+	pb "github.com/scul0405/saga-orchestration/proto"
+	"github.com/segmentio/kafka-go"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// options are the flags shared by every command
+type options struct {
+	brokers     string
+	topic       string
+	timeout     time.Duration
+	sourceTopic string
+	purchaseID  uint64
+	errorText   string
+	positions   positions
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.brokers, "brokers", "localhost:9091", "comma separated list of kafka brokers")
+	fs.StringVar(&o.topic, "topic", kafkaClient.DeadLetterQueueTopic, "dead letter queue topic")
+	fs.DurationVar(&o.timeout, "timeout", 30*time.Second, "timeout of the whole command")
+	fs.StringVar(&o.sourceTopic, "source-topic", "", "only dead letters consumed from this topic")
+	fs.Uint64Var(&o.purchaseID, "purchase-id", 0, "only dead letters of this purchase")
+	fs.StringVar(&o.errorText, "error", "", "only dead letters whose error contains this text")
+	fs.Var(&o.positions, "offset", "only the dead letter at partition:offset of the dead letter queue, can be repeated")
+}
+
+func (o *options) brokerList() []string {
+	return strings.Split(o.brokers, ",")
+}
+
+func (o *options) hasFilter() bool {
+	return o.sourceTopic != "" || o.purchaseID != 0 || o.errorText != "" || len(o.positions) != 0
+}
+
+// entry is a dead letter read from the queue
+type entry struct {
+	partition  int
+	offset     int64
+	deadLetter *kafkaClient.DeadLetter
+	purchaseID uint64
+	payload    interface{}
+}
+
+// readEntries reads the dead letter queue and returns the entries matching the filters
+func readEntries(ctx context.Context, o *options) ([]entry, error) {
+	var entries []entry
+
+	err := kafkaClient.ReadTopic(ctx, o.brokerList(), o.topic, func(m kafka.Message) error {
+		dl, err := kafkaClient.DecodeDeadLetter(m)
+		if err != nil {
+			return fmt.Errorf("partition %d offset %d: %w", m.Partition, m.Offset, err)
+		}
+
+		e := entry{
+			partition:  m.Partition,
+			offset:     m.Offset,
+			deadLetter: dl,
+		}
+		e.purchaseID, e.payload = decodePayload(dl)
+
+		if o.match(&e) {
+			entries = append(entries, e)
+		}
+		return nil
+	})
+
+	return entries, err
+}
+
+func (o *options) match(e *entry) bool {
+	if o.sourceTopic != "" && e.deadLetter.SourceTopic != o.sourceTopic {
+		return false
+	}
+	if o.purchaseID != 0 && e.purchaseID != o.purchaseID {
+		return false
+	}
+	if o.errorText != "" && !strings.Contains(e.deadLetter.Error, o.errorText) {
+		return false
+	}
+	if len(o.positions) != 0 && !o.positions.contains(e.partition, e.offset) {
+		return false
+	}
+	return true
+}
+
+// decodePayload decodes the saga message of a dead letter according to its content type, replies are decoded as
+// pb.CreatePurchaseResponse, purchase results as pb.PurchaseResult and commands as pb.CreatePurchaseRequest.
+// The payload is nil when the message could not be decoded.
+func decodePayload(dl *kafkaClient.DeadLetter) (uint64, interface{}) {
+	switch dl.SourceTopic {
+	case common.ReplyTopic:
+		var reply pb.CreatePurchaseResponse
+		if err := kafkaClient.Decode(dl.Message, &reply); err != nil {
+			return 0, nil
+		}
+		return reply.PurchaseId, &reply
+	case common.PurchaseResultTopic:
+		var result pb.PurchaseResult
+		if err := kafkaClient.Decode(dl.Message, &result); err != nil {
+			return 0, nil
+		}
+		return result.PurchaseId, &result
+	}
+
+	var request pb.CreatePurchaseRequest
+	if err := kafkaClient.Decode(dl.Message, &request); err != nil {
+		return 0, nil
+	}
+	return request.PurchaseId, &request
+}
+
+type position struct {
+	partition int
+	offset    int64
+}
+
+// positions is a repeatable partition:offset flag
+type positions []position
+
+func (p *positions) String() string {
+	values := make([]string, len(*p))
+	for i, pos := range *p {
+		values[i] = fmt.Sprintf("%d:%d", pos.partition, pos.offset)
+	}
+	return strings.Join(values, ",")
+}
+
+func (p *positions) Set(value string) error {
+	partition, offset, ok := strings.Cut(value, ":")
+	if !ok {
+		return fmt.Errorf("invalid position %q, expected partition:offset", value)
+	}
+
+	pos := position{}
+	var err error
+	if pos.partition, err = strconv.Atoi(partition); err != nil {
+		return fmt.Errorf("invalid partition %q", partition)
+	}
+	if pos.offset, err = strconv.ParseInt(offset, 10, 64); err != nil {
+		return fmt.Errorf("invalid offset %q", offset)
+	}
+
+	*p = append(*p, pos)
+	return nil
+}
+
+func (p *positions) contains(partition int, offset int64) bool {
+	for _, pos := range *p {
+		if pos.partition == partition && pos.offset == offset {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"github.com/scul0405/saga-orchestration/internal/common"
+	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
+	pb "github.com/scul0405/saga-orchestration/proto"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"testing"
+)
+
+func TestPositions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value string
+		err   bool
+	}{
+		{value: "0:42"},
+		{value: "3:0"},
+		{value: "42", err: true},
+		{value: "a:1", err: true},
+		{value: "1:b", err: true},
+		{value: ":", err: true},
+	}
+
+	var p positions
+	for _, tt := range tests {
+		err := p.Set(tt.value)
+		if tt.err {
+			require.Error(t, err, tt.value)
+		} else {
+			require.NoError(t, err, tt.value)
+		}
+	}
+
+	require.Equal(t, "0:42,3:0", p.String())
+	require.True(t, p.contains(0, 42))
+	require.True(t, p.contains(3, 0))
+	require.False(t, p.contains(42, 0))
+}
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	e := &entry{
+		partition: 1,
+		offset:    7,
+		deadLetter: &kafkaClient.DeadLetter{
+			SourceTopic: common.CreateOrderTopic,
+			Error:       "failed after 5 attempts: timeout",
+		},
+		purchaseID: 123,
+	}
+
+	tests := []struct {
+		name    string
+		options options
+		match   bool
+	}{
+		{name: "no filter", options: options{}, match: true},
+		{name: "source topic", options: options{sourceTopic: common.CreateOrderTopic}, match: true},
+		{name: "other source topic", options: options{sourceTopic: common.ReplyTopic}, match: false},
+		{name: "purchase id", options: options{purchaseID: 123}, match: true},
+		{name: "other purchase id", options: options{purchaseID: 124}, match: false},
+		{name: "error", options: options{errorText: "timeout"}, match: true},
+		{name: "other error", options: options{errorText: "declined"}, match: false},
+		{name: "position", options: options{positions: positions{{partition: 1, offset: 7}}}, match: true},
+		{name: "other position", options: options{positions: positions{{partition: 0, offset: 7}}}, match: false},
+		{name: "all filters", options: options{sourceTopic: common.CreateOrderTopic, purchaseID: 123, errorText: "timeout"}, match: true},
+		{name: "one filter failing", options: options{sourceTopic: common.CreateOrderTopic, purchaseID: 124}, match: false},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.match, tt.options.match(e), tt.name)
+	}
+}
+
+func TestDecodePayload(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		sourceTopic string
+		message     proto.Message
+	}{
+		{sourceTopic: common.CreateOrderTopic, message: &pb.CreatePurchaseRequest{PurchaseId: 1}},
+		{sourceTopic: common.ReplyTopic, message: &pb.CreatePurchaseResponse{PurchaseId: 2, Success: true}},
+		{sourceTopic: common.PurchaseResultTopic, message: &pb.PurchaseResult{PurchaseId: 3, Success: true, StepName: "CREATE_ORDER"}},
+	}
+
+	for i, tt := range tests {
+		value, header, err := kafkaClient.Encode(kafkaClient.ContentTypeProtobuf, tt.message)
+		require.NoError(t, err)
+
+		purchaseID, payload := decodePayload(&kafkaClient.DeadLetter{
+			SourceTopic: tt.sourceTopic,
+			Message:     kafka.Message{Value: value, Headers: []kafka.Header{header}},
+		})
+		require.Equal(t, uint64(i+1), purchaseID, tt.sourceTopic)
+		require.True(t, proto.Equal(tt.message, payload.(proto.Message)), tt.sourceTopic)
+	}
+
+	purchaseID, payload := decodePayload(&kafkaClient.DeadLetter{
+		SourceTopic: common.PurchaseResultTopic,
+		Message:     kafka.Message{Value: []byte("{")},
+	})
+	require.Zero(t, purchaseID)
+	require.Nil(t, payload)
+}
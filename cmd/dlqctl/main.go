@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `dlqctl inspects and replays the messages of the dead letter queue.
+
+Usage:
+  dlqctl list   [flags]   list dead letters matching the filters
+  dlqctl replay [flags]   publish the matching dead letters back to their source topic
+
+Run "dlqctl <command> -h" to print the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "list":
+		err = runList(os.Args[2:])
+	case "replay":
+		err = runReplay(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "dlqctl %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
@@ -3,17 +3,52 @@ package config
 import (
 	"errors"
 	"github.com/scul0405/saga-orchestration/pkg/appconfig"
+	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
 	"github.com/spf13/viper"
 	"log"
 )
 
 type Config struct {
-	App   appconfig.App
-	Kafka Kafka
+	App         appconfig.App
+	Tracing     appconfig.Tracing
+	HTTP        HTTP
+	Postgres    Postgres
+	Migration   Migration
+	RpcEnpoints RpcEndpoints `mapstructure:"rpcEndpoints"`
+	Kafka       Kafka
+	Saga        Saga
 }
 
+// HTTP serves the admin API next to the metrics and health endpoints
+type HTTP struct {
+	Port string
+	Mode string
+}
+
+type Postgres struct {
+	DnsURL string `mapstructure:"DNS_URL"`
+}
+
+type Migration struct {
+	Enable   bool
+	Recreate bool
+}
+
+type RpcEndpoints struct {
+	AuthSvc string
+}
+
+// Kafka configures the brokers and how the consumed messages are retried before going to the dead letter queue
 type Kafka struct {
 	Brokers []string
+	Retry   kafkaClient.RetryConfig
+}
+
+// Saga configures the step deadlines, StepTimeout and SweepInterval are in seconds
+type Saga struct {
+	StepTimeout   uint64
+	MaxRetries    uint64
+	SweepInterval uint64
 }
 
 func LoadConfig(filename string) (*viper.Viper, error) {
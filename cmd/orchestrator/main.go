@@ -5,8 +5,16 @@ import (
 	"github.com/scul0405/saga-orchestration/cmd/orchestrator/config"
 	"github.com/scul0405/saga-orchestration/internal/orchestrator/app"
 	"github.com/scul0405/saga-orchestration/internal/orchestrator/eventhandler"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/infrastructure/db/postgres"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/infrastructure/grpc"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/interface/http"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/repository/pgrepo"
+	"github.com/scul0405/saga-orchestration/internal/pkg/grpcconn"
+	"github.com/scul0405/saga-orchestration/internal/pkg/health"
 	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"github.com/scul0405/saga-orchestration/pkg/pgconn"
+	"github.com/scul0405/saga-orchestration/pkg/tracing"
 	"log"
 	"os"
 	"os/signal"
@@ -20,6 +28,8 @@ const (
 
 func main() {
 	log.Println("Start orchestrator service...")
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
 
 	cfgFile, err := config.LoadConfig("./config/config")
 	if err != nil {
@@ -35,30 +45,102 @@ func main() {
 	apiLogger.InitLogger()
 	apiLogger.Infof("Service Name: %s, LogLevel: %s, Mode: %s", cfg.App.Service.Name, cfg.App.Logger.Level, cfg.App.Service.Mode)
 
+	// init tracing
+	tp, err := tracing.NewTracerProvider(ctx, "orchestrator_service", cfg.Tracing)
+	if err != nil {
+		apiLogger.Fatal(err)
+	}
+	defer func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			apiLogger.Errorf("Shutdown tracer provider err: %v", err)
+		}
+	}()
+
+	// connect postgres
+	psqlDB, err := pgconn.NewPsqlDB(cfg.Postgres.DnsURL)
+	if err != nil {
+		apiLogger.Fatal(err)
+	}
+	defer func() {
+		db, err := psqlDB.DB()
+		if err = db.Close(); err != nil {
+			apiLogger.Errorf("Close db err: %v", err)
+		}
+	}()
+
+	// run migration
+	apiLogger.Infof("Run migrations with config: %+v", cfg.Migration)
+	err = postgres.NewMigrator(psqlDB).Migrate(cfg.Migration)
+	if err != nil {
+		apiLogger.Errorf("RunMigrations err: %v", err)
+		apiLogger.Fatal(err)
+	}
+	apiLogger.Info("Migrations successfully")
+
+	// create repositories
+	sagaRepo := pgrepo.NewSagaRepository(psqlDB)
+
+	// create grpc clients
+	authClientConn, err := grpcconn.NewGRPCClientConn(cfg.RpcEnpoints.AuthSvc)
+	if err != nil {
+		apiLogger.Fatal(err)
+	}
+	authSvc := grpc.NewAuthService(authClientConn)
+
 	// Init kafka
 	producer := kafkaClient.NewProducer(apiLogger, cfg.Kafka.Brokers)
 	consumer := kafkaClient.NewConsumerGroup(cfg.Kafka.Brokers, apiLogger)
+	dlqPublisher := kafkaClient.NewDeadLetterPublisher(producer)
 
 	// Init app
-	orchestratorSvc := app.NewApp(apiLogger, producer)
+	orchestratorSvc, err := app.NewApp(cfg, apiLogger, producer, sagaRepo)
+	if err != nil {
+		apiLogger.Fatal(err)
+	}
+
+	// resume sagas which were in flight before the last shutdown
+	if err = orchestratorSvc.ResumeTransactions(ctx); err != nil {
+		apiLogger.Errorf("ResumeTransactions err: %v", err)
+	}
 
 	// Init event handler
-	orchestratorEvHanlder := eventhandler.NewEventHandler(cfg, apiLogger, consumer, orchestratorSvc)
+	orchestratorEvHanlder := eventhandler.NewEventHandler(cfg, apiLogger, consumer, dlqPublisher, orchestratorSvc)
 
 	// create graceful shutdown
 	doneCh := make(chan struct{}) // for graceful shutdown
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
-	defer cancel()
 
 	// run event handler
 	orchestratorEvHanlder.Run(ctx)
 
+	// create health checks
+	checker := health.NewChecker()
+	checker.Add("postgres", health.Postgres(psqlDB))
+	checker.Add("kafka", health.Kafka(cfg.Kafka.Brokers))
+	checker.Add("auth_service", health.GRPCConn(authClientConn))
+
+	// create http server
+	engine := http.NewEngine(cfg.HTTP)
+	router := http.NewRouter(orchestratorSvc, authSvc, apiLogger)
+	httpServer := http.NewHTTPServer(cfg.HTTP, apiLogger, engine, router, checker)
+
+	// run http server
+	go func() {
+		if err := httpServer.Run(); err != nil {
+			apiLogger.Fatalf("Run http server err: %v", err)
+		}
+	}()
+
 	// graceful shutdown
 	<-ctx.Done()
 	go func() {
 		time.Sleep(shutdownTimeout)
 		apiLogger.Infof("Shutdown timeout exceeded, force shutdown")
 
+		err = httpServer.GracefulStop(ctx)
+		if err != nil {
+			apiLogger.Errorf("httpServer.GracefulStop err: %v", err)
+		}
+
 		doneCh <- struct{}{}
 	}()
 
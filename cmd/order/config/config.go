@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"github.com/scul0405/saga-orchestration/pkg/appconfig"
+	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
 	"github.com/spf13/viper"
 	"log"
 	"time"
@@ -10,12 +11,14 @@ import (
 
 type Config struct {
 	App         appconfig.App
+	Tracing     appconfig.Tracing
 	HTTP        HTTP
 	GRPC        GRPC
 	Postgres    Postgres
 	Migration   Migration
 	RpcEnpoints RpcEndpoints `mapstructure:"rpcEndpoints"`
 	Kafka       Kafka
+	Outbox      Outbox
 	LocalCache  LocalCache `mapstructure:"localCache"`
 	RedisCache  RedisCache `mapstructure:"redisCache"`
 }
@@ -47,8 +50,15 @@ type RpcEndpoints struct {
 	ProductSvc string
 }
 
+// Kafka configures the brokers and how the consumed messages are retried before going to the dead letter queue
 type Kafka struct {
 	Brokers []string
+	Retry   kafkaClient.RetryConfig
+}
+
+type Outbox struct {
+	PollInterval uint64 // milliseconds
+	BatchSize    int
 }
 
 type LocalCache struct {
@@ -13,9 +13,13 @@ import (
 	"github.com/scul0405/saga-orchestration/internal/order/service"
 	"github.com/scul0405/saga-orchestration/internal/pkg/cache"
 	"github.com/scul0405/saga-orchestration/internal/pkg/grpcconn"
+	"github.com/scul0405/saga-orchestration/internal/pkg/health"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
+	"github.com/scul0405/saga-orchestration/internal/pkg/outbox"
 	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
 	"github.com/scul0405/saga-orchestration/pkg/pgconn"
+	"github.com/scul0405/saga-orchestration/pkg/tracing"
 	"log"
 	"os"
 	"os/signal"
@@ -46,6 +50,17 @@ func main() {
 	apiLogger.InitLogger()
 	apiLogger.Infof("Service Name: %s, LogLevel: %s, Mode: %s", cfg.App.Service.Name, cfg.App.Logger.Level, cfg.App.Service.Mode)
 
+	// init tracing
+	tp, err := tracing.NewTracerProvider(ctx, "order_service", cfg.Tracing)
+	if err != nil {
+		apiLogger.Fatal(err)
+	}
+	defer func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			apiLogger.Errorf("Shutdown tracer provider err: %v", err)
+		}
+	}()
+
 	// connect postgres
 	psqlDB, err := pgconn.NewPsqlDB(cfg.Postgres.DnsURL)
 	if err != nil {
@@ -114,10 +129,18 @@ func main() {
 	// create services
 	orderSvc := service.NewOrderService(apiLogger, orderRepo, productSvc)
 
+	// create health checks
+	checker := health.NewChecker()
+	checker.Add("postgres", health.Postgres(psqlDB))
+	checker.Add("redis", health.Redis(redisClient))
+	checker.Add("kafka", health.Kafka(cfg.Kafka.Brokers))
+	checker.Add("product_service", health.GRPCConn(productClientConn))
+	checker.Add("auth_service", health.GRPCConn(authClientConn))
+
 	// create http server
 	engine := http.NewEngine(cfg.HTTP)
 	router := http.NewRouter(orderSvc, authSvc)
-	httpServer := http.NewHTTPServer(cfg.HTTP, apiLogger, engine, router)
+	httpServer := http.NewHTTPServer(cfg.HTTP, apiLogger, engine, router, checker)
 
 	// run http server
 	go func() {
@@ -129,9 +152,14 @@ func main() {
 	// create kafka
 	producer := kafkaClient.NewProducer(apiLogger, cfg.Kafka.Brokers)
 	consumer := kafkaClient.NewConsumerGroup(cfg.Kafka.Brokers, apiLogger)
+	dlqPublisher := kafkaClient.NewDeadLetterPublisher(producer)
+
+	// run outbox relay
+	outboxRelay := outbox.NewRelay(psqlDB, producer, apiLogger, time.Duration(cfg.Outbox.PollInterval)*time.Millisecond, cfg.Outbox.BatchSize)
+	go outboxRelay.Run(ctx)
 
 	// create event handler
-	orderEvHandler := eventhandler.NewEventHandler(cfg, apiLogger, consumer, producer, orderSvc)
+	orderEvHandler := eventhandler.NewEventHandler(cfg, apiLogger, consumer, inbox.NewInbox(psqlDB), dlqPublisher, orderSvc)
 
 	doneCh := make(chan struct{}) // for graceful shutdown
 
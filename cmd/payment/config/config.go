@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"github.com/scul0405/saga-orchestration/pkg/appconfig"
+	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
 	"github.com/spf13/viper"
 	"log"
 	"time"
@@ -10,12 +11,15 @@ import (
 
 type Config struct {
 	App         appconfig.App
+	Tracing     appconfig.Tracing
 	HTTP        HTTP
 	GRPC        GRPC
 	Postgres    Postgres
 	Migration   Migration
 	RpcEnpoints RpcEndpoints `mapstructure:"rpcEndpoints"`
 	Kafka       Kafka
+	Gateway     Gateway
+	Outbox      Outbox
 	LocalCache  LocalCache `mapstructure:"localCache"`
 	RedisCache  RedisCache `mapstructure:"redisCache"`
 }
@@ -62,8 +66,34 @@ type RpcEndpoints struct {
 	AuthSvc string
 }
 
+// Kafka configures the brokers and how the consumed messages are retried before going to the dead letter queue
 type Kafka struct {
 	Brokers []string
+	Retry   kafkaClient.RetryConfig
+}
+
+// Gateway selects the payment provider: "fake" is the in-process gateway declining the payments matching Decline,
+// "http" calls the provider API at Endpoint, which notifies the payment service with webhooks signed with WebhookSecret
+type Gateway struct {
+	Provider      string
+	Endpoint      string
+	APIKey        string
+	WebhookSecret string
+	Timeout       time.Duration
+	Decline       DeclineRules
+}
+
+// DeclineRules are the payments declined by the fake gateway: above MaxAmount (0 is no limit),
+// in one of the Currencies or of one of the Customers
+type DeclineRules struct {
+	MaxAmount  uint64
+	Currencies []string
+	Customers  []uint64
+}
+
+type Outbox struct {
+	PollInterval uint64 // milliseconds
+	BatchSize    int
 }
 
 type LocalCache struct {
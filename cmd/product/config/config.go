@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"github.com/scul0405/saga-orchestration/pkg/appconfig"
+	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
 	"github.com/spf13/viper"
 	"log"
 	"time"
@@ -10,12 +11,15 @@ import (
 
 type Config struct {
 	App         appconfig.App
+	Tracing     appconfig.Tracing
 	HTTP        HTTP
 	GRPC        GRPC
 	Postgres    Postgres
 	Migration   Migration
 	RpcEnpoints RpcEndpoints `mapstructure:"rpcEndpoints"`
 	Kafka       Kafka
+	Outbox      Outbox
+	Reservation Reservation
 	LocalCache  LocalCache `mapstructure:"localCache"`
 	RedisCache  RedisCache `mapstructure:"redisCache"`
 }
@@ -46,8 +50,22 @@ type RpcEndpoints struct {
 	AuthSvc string
 }
 
+// Kafka configures the brokers and how the consumed messages are retried before going to the dead letter queue
 type Kafka struct {
 	Brokers []string
+	Retry   kafkaClient.RetryConfig
+}
+
+type Outbox struct {
+	PollInterval uint64 // milliseconds
+	BatchSize    int
+}
+
+// Reservation configures the inventory reservations, TTL and ReapInterval are in seconds
+type Reservation struct {
+	TTL          uint64
+	ReapInterval uint64
+	BatchSize    int
 }
 
 type LocalCache struct {
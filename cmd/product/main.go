@@ -6,6 +6,8 @@ import (
 	"github.com/scul0405/saga-orchestration/cmd/product/config"
 	"github.com/scul0405/saga-orchestration/internal/pkg/cache"
 	"github.com/scul0405/saga-orchestration/internal/pkg/grpcconn"
+	"github.com/scul0405/saga-orchestration/internal/pkg/health"
+	"github.com/scul0405/saga-orchestration/internal/pkg/outbox"
 	"github.com/scul0405/saga-orchestration/internal/product/eventhandler"
 	"github.com/scul0405/saga-orchestration/internal/product/infrastructure/db/postgres"
 	grpcclient "github.com/scul0405/saga-orchestration/internal/product/infrastructure/grpc"
@@ -18,6 +20,9 @@ import (
 	"github.com/scul0405/saga-orchestration/pkg/logger"
 	"github.com/scul0405/saga-orchestration/pkg/pgconn"
 	"github.com/scul0405/saga-orchestration/pkg/sonyflake"
+	"github.com/scul0405/saga-orchestration/pkg/tracing"
+	pb "github.com/scul0405/saga-orchestration/proto"
+	grpchealth "google.golang.org/grpc/health"
 	"log"
 	"os"
 	"os/signal"
@@ -48,6 +53,17 @@ func main() {
 	apiLogger.InitLogger()
 	apiLogger.Infof("Service Name: %s, LogLevel: %s, Mode: %s", cfg.App.Service.Name, cfg.App.Logger.Level, cfg.App.Service.Mode)
 
+	// init tracing
+	tp, err := tracing.NewTracerProvider(ctx, "product_service", cfg.Tracing)
+	if err != nil {
+		apiLogger.Fatal(err)
+	}
+	defer func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			apiLogger.Errorf("Shutdown tracer provider err: %v", err)
+		}
+	}()
+
 	// connect postgres
 	psqlDB, err := pgconn.NewPsqlDB(cfg.Postgres.DnsURL)
 	if err != nil {
@@ -108,7 +124,7 @@ func main() {
 	}
 
 	// create services
-	productSvc := service.NewProductService(sf, apiLogger, productRepo)
+	productSvc := service.NewProductService(sf, apiLogger, productRepo, time.Duration(cfg.Reservation.TTL)*time.Second)
 	categorySvc := service.NewCategoryService(sf, apiLogger, categoryPgRepo)
 
 	authConn, err := grpcconn.NewGRPCClientConn(cfg.RpcEnpoints.AuthSvc)
@@ -117,10 +133,17 @@ func main() {
 	}
 	authSvc := grpcclient.NewAuthService(authConn)
 
+	// create health checks
+	checker := health.NewChecker()
+	checker.Add("postgres", health.Postgres(psqlDB))
+	checker.Add("redis", health.Redis(redisClient))
+	checker.Add("kafka", health.Kafka(cfg.Kafka.Brokers))
+	checker.Add("auth_service", health.GRPCConn(authConn))
+
 	// create http server
 	engine := http.NewEngine(cfg.HTTP)
 	router := http.NewRouter(productSvc, categorySvc, authSvc)
-	httpServer := http.NewHTTPServer(cfg.HTTP, apiLogger, engine, router)
+	httpServer := http.NewHTTPServer(cfg.HTTP, apiLogger, engine, router, checker)
 
 	// run http server
 	go func() {
@@ -130,7 +153,9 @@ func main() {
 	}()
 
 	// create grpc server
-	grpcServer := grpc.NewGRPCServer(cfg.GRPC, productSvc)
+	healthServer := grpchealth.NewServer()
+	go checker.Watch(ctx, healthServer, pb.ProductService_ServiceDesc.ServiceName)
+	grpcServer := grpc.NewGRPCServer(cfg.GRPC, productSvc, healthServer)
 
 	// run grpc server
 	go func() {
@@ -142,9 +167,15 @@ func main() {
 	// create kafka
 	producer := kafkaClient.NewProducer(apiLogger, cfg.Kafka.Brokers)
 	consumer := kafkaClient.NewConsumerGroup(cfg.Kafka.Brokers, apiLogger)
+	dlqPublisher := kafkaClient.NewDeadLetterPublisher(producer)
+
+	// run outbox relay
+	outboxRepo := outbox.NewRepository(psqlDB)
+	outboxRelay := outbox.NewRelay(psqlDB, producer, apiLogger, time.Duration(cfg.Outbox.PollInterval)*time.Millisecond, cfg.Outbox.BatchSize)
+	go outboxRelay.Run(ctx)
 
 	// create event handler
-	productEvHandler := eventhandler.NewEventHandler(cfg, apiLogger, consumer, producer, productSvc)
+	productEvHandler := eventhandler.NewEventHandler(cfg, apiLogger, consumer, outboxRepo, dlqPublisher, productSvc)
 
 	doneCh := make(chan struct{}) // for graceful shutdown
 
@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"github.com/scul0405/saga-orchestration/pkg/appconfig"
+	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
 	"github.com/spf13/viper"
 	"log"
 	"time"
@@ -10,7 +11,10 @@ import (
 
 type Config struct {
 	App         appconfig.App
+	Tracing     appconfig.Tracing
 	HTTP        HTTP
+	Postgres    Postgres
+	Migration   Migration
 	RpcEnpoints RpcEndpoints `mapstructure:"rpcEndpoints"`
 	Kafka       Kafka
 }
@@ -28,6 +32,15 @@ type HTTP struct {
 	Mode string
 }
 
+type Postgres struct {
+	DnsURL string `mapstructure:"DNS_URL"`
+}
+
+type Migration struct {
+	Enable   bool
+	Recreate bool
+}
+
 type Logger struct {
 	Development       bool
 	DisableCaller     bool
@@ -41,8 +54,10 @@ type RpcEndpoints struct {
 	ProductSvc string
 }
 
+// Kafka configures the brokers and how the consumed messages are retried before going to the dead letter queue
 type Kafka struct {
 	Brokers []string
+	Retry   kafkaClient.RetryConfig
 }
 
 func LoadConfig(filename string) (*viper.Viper, error) {
@@ -4,13 +4,19 @@ import (
 	"context"
 	"github.com/scul0405/saga-orchestration/cmd/purchase/config"
 	"github.com/scul0405/saga-orchestration/internal/pkg/grpcconn"
+	"github.com/scul0405/saga-orchestration/internal/pkg/health"
 	"github.com/scul0405/saga-orchestration/internal/purchase/eventhandler"
+	"github.com/scul0405/saga-orchestration/internal/purchase/infrastructure/db/postgres"
 	"github.com/scul0405/saga-orchestration/internal/purchase/infrastructure/grpc"
+	"github.com/scul0405/saga-orchestration/internal/purchase/interface/eventconsumer"
 	"github.com/scul0405/saga-orchestration/internal/purchase/interface/http"
+	"github.com/scul0405/saga-orchestration/internal/purchase/repository/pgrepo"
 	"github.com/scul0405/saga-orchestration/internal/purchase/service"
 	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"github.com/scul0405/saga-orchestration/pkg/pgconn"
 	"github.com/scul0405/saga-orchestration/pkg/sonyflake"
+	"github.com/scul0405/saga-orchestration/pkg/tracing"
 	"log"
 	"os"
 	"os/signal"
@@ -43,6 +49,41 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 	defer cancel()
 
+	// init tracing
+	tp, err := tracing.NewTracerProvider(ctx, "purchase_service", cfg.Tracing)
+	if err != nil {
+		apiLogger.Fatal(err)
+	}
+	defer func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			apiLogger.Errorf("Shutdown tracer provider err: %v", err)
+		}
+	}()
+
+	// connect postgres
+	psqlDB, err := pgconn.NewPsqlDB(cfg.Postgres.DnsURL)
+	if err != nil {
+		apiLogger.Fatal(err)
+	}
+	defer func() {
+		db, err := psqlDB.DB()
+		if err = db.Close(); err != nil {
+			apiLogger.Errorf("Close db err: %v", err)
+		}
+	}()
+
+	// run migration
+	apiLogger.Infof("Run migrations with config: %+v", cfg.Migration)
+	err = postgres.NewMigrator(psqlDB).Migrate(cfg.Migration)
+	if err != nil {
+		apiLogger.Errorf("RunMigrations err: %v", err)
+		apiLogger.Fatal(err)
+	}
+	apiLogger.Info("Migrations successfully")
+
+	// create repositories
+	purchaseRepo := pgrepo.NewPurchaseRepository(psqlDB)
+
 	// create sony flake
 	sf, err := sonyflake.NewSonyFlake()
 	if err != nil {
@@ -63,16 +104,30 @@ func main() {
 	authSvc := grpc.NewAuthService(authClientConn)
 
 	producer := kafkaClient.NewProducer(apiLogger, cfg.Kafka.Brokers)
+	consumer := kafkaClient.NewConsumerGroup(cfg.Kafka.Brokers, apiLogger)
+	dlqPublisher := kafkaClient.NewDeadLetterPublisher(producer)
 
 	// Create event publisher
 	evPub := eventhandler.NewPurchaseEventHandler(producer)
 
-	purchaseSvc := service.NewPurchaseService(sf, apiLogger, productSvc, evPub)
+	purchaseSvc := service.NewPurchaseService(sf, apiLogger, productSvc, purchaseRepo, evPub)
+
+	// run purchase result consumer
+	resultBroker := eventhandler.NewPurchaseResultBroker()
+	purchaseEvHandler := eventconsumer.NewEventHandler(cfg, apiLogger, consumer, dlqPublisher, resultBroker, purchaseSvc)
+	purchaseEvHandler.Run(ctx)
+
+	// create health checks
+	checker := health.NewChecker()
+	checker.Add("postgres", health.Postgres(psqlDB))
+	checker.Add("kafka", health.Kafka(cfg.Kafka.Brokers))
+	checker.Add("product_service", health.GRPCConn(productClientConn))
+	checker.Add("auth_service", health.GRPCConn(authClientConn))
 
 	// create http server
 	engine := http.NewEngine(cfg.HTTP)
-	router := http.NewRouter(purchaseSvc, authSvc)
-	httpServer := http.NewHTTPServer(cfg.HTTP, apiLogger, engine, router)
+	router := http.NewRouter(purchaseSvc, authSvc, resultBroker, apiLogger)
+	httpServer := http.NewHTTPServer(cfg.HTTP, apiLogger, engine, router, checker)
 
 	// run http server
 	go func() {
@@ -21,4 +21,6 @@ type JWTAuthRepository interface {
 	CheckCustomer(ctx context.Context, customerID uint64) (bool, bool, error)
 	CreateCustomer(ctx context.Context, customer *entity.Customer) error
 	GetCustomerCredentials(ctx context.Context, email string) (bool, *valueobject.CustomerCredentials, error)
+	// GrantRole gives the role to the accounts of the emails, it returns the number of accounts found
+	GrantRole(ctx context.Context, emails []string, role string) (int64, error)
 }
@@ -2,19 +2,26 @@ package valueobject
 
 import "github.com/golang-jwt/jwt/v5"
 
+const (
+	RoleCustomer = "customer"
+	RoleAdmin    = "admin"
+)
+
 // AuthPayload is a payload contains access token of customer
 type AuthPayload struct {
 	AccessToken string
 }
 
-// AuthResponse is a response contains customer id and expired status of access token
+// AuthResponse is a response contains customer id, role and expired status of access token
 type AuthResponse struct {
 	CustomerID uint64
+	Role       string
 	Expired    bool
 }
 
 type JWTClaims struct {
 	CustomerID uint64
+	Role       string
 	Refresh    bool
 	jwt.RegisteredClaims
 }
@@ -24,4 +31,5 @@ type CustomerCredentials struct {
 	CustomerID uint64
 	Active     bool
 	Password   string
+	Role       string
 }
@@ -9,6 +9,7 @@ type Account struct {
 	Address     string `gorm:"type:text;not null"`
 	PhoneNumber string `gorm:"type:varchar(20);unique;not null"`
 	Password    string `gorm:"type:text;not null"`
+	Role        string `gorm:"type:varchar(20);not null;default:'customer'"`
 	UpdatedAt   int64  `gorm:"autoUpdateTime:milli"`
 	CreatedAt   int64  `gorm:"autoCreateTime:milli"`
 }
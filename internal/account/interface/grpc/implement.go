@@ -23,5 +23,6 @@ func (srv *Server) Auth(ctx context.Context, req *pb.AuthPayload) (*pb.AuthRespo
 	return &pb.AuthResponse{
 		CustomerId: authResponse.CustomerID,
 		Expired:    authResponse.Expired,
+		Role:       authResponse.Role,
 	}, nil
 }
@@ -5,7 +5,10 @@ import (
 	"github.com/scul0405/saga-orchestration/cmd/account/config"
 	"github.com/scul0405/saga-orchestration/internal/account/app"
 	pb "github.com/scul0405/saga-orchestration/proto"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 	"log"
@@ -20,7 +23,7 @@ type Server struct {
 	pb.UnimplementedAuthServiceServer
 }
 
-func NewGRPCServer(config config.GRPC, authSvc app.AuthService) *Server {
+func NewGRPCServer(config config.GRPC, authSvc app.AuthService, healthSrv *grpchealth.Server) *Server {
 	srv := &Server{
 		Port:    config.Port,
 		authSvc: authSvc,
@@ -33,6 +36,7 @@ func NewGRPCServer(config config.GRPC, authSvc app.AuthService) *Server {
 			Timeout:           config.Timeout * time.Second,
 			Time:              config.Time * time.Second,
 		}),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
 		grpc.ChainUnaryInterceptor(
 			grpcrecovery.UnaryServerInterceptor(),
 		),
@@ -40,6 +44,8 @@ func NewGRPCServer(config config.GRPC, authSvc app.AuthService) *Server {
 
 	pb.RegisterAuthServiceServer(srv.grpcServer, srv)
 
+	healthpb.RegisterHealthServer(srv.grpcServer, healthSrv)
+
 	reflection.Register(srv.grpcServer)
 	return srv
 }
@@ -24,6 +24,7 @@ type CustomerCredentials struct {
 	ID       uint64
 	Active   bool
 	Password string
+	Role     string
 }
 
 type jwtAuthRepositoryImpl struct {
@@ -75,7 +76,7 @@ func (r *jwtAuthRepositoryImpl) CreateCustomer(ctx context.Context, customer *en
 
 func (r *jwtAuthRepositoryImpl) GetCustomerCredentials(ctx context.Context, email string) (bool, *valueobject.CustomerCredentials, error) {
 	var credentials CustomerCredentials
-	if err := r.db.Model(&model.Account{}).Select("id", "active", "password").
+	if err := r.db.Model(&model.Account{}).Select("id", "active", "password", "role").
 		Where("email = ?", email).First(&credentials).WithContext(ctx).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return false, nil, nil
@@ -86,5 +87,11 @@ func (r *jwtAuthRepositoryImpl) GetCustomerCredentials(ctx context.Context, emai
 		CustomerID: credentials.ID,
 		Active:     credentials.Active,
 		Password:   credentials.Password,
+		Role:       credentials.Role,
 	}, nil
 }
+
+func (r *jwtAuthRepositoryImpl) GrantRole(ctx context.Context, emails []string, role string) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&model.Account{}).Where("email IN ?", emails).Update("role", role)
+	return result.RowsAffected, result.Error
+}
@@ -167,15 +167,16 @@ func TestGetCustomerCredentials(t *testing.T) {
 		hashedPassword, err := crypto.HashPassword(testCustomer.Password)
 		require.NoError(t, err)
 
-		rows := sqlmock.NewRows([]string{"id", "active", "email", "password"}).
+		rows := sqlmock.NewRows([]string{"id", "active", "email", "password", "role"}).
 			AddRow(testCustomer.ID,
 				testCustomer.Active,
 				testCustomer.PersonalInfo.Email,
 				hashedPassword,
+				valueobject.RoleCustomer,
 			)
 
 		mock.ExpectQuery(
-			"SELECT \"id\",\"active\",\"password\" FROM \"accounts\" WHERE email = $1 ORDER BY \"accounts\".\"id\" LIMIT 1").
+			"SELECT \"id\",\"active\",\"password\",\"role\" FROM \"accounts\" WHERE email = $1 ORDER BY \"accounts\".\"id\" LIMIT 1").
 			WithArgs(testCustomer.PersonalInfo.Email).WillReturnRows(rows)
 
 		exists, creds, err := authRepo.GetCustomerCredentials(context.Background(), testCustomer.PersonalInfo.Email)
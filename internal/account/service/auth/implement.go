@@ -60,8 +60,15 @@ func (s *jwtAuthServiceImpl) Auth(ctx context.Context, authPayload *valueobject.
 		return nil, ErrInvalidToken
 	}
 
+	// tokens issued before the roles were introduced belong to customers
+	role := claims.Role
+	if role == "" {
+		role = valueobject.RoleCustomer
+	}
+
 	return &valueobject.AuthResponse{
 		CustomerID: claims.CustomerID,
+		Role:       role,
 		Expired:    false,
 	}, nil
 }
@@ -81,7 +88,7 @@ func (s *jwtAuthServiceImpl) Register(ctx context.Context, customer *entity.Cust
 		return "", "", err
 	}
 
-	return s.generatePairToken(customer.ID)
+	return s.generatePairToken(customer.ID, valueobject.RoleCustomer)
 }
 
 func (s *jwtAuthServiceImpl) Login(ctx context.Context, email, password string) (string, string, error) {
@@ -103,7 +110,7 @@ func (s *jwtAuthServiceImpl) Login(ctx context.Context, email, password string)
 		return "", "", ErrAuthenticationFailed
 	}
 
-	return s.generatePairToken(customer.CustomerID)
+	return s.generatePairToken(customer.CustomerID, customer.Role)
 }
 
 func (s *jwtAuthServiceImpl) RefreshToken(ctx context.Context, refreshToken string) (string, string, error) {
@@ -143,7 +150,7 @@ func (s *jwtAuthServiceImpl) RefreshToken(ctx context.Context, refreshToken stri
 		return "", "", ErrCustomerInactive
 	}
 
-	return s.generatePairToken(claims.CustomerID)
+	return s.generatePairToken(claims.CustomerID, claims.Role)
 }
 
 func (s *jwtAuthServiceImpl) parseToken(accessToken string) (*jwt.Token, error) {
@@ -155,7 +162,7 @@ func (s *jwtAuthServiceImpl) parseToken(accessToken string) (*jwt.Token, error)
 	})
 }
 
-func (s *jwtAuthServiceImpl) generateToken(customerID uint64, refresh bool) (string, error) {
+func (s *jwtAuthServiceImpl) generateToken(customerID uint64, role string, refresh bool) (string, error) {
 	var expiresAt time.Time
 	if refresh {
 		expiresAt = time.Now().Add(time.Duration(s.jwtConfig.RefreshTokenExpire) * time.Minute)
@@ -165,6 +172,7 @@ func (s *jwtAuthServiceImpl) generateToken(customerID uint64, refresh bool) (str
 
 	claims := &valueobject.JWTClaims{
 		CustomerID: customerID,
+		Role:       role,
 		Refresh:    refresh,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
@@ -175,14 +183,14 @@ func (s *jwtAuthServiceImpl) generateToken(customerID uint64, refresh bool) (str
 	return token.SignedString([]byte(s.jwtConfig.SecretKey))
 }
 
-func (s *jwtAuthServiceImpl) generatePairToken(customerID uint64) (string, string, error) {
-	accessToken, err := s.generateToken(customerID, false)
+func (s *jwtAuthServiceImpl) generatePairToken(customerID uint64, role string) (string, string, error) {
+	accessToken, err := s.generateToken(customerID, role, false)
 	if err != nil {
 		s.logger.Errorf("failed to generate access token: %v", err)
 		return "", "", err
 	}
 
-	refreshToken, err := s.generateToken(customerID, true)
+	refreshToken, err := s.generateToken(customerID, role, true)
 	if err != nil {
 		s.logger.Errorf("failed to generate refresh token: %v", err)
 		return "", "", err
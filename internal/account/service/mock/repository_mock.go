@@ -162,3 +162,18 @@ func (mr *MockJWTAuthRepositoryMockRecorder) GetCustomerCredentials(ctx, email i
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCustomerCredentials", reflect.TypeOf((*MockJWTAuthRepository)(nil).GetCustomerCredentials), ctx, email)
 }
+
+// GrantRole mocks base method.
+func (m *MockJWTAuthRepository) GrantRole(ctx context.Context, emails []string, role string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GrantRole", ctx, emails, role)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GrantRole indicates an expected call of GrantRole.
+func (mr *MockJWTAuthRepositoryMockRecorder) GrantRole(ctx, emails, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrantRole", reflect.TypeOf((*MockJWTAuthRepository)(nil).GrantRole), ctx, emails, role)
+}
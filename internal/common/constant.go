@@ -2,21 +2,33 @@ package common
 
 var (
 	HandlerHeader = "handler"
+	// ReasonHeader carries why a saga is compensated on the compensation messages
+	ReasonHeader = "reason"
+	// RetryHeader marks the commands and compensations re-sent by an operator, the participants process them again
+	// even when they rejected them before
+	RetryHeader = "retry"
 
 	// PurchaseTopic is the subscribed topic for new purchase
-	PurchaseTopic       = "purchase"
-	PurchaseGroupID     = "purchase-group"
-	PurchaseResultTopic = "purchase-result"
+	PurchaseTopic         = "purchase"
+	PurchaseGroupID       = "purchase-group"
+	PurchaseResultTopic   = "purchase-result"
+	PurchaseResultGroupID = "purchase-result-group"
 
-	// UpdateProductInventoryTopic is the topic to which we publish update product inventory
+	// UpdateProductInventoryTopic is the topic to which we publish update product inventory, which reserves the products
 	UpdateProductInventoryTopic   = "update-product-inventory"
 	UpdateProductInventoryGroupID = "update-product-inventory-group"
 	UpdateProductInventoryHandler = "update-product-inventory-handler"
 
+	// RollbackProductInventoryTopic is the topic to which we publish rollback product inventory, which releases the products
 	RollbackProductInventoryTopic   = "rollback-product-inventory"
 	RollbackProductInventoryGroupID = "rollback-product-inventory-group"
 	RollbackProductInventoryHandler = "rollback-product-inventory-handler"
 
+	// ConfirmProductInventoryTopic is the topic to which we publish confirm product inventory, once the payment succeeded
+	ConfirmProductInventoryTopic   = "confirm-product-inventory"
+	ConfirmProductInventoryGroupID = "confirm-product-inventory-group"
+	ConfirmProductInventoryHandler = "confirm-product-inventory-handler"
+
 	// CreateOrderTopic is the topic to which we publish create order
 	CreateOrderTopic   = "create-order"
 	CreateOrderGroupID = "create-order-group"
@@ -26,6 +38,11 @@ var (
 	RollbackOrderGroupID = "rollback-order-group"
 	RollbackOrderHandler = "rollback-order-handler"
 
+	// ConfirmOrderTopic is the topic to which we publish confirm order, once the payment succeeded
+	ConfirmOrderTopic   = "confirm-order"
+	ConfirmOrderGroupID = "confirm-order-group"
+	ConfirmOrderHandler = "confirm-order-handler"
+
 	// CreatePaymentTopic is the topic to which we publish create order
 	CreatePaymentTopic   = "create-payment"
 	CreatePaymentGroupID = "create-payment-group"
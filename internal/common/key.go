@@ -0,0 +1,9 @@
+package common
+
+import "strconv"
+
+// PurchaseKey is the key of every message of a purchase saga, so they all go to the same partition
+// and are consumed in order
+func PurchaseKey(purchaseID uint64) []byte {
+	return []byte(strconv.FormatUint(purchaseID, 10))
+}
@@ -2,15 +2,14 @@ package app
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"github.com/scul0405/saga-orchestration/cmd/orchestrator/config"
 	"github.com/scul0405/saga-orchestration/internal/common"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain"
 	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/aggregate"
-	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/event"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/entity"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/saga"
 	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
-	"github.com/scul0405/saga-orchestration/pkg/timeconvert"
-	pb "github.com/scul0405/saga-orchestration/proto"
 	"github.com/segmentio/kafka-go"
 	"time"
 )
@@ -18,296 +17,118 @@ import (
 type App interface {
 	StartTransaction(ctx context.Context, purchase *aggregate.Purchase) error
 	HandleReply(ctx context.Context, msg *kafka.Message) error
+	ResumeTransactions(ctx context.Context) error
+	SweepTransactions(ctx context.Context) error
+
+	ListSagas(ctx context.Context, status string, limit, offset int) (*[]aggregate.Saga, error)
+	GetSaga(ctx context.Context, id uint64) (*aggregate.Saga, *[]entity.SagaTransition, error)
+	RetrySaga(ctx context.Context, id uint64) error
+	CompensateSaga(ctx context.Context, id uint64, reason string) error
+	ResolveSaga(ctx context.Context, id uint64, reason string) error
 }
 
 type app struct {
 	logger   logger.Logger
 	producer kafkaClient.Producer
+	sagaRepo domain.SagaRepository
+	engine   saga.Engine
 }
 
-func NewApp(logger logger.Logger, producer kafkaClient.Producer) App {
-	return &app{
+func NewApp(cfg *config.Config, logger logger.Logger, producer kafkaClient.Producer, sagaRepo domain.SagaRepository) (App, error) {
+	definition, err := NewPurchaseDefinition(time.Duration(cfg.Saga.StepTimeout)*time.Second, cfg.Saga.MaxRetries)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &app{
 		logger:   logger,
 		producer: producer,
+		sagaRepo: sagaRepo,
 	}
+	a.engine = saga.NewEngine(logger, definition, producer, sagaRepo, a.publishPurchaseResult)
+
+	return a, nil
 }
 
 func (a *app) StartTransaction(ctx context.Context, purchase *aggregate.Purchase) error {
-	err := a.publishPurchaseResult(ctx, &event.PurchaseResult{
-		PurchaseID: purchase.ID,
-		Status:     event.StatusExecute,
-		Step:       event.StepUpdateProductInventory,
-	})
-	if err != nil {
-		return err
-	}
-
-	// Call the product service to update the product inventory
 	pbPurchase := encodeModel2PurchaseRequest(purchase)
 
-	payload, _ := json.Marshal(pbPurchase)
-	return a.producer.PublishMessage(ctx, kafka.Message{
-		Topic: common.UpdateProductInventoryTopic,
-		Value: payload,
-	})
-}
-
-func (a *app) HandleReply(ctx context.Context, msg *kafka.Message) error {
-	switch string(msg.Headers[0].Value) {
-	case common.UpdateProductInventoryHandler:
-		purchaseResult, err := decodePbResponseToEventModel(msg.Value)
-		if err != nil {
-			return err
-		}
-
-		if purchaseResult.Success {
-			return a.createOrder(ctx, purchaseResult.Purchase)
-		}
-
-		return a.rollbackUpdateProductInventory(ctx, purchaseResult.Purchase)
-	case common.RollbackProductInventoryHandler:
-		purchaseResult, err := decodePbResponseToEventModel(msg.Value)
-		if err != nil {
-			return err
-		}
-
-		if !purchaseResult.Success {
-			return a.publishPurchaseResult(ctx, &event.PurchaseResult{
-				PurchaseID: purchaseResult.Purchase.ID,
-				Status:     event.StatusRollbackFailed,
-				Step:       event.StepUpdateProductInventory,
-			})
-		}
-
-		return nil
-	case common.CreateOrderHandler:
-		purchaseResult, err := decodePbResponseToEventModel(msg.Value)
-		if err != nil {
-			return err
-		}
-
-		if purchaseResult.Success {
-			return a.createPayment(ctx, purchaseResult.Purchase)
-		}
-
-		return a.rollbackCreateOrder(ctx, purchaseResult.Purchase)
-	case common.RollbackOrderHandler:
-		purchaseResult, err := decodePbResponseToEventModel(msg.Value)
-		if err != nil {
-			return err
-		}
-
-		if !purchaseResult.Success {
-			return a.publishPurchaseResult(ctx, &event.PurchaseResult{
-				PurchaseID: purchaseResult.Purchase.ID,
-				Status:     event.StatusRollbackFailed,
-				Step:       event.StepCreateOrder,
-			})
-		}
-
-		return nil
-	case common.CreatePaymentHandler:
-		purchaseResult, err := decodePbResponseToEventModel(msg.Value)
-		if err != nil {
-			return err
-		}
-
-		if purchaseResult.Success {
-			return a.publishPurchaseResult(ctx, &event.PurchaseResult{
-				PurchaseID: purchaseResult.Purchase.ID,
-				Status:     event.StatusSucess,
-				Step:       event.StepCreatePayment,
-			})
-		}
-
-		return a.rollbackPayment(ctx, purchaseResult.Purchase)
-	case common.RollbackPaymentHandler:
-		purchaseResult, err := decodePbResponseToEventModel(msg.Value)
-		if err != nil {
-			return err
-		}
-
-		if !purchaseResult.Success {
-			return a.publishPurchaseResult(ctx, &event.PurchaseResult{
-				PurchaseID: purchaseResult.Purchase.ID,
-				Status:     event.StatusRollbackFailed,
-				Step:       event.StepCreatePayment,
-			})
-		}
-
-		return nil
-	default:
-		return fmt.Errorf("handle reply: unknown handler: %s", msg.Headers[0].Key)
-	}
-}
-
-func (a *app) rollbackUpdateProductInventory(ctx context.Context, purchase *aggregate.Purchase) error {
-	err := a.publishPurchaseResult(ctx, &event.PurchaseResult{
-		PurchaseID: purchase.ID,
-		Status:     event.StatusFailed,
-		Step:       event.StepUpdateProductInventory,
-	})
-	if err != nil {
-		return err
-	}
-
-	pbRollback := &pb.RollbackPurchaseRequest{
-		PurchaseId: purchase.ID,
-		Timestamp:  timeconvert.Time2pbTimestamp(time.Now()),
-	}
-
-	err = a.publishPurchaseResult(ctx, &event.PurchaseResult{
-		PurchaseID: purchase.ID,
-		Status:     event.StatusRollback,
-		Step:       event.StepUpdateProductInventory,
-	})
+	payload, contentType, err := kafkaClient.Encode(kafkaClient.ContentTypeProtobuf, pbPurchase)
 	if err != nil {
 		return err
 	}
 
-	payload, _ := json.Marshal(pbRollback)
-	return a.producer.PublishMessage(ctx, kafka.Message{
-		Topic: common.RollbackProductInventoryTopic,
-		Value: payload,
-	})
+	return a.engine.Start(ctx, purchase.ID, payload, string(contentType.Value))
 }
 
-func (a *app) createOrder(ctx context.Context, purchase *aggregate.Purchase) error {
-	err := a.publishPurchaseResult(ctx, &event.PurchaseResult{
-		PurchaseID: purchase.ID,
-		Status:     event.StatusSucess,
-		Step:       event.StepUpdateProductInventory,
-	})
+func (a *app) HandleReply(ctx context.Context, msg *kafka.Message) error {
+	reply, err := decodePbResponseToReply(msg)
 	if err != nil {
 		return err
 	}
 
-	pbPurchase := encodeModel2PurchaseRequest(purchase)
-
-	err = a.publishPurchaseResult(ctx, &event.PurchaseResult{
-		PurchaseID: purchase.ID,
-		Status:     event.StatusExecute,
-		Step:       event.StepCreateOrder,
-	})
-	if err != nil {
-		return err
-	}
+	return a.engine.HandleReply(ctx, reply)
+}
 
-	payload, _ := json.Marshal(pbPurchase)
-	return a.producer.PublishMessage(ctx, kafka.Message{
-		Topic: common.CreateOrderTopic,
-		Value: payload,
-	})
+// ResumeTransactions re-drives the sagas which were in flight when the orchestrator stopped
+func (a *app) ResumeTransactions(ctx context.Context) error {
+	return a.engine.Resume(ctx)
 }
 
-func (a *app) rollbackCreateOrder(ctx context.Context, purchase *aggregate.Purchase) error {
-	err := a.publishPurchaseResult(ctx, &event.PurchaseResult{
-		PurchaseID: purchase.ID,
-		Status:     event.StatusFailed,
-		Step:       event.StepCreateOrder,
-	})
-	if err != nil {
-		return err
-	}
+// SweepTransactions retries or fails the sagas whose current step timed out
+func (a *app) SweepTransactions(ctx context.Context) error {
+	return a.engine.Sweep(ctx)
+}
 
-	pbRollback := &pb.RollbackPurchaseRequest{
-		PurchaseId: purchase.ID,
-		Timestamp:  timeconvert.Time2pbTimestamp(time.Now()),
-	}
+// ListSagas returns the most recently updated sagas, filtered by status when it is not empty
+func (a *app) ListSagas(ctx context.Context, status string, limit, offset int) (*[]aggregate.Saga, error) {
+	return a.sagaRepo.ListSagas(ctx, status, limit, offset)
+}
 
-	err = a.publishPurchaseResult(ctx, &event.PurchaseResult{
-		PurchaseID: purchase.ID,
-		Status:     event.StatusRollback,
-		Step:       event.StepCreateOrder,
-	})
+// GetSaga returns the saga with its history
+func (a *app) GetSaga(ctx context.Context, id uint64) (*aggregate.Saga, *[]entity.SagaTransition, error) {
+	s, err := a.sagaRepo.GetSaga(ctx, id)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	payload, _ := json.Marshal(pbRollback)
-	err = a.producer.PublishMessage(ctx, kafka.Message{
-		Topic: common.RollbackOrderTopic,
-		Value: payload,
-	})
+	transitions, err := a.sagaRepo.ListSagaTransitions(ctx, id)
 	if err != nil {
-		a.logger.Errorf("Orchestrator.RollbackFromOrder.RollbackCreateOrder, err: %v", err)
-		return err
+		return nil, nil, err
 	}
 
-	return a.rollbackUpdateProductInventory(ctx, purchase)
+	return s, transitions, nil
 }
 
-func (a *app) createPayment(ctx context.Context, purchase *aggregate.Purchase) error {
-	err := a.publishPurchaseResult(ctx, &event.PurchaseResult{
-		PurchaseID: purchase.ID,
-		Status:     event.StatusSucess,
-		Step:       event.StepCreatePayment,
-	})
-	if err != nil {
-		return err
-	}
-
-	pbPurchase := encodeModel2PurchaseRequest(purchase)
-
-	err = a.publishPurchaseResult(ctx, &event.PurchaseResult{
-		PurchaseID: purchase.ID,
-		Status:     event.StatusExecute,
-		Step:       event.StepCreatePayment,
-	})
-	if err != nil {
-		return err
-	}
+// RetrySaga re-sends the command or the compensation of the current step of the saga
+func (a *app) RetrySaga(ctx context.Context, id uint64) error {
+	return a.engine.Retry(ctx, id)
+}
 
-	payload, _ := json.Marshal(pbPurchase)
-	return a.producer.PublishMessage(ctx, kafka.Message{
-		Topic: common.CreatePaymentTopic,
-		Value: payload,
-	})
+// CompensateSaga fails the saga and rolls back its executed steps
+func (a *app) CompensateSaga(ctx context.Context, id uint64, reason string) error {
+	return a.engine.Compensate(ctx, id, reason)
 }
 
-func (a *app) rollbackPayment(ctx context.Context, purchase *aggregate.Purchase) error {
-	err := a.publishPurchaseResult(ctx, &event.PurchaseResult{
-		PurchaseID: purchase.ID,
-		Status:     event.StatusFailed,
-		Step:       event.StepCreatePayment,
-	})
-	if err != nil {
-		return err
-	}
+// ResolveSaga closes a saga settled by hand
+func (a *app) ResolveSaga(ctx context.Context, id uint64, reason string) error {
+	return a.engine.Resolve(ctx, id, reason)
+}
 
-	pbRollback := &pb.RollbackPurchaseRequest{
-		PurchaseId: purchase.ID,
-		Timestamp:  timeconvert.Time2pbTimestamp(time.Now()),
-	}
+func (a *app) publishPurchaseResult(ctx context.Context, saga *aggregate.Saga) error {
+	sagaTransitions.WithLabelValues(saga.Step, saga.Status).Inc()
 
-	err = a.publishPurchaseResult(ctx, &event.PurchaseResult{
-		PurchaseID: purchase.ID,
-		Status:     event.StatusRollback,
-		Step:       event.StepCreatePayment,
-	})
-	if err != nil {
-		return err
-	}
+	pbResult := encodePurchaseResult(saga)
 
-	payload, _ := json.Marshal(pbRollback)
-	err = a.producer.PublishMessage(ctx, kafka.Message{
-		Topic: common.RollbackPaymentTopic,
-		Value: payload,
-	})
+	payload, contentType, err := kafkaClient.Encode(kafkaClient.ContentTypeProtobuf, pbResult)
 	if err != nil {
-		a.logger.Errorf("Orchestrator.RollbackFromPayment.RollbackCreatePayment, err: %v", err)
 		return err
 	}
 
-	return a.rollbackCreateOrder(ctx, purchase)
-}
-
-func (a *app) publishPurchaseResult(ctx context.Context, result *event.PurchaseResult) error {
-	pbResult := encodePurchaseResult(result)
-
-	payload, _ := json.Marshal(pbResult)
 	return a.producer.PublishMessage(ctx, kafka.Message{
-		Topic: common.PurchaseResultTopic,
-		Value: payload,
+		Topic:   common.PurchaseResultTopic,
+		Key:     common.PurchaseKey(saga.ID),
+		Value:   payload,
+		Headers: []kafka.Header{contentType},
 	})
 }
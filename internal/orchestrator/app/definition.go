@@ -0,0 +1,57 @@
+package app
+
+import (
+	"github.com/scul0405/saga-orchestration/internal/common"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/event"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/saga"
+	"time"
+)
+
+// NewPurchaseDefinition declares the purchase saga: the reservation of the inventory, then order, then payment,
+// then the confirmation of the reservation and of the order. Compensating the inventory releases the reservation,
+// or restocks the products once it is confirmed.
+func NewPurchaseDefinition(stepTimeout time.Duration, maxRetries uint64) (*saga.Definition, error) {
+	return saga.NewDefinitionBuilder().
+		AddStep(saga.Step{
+			Name:                event.StepUpdateProductInventory,
+			CommandTopic:        common.UpdateProductInventoryTopic,
+			ReplyHandler:        common.UpdateProductInventoryHandler,
+			CompensationTopic:   common.RollbackProductInventoryTopic,
+			CompensationHandler: common.RollbackProductInventoryHandler,
+			Timeout:             stepTimeout,
+			MaxRetries:          maxRetries,
+		}).
+		AddStep(saga.Step{
+			Name:                event.StepCreateOrder,
+			CommandTopic:        common.CreateOrderTopic,
+			ReplyHandler:        common.CreateOrderHandler,
+			CompensationTopic:   common.RollbackOrderTopic,
+			CompensationHandler: common.RollbackOrderHandler,
+			Timeout:             stepTimeout,
+			MaxRetries:          maxRetries,
+		}).
+		AddStep(saga.Step{
+			Name:                event.StepCreatePayment,
+			CommandTopic:        common.CreatePaymentTopic,
+			ReplyHandler:        common.CreatePaymentHandler,
+			CompensationTopic:   common.RollbackPaymentTopic,
+			CompensationHandler: common.RollbackPaymentHandler,
+			Timeout:             stepTimeout,
+			MaxRetries:          maxRetries,
+		}).
+		AddStep(saga.Step{
+			Name:         event.StepConfirmProductInventory,
+			CommandTopic: common.ConfirmProductInventoryTopic,
+			ReplyHandler: common.ConfirmProductInventoryHandler,
+			Timeout:      stepTimeout,
+			MaxRetries:   maxRetries,
+		}).
+		AddStep(saga.Step{
+			Name:         event.StepConfirmOrder,
+			CommandTopic: common.ConfirmOrderTopic,
+			ReplyHandler: common.ConfirmOrderHandler,
+			Timeout:      stepTimeout,
+			MaxRetries:   maxRetries,
+		}).
+		Build()
+}
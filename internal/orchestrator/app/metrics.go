@@ -0,0 +1,13 @@
+package app
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// sagaTransitions counts the transitions of the sagas, the terminal statuses give the success,
+// failure and compensation rates of the purchases
+var sagaTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "saga_transitions_total",
+	Help: "Persisted saga transitions by step and status.",
+}, []string{"step", "status"})
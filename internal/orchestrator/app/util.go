@@ -1,20 +1,25 @@
 package app
 
 import (
-	"encoding/json"
+	"fmt"
+	"github.com/scul0405/saga-orchestration/internal/common"
 	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/aggregate"
-	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/entity"
 	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/event"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/saga"
+	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
 	"github.com/scul0405/saga-orchestration/pkg/timeconvert"
 	pb "github.com/scul0405/saga-orchestration/proto"
+	"github.com/segmentio/kafka-go"
 	"time"
 )
 
-func encodePurchaseResult(result *event.PurchaseResult) *pb.PurchaseResult {
+func encodePurchaseResult(saga *aggregate.Saga) *pb.PurchaseResult {
 	return &pb.PurchaseResult{
-		PurchaseId: result.PurchaseID,
-		Step:       getPbPurchaseStep(result.Step),
-		Status:     getPbPurchaseStatus(result.Status),
+		PurchaseId: saga.ID,
+		Step:       getPbPurchaseStep(saga.Step),
+		StepName:   saga.Step,
+		Status:     getPbPurchaseStatus(saga.Status),
+		Reason:     saga.Reason,
 		Timestamp:  timeconvert.Time2pbTimestamp(time.Now()),
 	}
 }
@@ -25,6 +30,7 @@ func encodeModel2PurchaseRequest(purchase *aggregate.Purchase) *pb.CreatePurchas
 		orderItems[i] = &pb.PurchaseOrderItem{
 			ProductId: item.ID,
 			Quantity:  item.Quantity,
+			Price:     item.Price,
 		}
 	}
 
@@ -43,35 +49,26 @@ func encodeModel2PurchaseRequest(purchase *aggregate.Purchase) *pb.CreatePurchas
 	}
 }
 
-func decodePbResponseToEventModel(data []byte) (*event.CreatePurchaseResponse, error) {
+func decodePbResponseToReply(msg *kafka.Message) (*saga.Reply, error) {
+	var handler string
+	for _, header := range msg.Headers {
+		if header.Key == common.HandlerHeader {
+			handler = string(header.Value)
+		}
+	}
+	if handler == "" {
+		return nil, fmt.Errorf("%w: decode reply: missing %s header", kafkaClient.ErrPoisonMessage, common.HandlerHeader)
+	}
+
 	var pbResult pb.CreatePurchaseResponse
-	err := json.Unmarshal(data, &pbResult)
+	err := kafkaClient.Decode(*msg, &pbResult)
 	if err != nil {
 		return nil, err
 	}
 
-	orderItems := make([]entity.OrderItem, len(pbResult.Purchase.Order.OrderItems))
-	for i, item := range pbResult.Purchase.Order.OrderItems {
-		orderItems[i] = entity.OrderItem{
-			ID:       item.ProductId,
-			Quantity: item.Quantity,
-		}
-	}
-
-	return &event.CreatePurchaseResponse{
-		Purchase: &aggregate.Purchase{
-			ID: pbResult.PurchaseId,
-			Order: &entity.Order{
-				ID:         pbResult.PurchaseId,
-				CustomerID: pbResult.Purchase.Order.CustomerId,
-				OrderItems: &orderItems,
-			},
-			Payment: &entity.Payment{
-				ID:           pbResult.PurchaseId,
-				CurrencyCode: pbResult.Purchase.Payment.CurrencyCode,
-				Amount:       pbResult.Purchase.Payment.Amount,
-			},
-		},
+	return &saga.Reply{
+		SagaID:  pbResult.PurchaseId,
+		Handler: handler,
 		Success: pbResult.Success,
 		Error:   pbResult.ErrorMessage,
 	}, nil
@@ -85,6 +82,10 @@ func getPbPurchaseStep(step string) pb.PurchaseStep {
 		return pb.PurchaseStep_CREATE_ORDER
 	case event.StepCreatePayment:
 		return pb.PurchaseStep_CREATE_PAYMENT
+	case event.StepConfirmProductInventory:
+		return pb.PurchaseStep_CONFIRM_PRODUCT_INVENTORY
+	case event.StepConfirmOrder:
+		return pb.PurchaseStep_CONFIRM_ORDER
 	}
 	return -1
 }
@@ -101,6 +102,10 @@ func getPbPurchaseStatus(status string) pb.PurchaseStatus {
 		return pb.PurchaseStatus_ROLLBACK
 	case event.StatusRollbackFailed:
 		return pb.PurchaseStatus_ROLLBACK_FAILED
+	case event.StatusCompensated:
+		return pb.PurchaseStatus_COMPENSATED
+	case event.StatusResolved:
+		return pb.PurchaseStatus_RESOLVED
 	}
 	return -1
 }
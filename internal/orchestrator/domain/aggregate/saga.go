@@ -0,0 +1,21 @@
+package aggregate
+
+import "time"
+
+// Saga aggregate keeps track of the progress of a distributed transaction.
+// Payload is the encoded command sent to every participant of the saga, encoded with ContentType
+// (an empty ContentType is the plain JSON of the sagas started before the Kafka codec),
+// Deadline is the time by which the participant of the current step must reply.
+type Saga struct {
+	ID          uint64
+	Step        string
+	Status      string
+	Reason      string
+	Attempts    map[string]uint64
+	Payload     []byte
+	ContentType string
+	Deadline    time.Time
+	Version     uint64
+	UpdatedAt   time.Time
+	CreatedAt   time.Time
+}
@@ -3,4 +3,6 @@ package entity
 type OrderItem struct {
 	ID       uint64
 	Quantity uint64
+	// Price is the unit price of the product when it was purchased
+	Price uint64
 }
@@ -0,0 +1,11 @@
+package entity
+
+import "time"
+
+// SagaTransition is an entry of the saga history, one per persisted step and status
+type SagaTransition struct {
+	Step      string
+	Status    string
+	Reason    string
+	CreatedAt time.Time
+}
@@ -3,15 +3,22 @@ package event
 import "time"
 
 var (
-	StepUpdateProductInventory = "UPDATE_PRODUCT_INVENTORY"
-	StepCreateOrder            = "CREATE_ORDER"
-	StepCreatePayment          = "CREATE_PAYMENT"
+	StepUpdateProductInventory  = "UPDATE_PRODUCT_INVENTORY"
+	StepCreateOrder             = "CREATE_ORDER"
+	StepCreatePayment           = "CREATE_PAYMENT"
+	StepConfirmProductInventory = "CONFIRM_PRODUCT_INVENTORY"
+	StepConfirmOrder            = "CONFIRM_ORDER"
 
 	StatusExecute        = "EXUCUTE"
 	StatusSucess         = "SUCCESS"
 	StatusFailed         = "FAILED"
 	StatusRollback       = "ROLLBACK"
 	StatusRollbackFailed = "ROLLBACK_FAILED"
+
+	// StatusCompensated is the final saga status once every compensation succeeded
+	StatusCompensated = "COMPENSATED"
+	// StatusResolved is the final saga status set by an operator who settled the saga by hand
+	StatusResolved = "RESOLVED"
 )
 
 // PurchaseResult event
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/aggregate"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/entity"
+	"time"
+)
+
+var (
+	// ErrDuplicateEntry is returned when the saga of the purchase already exists
+	ErrDuplicateEntry = errors.New("duplicate entry")
+	// ErrStaleSaga is returned when the saga was changed since it was read
+	ErrStaleSaga = errors.New("saga was updated concurrently")
+)
+
+type SagaRepository interface {
+	GetSaga(ctx context.Context, purchaseID uint64) (*aggregate.Saga, error)
+	ListSagasByStatus(ctx context.Context, statuses ...string) (*[]aggregate.Saga, error)
+	ListSagas(ctx context.Context, status string, limit, offset int) (*[]aggregate.Saga, error)
+	ListSagaTransitions(ctx context.Context, purchaseID uint64) (*[]entity.SagaTransition, error)
+	ListExpiredSagas(ctx context.Context, now time.Time) (*[]aggregate.Saga, error)
+	CreateSaga(ctx context.Context, saga *aggregate.Saga) error
+	UpdateSaga(ctx context.Context, saga *aggregate.Saga) error
+}
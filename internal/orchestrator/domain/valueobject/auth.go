@@ -1,6 +1,10 @@
 package valueobject
 
+// RoleAdmin is the role of the accounts allowed to use the admin API
+const RoleAdmin = "admin"
+
 type AuthResponse struct {
 	CustomerID uint64
 	Expired    bool
+	Role       string
 }
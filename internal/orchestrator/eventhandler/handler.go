@@ -2,8 +2,6 @@ package eventhandler
 
 import (
 	"context"
-	"encoding/json"
-	"github.com/avast/retry-go"
 	"github.com/scul0405/saga-orchestration/cmd/orchestrator/config"
 	"github.com/scul0405/saga-orchestration/internal/common"
 	"github.com/scul0405/saga-orchestration/internal/orchestrator/app"
@@ -12,15 +10,12 @@ import (
 	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
 	pb "github.com/scul0405/saga-orchestration/proto"
-	"github.com/segmentio/kafka-go"
 	"sync"
 	"time"
 )
 
 var (
-	retryAttempts uint = 10
-	retryDelay         = 1 * time.Second
-	poolSize           = 16
+	poolSize = 16
 )
 
 type EventHandler interface {
@@ -31,14 +26,21 @@ type eventHandler struct {
 	cfg      *config.Config
 	logger   logger.Logger
 	consumer kafkaClient.ConsumerGroup
+	dlq      kafkaClient.DeadLetterPublisher
 	app      app.App
 }
 
-func NewEventHandler(cfg *config.Config, logger logger.Logger, consumer kafkaClient.ConsumerGroup, app app.App) EventHandler {
+func NewEventHandler(
+	cfg *config.Config,
+	logger logger.Logger,
+	consumer kafkaClient.ConsumerGroup,
+	dlq kafkaClient.DeadLetterPublisher,
+	app app.App) EventHandler {
 	return &eventHandler{
 		cfg:      cfg,
 		logger:   logger,
 		consumer: consumer,
+		dlq:      dlq,
 		app:      app,
 	}
 }
@@ -46,11 +48,14 @@ func NewEventHandler(cfg *config.Config, logger logger.Logger, consumer kafkaCli
 func (h *eventHandler) Run(ctx context.Context) {
 	go h.consumer.ConsumeTopic(ctx, poolSize, common.PurchaseGroupID, common.PurchaseTopic, h.createPurchaseWorker)
 	go h.consumer.ConsumeTopic(ctx, poolSize, common.ReplyGroupID, common.ReplyTopic, h.replyWorker)
+	go h.sweepWorker(ctx)
 }
 
-func (h *eventHandler) createPurchaseWorker(ctx context.Context, r *kafka.Reader, wg *sync.WaitGroup, workerID int) {
+func (h *eventHandler) createPurchaseWorker(ctx context.Context, r kafkaClient.MessageReader, wg *sync.WaitGroup, workerID int) {
 	defer wg.Done()
 
+	policy := h.cfg.Kafka.Retry.Policy(common.PurchaseTopic)
+
 	for {
 		m, err := r.FetchMessage(ctx)
 		if err != nil {
@@ -58,9 +63,18 @@ func (h *eventHandler) createPurchaseWorker(ctx context.Context, r *kafka.Reader
 			return
 		}
 
+		msgCtx, span := kafkaClient.StartConsumerSpan(ctx, m, "Orchestrator.CreatePurchaseWorker")
+
 		var purchase pb.CreatePurchaseRequest
-		if err = json.Unmarshal(m.Value, &purchase); err != nil {
+		if err = kafkaClient.Decode(m, &purchase); err != nil {
 			h.logger.Errorf("Orchestrator.CreatePurchaseWorker: UnmarshalProto", err)
+			if err = h.dlq.Publish(msgCtx, m, err, 1, "Orchestrator.CreatePurchaseWorker"); err != nil {
+				h.logger.Errorf("Orchestrator.CreatePurchaseWorker: PublishDeadLetter", err)
+			}
+			if err = r.CommitMessages(msgCtx, m); err != nil {
+				h.logger.Errorf("Orchestrator.CreatePurchaseWorker: CommitMessages", err)
+			}
+			span.End()
 			continue
 		}
 		h.logger.Infof("Orchestrator.CreatePurchaseWorker: %v, message at topic/partition/offset %v/%v/%v: %s = %s\n", workerID, m.Topic, m.Partition, m.Offset, string(m.Key), string(m.Value))
@@ -70,6 +84,7 @@ func (h *eventHandler) createPurchaseWorker(ctx context.Context, r *kafka.Reader
 			orderItems[i] = entity.OrderItem{
 				ID:       item.ProductId,
 				Quantity: item.Quantity,
+				Price:    item.Price,
 			}
 		}
 
@@ -87,31 +102,35 @@ func (h *eventHandler) createPurchaseWorker(ctx context.Context, r *kafka.Reader
 			},
 		}
 
-		if err = retry.Do(func() error {
-			err = h.app.StartTransaction(ctx, &domainPurchase)
+		attempts, err := policy.Do(msgCtx, func() error {
+			err = h.app.StartTransaction(msgCtx, &domainPurchase)
 			if err != nil {
 				h.logger.Errorf("Orchestrator.CreatePurchaseWorker: StartTransaction", err)
 			}
 
 			return err
-		},
-			retry.Attempts(retryAttempts),
-			retry.Delay(retryDelay),
-			retry.Context(ctx),
-		); err != nil {
-			// TODO: publish error message to error topic
+		})
+		if err != nil {
+			h.logger.Errorf("Orchestrator.CreatePurchaseWorker: failed after %d attempts: %v", attempts, err)
+			if err = h.dlq.Publish(msgCtx, m, err, attempts, "Orchestrator.CreatePurchaseWorker"); err != nil {
+				h.logger.Errorf("Orchestrator.CreatePurchaseWorker: PublishDeadLetter", err)
+			}
 		}
 
-		err = r.CommitMessages(ctx, m)
+		err = r.CommitMessages(msgCtx, m)
 		if err != nil {
 			h.logger.Errorf("Orchestrator.CreatePurchaseWorker: CommitMessages", err)
 		}
+
+		span.End()
 	}
 }
 
-func (h *eventHandler) replyWorker(ctx context.Context, r *kafka.Reader, wg *sync.WaitGroup, workerID int) {
+func (h *eventHandler) replyWorker(ctx context.Context, r kafkaClient.MessageReader, wg *sync.WaitGroup, workerID int) {
 	defer wg.Done()
 
+	policy := h.cfg.Kafka.Retry.Policy(common.ReplyTopic)
+
 	for {
 		m, err := r.FetchMessage(ctx)
 		if err != nil {
@@ -119,26 +138,51 @@ func (h *eventHandler) replyWorker(ctx context.Context, r *kafka.Reader, wg *syn
 			return
 		}
 
+		msgCtx, span := kafkaClient.StartConsumerSpan(ctx, m, "Orchestrator.ReplyWorker")
+
 		h.logger.Infof("ReplyWorker: %v, message at topic/partition/offset %v/%v/%v: %s = %s\n", workerID, m.Topic, m.Partition, m.Offset, string(m.Key), string(m.Value))
 
-		if err = retry.Do(func() error {
-			err = h.app.HandleReply(ctx, &m)
+		attempts, err := policy.Do(msgCtx, func() error {
+			err = h.app.HandleReply(msgCtx, &m)
 			if err != nil {
 				h.logger.Errorf("Orchestrator.ReplyWorker: StartTransaction", err)
 			}
 
 			return err
-		},
-			retry.Attempts(retryAttempts),
-			retry.Delay(retryDelay),
-			retry.Context(ctx),
-		); err != nil {
-			// TODO: publish error message to error topic
+		})
+		if err != nil {
+			h.logger.Errorf("Orchestrator.ReplyWorker: failed after %d attempts: %v", attempts, err)
+			if err = h.dlq.Publish(msgCtx, m, err, attempts, "Orchestrator.ReplyWorker"); err != nil {
+				h.logger.Errorf("Orchestrator.ReplyWorker: PublishDeadLetter", err)
+			}
 		}
 
-		err = r.CommitMessages(ctx, m)
+		err = r.CommitMessages(msgCtx, m)
 		if err != nil {
 			h.logger.Errorf("Orchestrator.ReplyWorker: CommitMessages", err)
 		}
+
+		span.End()
+	}
+}
+
+// sweepWorker periodically checks the deadlines of the saga steps waiting for a reply
+func (h *eventHandler) sweepWorker(ctx context.Context) {
+	if h.cfg.Saga.SweepInterval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(h.cfg.Saga.SweepInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := h.app.SweepTransactions(ctx); err != nil {
+				h.logger.Errorf("Orchestrator.SweepWorker: SweepTransactions", err)
+			}
+		}
 	}
 }
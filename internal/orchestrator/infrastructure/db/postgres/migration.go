@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"github.com/scul0405/saga-orchestration/cmd/orchestrator/config"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/infrastructure/db/postgres/model"
+	"gorm.io/gorm"
+)
+
+type Migrator struct {
+	db *gorm.DB
+}
+
+func NewMigrator(db *gorm.DB) *Migrator {
+	return &Migrator{db: db}
+}
+
+func (m *Migrator) Migrate(migration config.Migration) error {
+	if !migration.Enable {
+		return nil
+	}
+
+	if migration.Recreate {
+		if err := m.db.Migrator().DropTable(&model.Saga{}, &model.SagaTransition{}); err != nil {
+			return err
+		}
+	}
+
+	if err := m.migratePayload(); err != nil {
+		return err
+	}
+
+	return m.db.AutoMigrate(&model.Saga{}, &model.SagaTransition{})
+}
+
+// migratePayload turns the jsonb payload of the sagas started before the Kafka codec into bytea,
+// postgres has no cast between the two types so AutoMigrate can not change it
+func (m *Migrator) migratePayload() error {
+	if !m.db.Migrator().HasTable(&model.Saga{}) {
+		return nil
+	}
+
+	columns, err := m.db.Migrator().ColumnTypes(&model.Saga{})
+	if err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		if column.Name() == "payload" && column.DatabaseTypeName() == "jsonb" {
+			return m.db.Exec("ALTER TABLE sagas ALTER COLUMN payload TYPE bytea USING convert_to(payload::text, 'UTF8')").Error
+		}
+	}
+
+	return nil
+}
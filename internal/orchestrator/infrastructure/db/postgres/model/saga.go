@@ -0,0 +1,25 @@
+package model
+
+type Saga struct {
+	PurchaseID  uint64 `gorm:"primaryKey"`
+	Step        string `gorm:"not null"`
+	Status      string `gorm:"not null;index"`
+	Reason      string
+	Attempts    []byte `gorm:"type:jsonb;not null"`
+	Payload     []byte `gorm:"type:bytea;not null"`
+	ContentType string `gorm:"not null;default:''"`
+	Deadline    int64  `gorm:"not null;default:0;index"`
+	Version     uint64 `gorm:"not null;default:0"`
+	UpdatedAt   int64  `gorm:"autoUpdateTime:milli"`
+	CreatedAt   int64  `gorm:"autoCreateTime:milli"`
+}
+
+// SagaTransition is an entry of the saga history, written with each update of the saga
+type SagaTransition struct {
+	ID         uint64 `gorm:"primaryKey;autoIncrement"`
+	PurchaseID uint64 `gorm:"not null;index"`
+	Step       string `gorm:"not null"`
+	Status     string `gorm:"not null"`
+	Reason     string
+	CreatedAt  int64 `gorm:"autoCreateTime:milli"`
+}
@@ -0,0 +1,33 @@
+package dto
+
+import "time"
+
+type ListSagas struct {
+	Status string `form:"status"`
+	Limit  int    `form:"limit,default=20" binding:"min=1,max=100"`
+	Offset int    `form:"offset,default=0" binding:"min=0"`
+}
+
+type Saga struct {
+	PurchaseID uint64            `json:"purchase_id"`
+	Step       string            `json:"step"`
+	Status     string            `json:"status"`
+	Reason     string            `json:"reason,omitempty"`
+	Attempts   map[string]uint64 `json:"attempts,omitempty"`
+	Deadline   *time.Time        `json:"deadline,omitempty"`
+	Version    uint64            `json:"version"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	History    *[]SagaTransition `json:"history,omitempty"`
+}
+
+type SagaTransition struct {
+	Step      string    `json:"step"`
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SagaAction struct {
+	Reason string `json:"reason" binding:"required"`
+}
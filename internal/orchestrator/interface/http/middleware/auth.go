@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/valueobject"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/infrastructure/grpc"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"net/http"
+	"strings"
+)
+
+const (
+	ErrTokenExpired = "token expired"
+	ErrForbidden    = "forbidden"
+)
+
+type JWTAuthMW struct {
+	authSvc grpc.AuthService
+	logger  logger.Logger
+}
+
+func NewJWTAuthMW(authSvc grpc.AuthService, logger logger.Logger) *JWTAuthMW {
+	return &JWTAuthMW{
+		authSvc: authSvc,
+		logger:  logger,
+	}
+}
+
+// AdminMiddleware only lets through the requests of accounts with the admin role
+func (m *JWTAuthMW) AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accessToken := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if accessToken == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		authResponse, err := m.authSvc.Auth(c.Request.Context(), accessToken)
+		if err != nil {
+			m.logger.Errorf("auth middleware: %v", err)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		if authResponse.Expired {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": ErrTokenExpired})
+			c.Abort()
+			return
+		}
+
+		if authResponse.Role != valueobject.RoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": ErrForbidden})
+			c.Abort()
+			return
+		}
+
+		c.Set("customer_id", authResponse.CustomerID)
+		c.Next()
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/valueobject"
+	"github.com/scul0405/saga-orchestration/pkg/appconfig"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeAuthService struct {
+	responses map[string]*valueobject.AuthResponse
+}
+
+func (s *fakeAuthService) Auth(_ context.Context, accessToken string) (*valueobject.AuthResponse, error) {
+	response, ok := s.responses[accessToken]
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	return response, nil
+}
+
+func TestAdminMiddleware(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+
+	apiLogger := logger.NewApiLogger(&appconfig.App{Logger: appconfig.Logger{Development: true, Encoding: "json"}})
+	apiLogger.InitLogger()
+
+	authSvc := &fakeAuthService{responses: map[string]*valueobject.AuthResponse{
+		"admin":    {CustomerID: 1, Role: valueobject.RoleAdmin},
+		"customer": {CustomerID: 2, Role: "customer"},
+		"expired":  {CustomerID: 1, Role: valueobject.RoleAdmin, Expired: true},
+	}}
+
+	engine := gin.New()
+	engine.Use(NewJWTAuthMW(authSvc, apiLogger).AdminMiddleware())
+	engine.GET("/sagas", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	testCases := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "admin", token: "admin", status: http.StatusOK},
+		{name: "customer", token: "customer", status: http.StatusForbidden},
+		{name: "expired", token: "expired", status: http.StatusUnauthorized},
+		{name: "invalid", token: "invalid", status: http.StatusUnauthorized},
+		{name: "missing", token: "", status: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/sagas", nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package http
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/app"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/aggregate"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/entity"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/infrastructure/grpc"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/interface/http/dto"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/saga"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+var (
+	OkMessage           = "success"
+	ErrInvalidID        = "invalid id"
+	ErrInvalidJSON      = "invalid json"
+	ErrInvalidQuery     = "invalid query"
+	ErrInternal         = "internal error"
+	ErrSagaNotFound     = "saga not found"
+	ErrSagaFinished     = "saga is finished"
+	ErrActionNotAllowed = "action not allowed in the current saga status"
+	ErrStaleSaga        = "saga was updated concurrently, try again"
+)
+
+type Router struct {
+	app     app.App
+	authSvc grpc.AuthService
+	logger  logger.Logger
+}
+
+func NewRouter(app app.App, authSvc grpc.AuthService, logger logger.Logger) *Router {
+	return &Router{
+		app:     app,
+		authSvc: authSvc,
+		logger:  logger,
+	}
+}
+
+func (r *Router) ListSagas(c *gin.Context) {
+	var req dto.ListSagas
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidQuery})
+		return
+	}
+
+	sagas, err := r.app.ListSagas(c, req.Status, req.Limit, req.Offset)
+	if err != nil {
+		r.logger.Errorf("ListSagas: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
+		return
+	}
+
+	resp := make([]dto.Saga, len(*sagas))
+	for i := range *sagas {
+		resp[i] = toSagaDTO(&(*sagas)[i], nil)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (r *Router) GetSaga(c *gin.Context) {
+	id, ok := r.extractID(c)
+	if !ok {
+		return
+	}
+
+	s, transitions, err := r.app.GetSaga(c, id)
+	if err != nil {
+		r.handleError(c, "GetSaga", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toSagaDTO(s, transitions))
+}
+
+func (r *Router) RetrySaga(c *gin.Context) {
+	id, ok := r.extractID(c)
+	if !ok {
+		return
+	}
+
+	if err := r.app.RetrySaga(c, id); err != nil {
+		r.handleError(c, "RetrySaga", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": OkMessage})
+}
+
+func (r *Router) CompensateSaga(c *gin.Context) {
+	id, ok := r.extractID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SagaAction
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidJSON})
+		return
+	}
+
+	if err := r.app.CompensateSaga(c, id, req.Reason); err != nil {
+		r.handleError(c, "CompensateSaga", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": OkMessage})
+}
+
+func (r *Router) ResolveSaga(c *gin.Context) {
+	id, ok := r.extractID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SagaAction
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidJSON})
+		return
+	}
+
+	if err := r.app.ResolveSaga(c, id, req.Reason); err != nil {
+		r.handleError(c, "ResolveSaga", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": OkMessage})
+}
+
+func (r *Router) extractID(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidID})
+		return 0, false
+	}
+
+	return id, true
+}
+
+func (r *Router) handleError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrSagaNotFound})
+	case errors.Is(err, saga.ErrSagaFinished):
+		c.JSON(http.StatusConflict, gin.H{"error": ErrSagaFinished})
+	case errors.Is(err, saga.ErrActionNotAllowed):
+		c.JSON(http.StatusConflict, gin.H{"error": ErrActionNotAllowed})
+	case errors.Is(err, domain.ErrStaleSaga):
+		c.JSON(http.StatusConflict, gin.H{"error": ErrStaleSaga})
+	default:
+		r.logger.Errorf("%s: %v", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
+	}
+}
+
+func toSagaDTO(s *aggregate.Saga, transitions *[]entity.SagaTransition) dto.Saga {
+	resp := dto.Saga{
+		PurchaseID: s.ID,
+		Step:       s.Step,
+		Status:     s.Status,
+		Reason:     s.Reason,
+		Attempts:   s.Attempts,
+		Version:    s.Version,
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
+	}
+	if !s.Deadline.IsZero() {
+		resp.Deadline = &s.Deadline
+	}
+
+	if transitions != nil {
+		history := make([]dto.SagaTransition, len(*transitions))
+		for i, t := range *transitions {
+			history[i] = dto.SagaTransition{
+				Step:      t.Step,
+				Status:    t.Status,
+				Reason:    t.Reason,
+				CreatedAt: t.CreatedAt,
+			}
+		}
+		resp.History = &history
+	}
+
+	return resp
+}
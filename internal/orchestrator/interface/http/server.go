@@ -0,0 +1,81 @@
+package http
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/scul0405/saga-orchestration/cmd/orchestrator/config"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/interface/http/middleware"
+	"github.com/scul0405/saga-orchestration/internal/pkg/health"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"net/http"
+)
+
+type Server struct {
+	config     config.HTTP
+	logger     logger.Logger
+	Engine     *gin.Engine
+	Router     *Router
+	health     *health.Checker
+	httpServer *http.Server
+}
+
+func NewHTTPServer(config config.HTTP, logger logger.Logger, engine *gin.Engine, router *Router, health *health.Checker) *Server {
+	return &Server{
+		config: config,
+		logger: logger,
+		Engine: engine,
+		Router: router,
+		health: health,
+	}
+}
+
+func NewEngine(config config.HTTP) *gin.Engine {
+	gin.SetMode(config.Mode)
+
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	engine.Use(otelgin.Middleware("orchestrator_service"))
+	engine.Use(gin.Logger()) // TODO: replace with custom logger
+	engine.Use(middleware.CORSMiddleware())
+
+	return engine
+}
+
+func (srv *Server) InitRoutes() {
+	mw := middleware.NewJWTAuthMW(srv.Router.authSvc, srv.logger)
+
+	srv.Engine.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	srv.Engine.GET("/healthz", gin.WrapH(health.LivenessHandler()))
+	srv.Engine.GET("/readyz", gin.WrapH(srv.health.ReadinessHandler()))
+
+	adminGroup := srv.Engine.Group("/api/v1/admin/sagas")
+	adminGroup.Use(mw.AdminMiddleware())
+	{
+		adminGroup.GET("", srv.Router.ListSagas)
+		adminGroup.GET("/:id", srv.Router.GetSaga)
+		adminGroup.POST("/:id/retry", srv.Router.RetrySaga)
+		adminGroup.POST("/:id/compensate", srv.Router.CompensateSaga)
+		adminGroup.POST("/:id/resolve", srv.Router.ResolveSaga)
+	}
+}
+
+func (srv *Server) Run() error {
+	srv.InitRoutes()
+
+	srv.httpServer = &http.Server{
+		Addr:    ":" + srv.config.Port,
+		Handler: srv.Engine,
+	}
+
+	if err := srv.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+func (srv *Server) GracefulStop(ctx context.Context) error {
+	return srv.httpServer.Shutdown(ctx)
+}
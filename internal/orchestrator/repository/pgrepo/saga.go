@@ -0,0 +1,229 @@
+package pgrepo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/aggregate"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/entity"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/infrastructure/db/postgres/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+type SagaRepository interface {
+	GetSaga(ctx context.Context, purchaseID uint64) (*aggregate.Saga, error)
+	ListSagasByStatus(ctx context.Context, statuses ...string) (*[]aggregate.Saga, error)
+	ListSagas(ctx context.Context, status string, limit, offset int) (*[]aggregate.Saga, error)
+	ListSagaTransitions(ctx context.Context, purchaseID uint64) (*[]entity.SagaTransition, error)
+	ListExpiredSagas(ctx context.Context, now time.Time) (*[]aggregate.Saga, error)
+	CreateSaga(ctx context.Context, saga *aggregate.Saga) error
+	UpdateSaga(ctx context.Context, saga *aggregate.Saga) error
+}
+
+type sagaRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewSagaRepository(db *gorm.DB) SagaRepository {
+	return &sagaRepositoryImpl{db: db}
+}
+
+func (r *sagaRepositoryImpl) GetSaga(ctx context.Context, purchaseID uint64) (*aggregate.Saga, error) {
+	var saga model.Saga
+	if err := r.db.WithContext(ctx).Where("purchase_id = ?", purchaseID).First(&saga).Error; err != nil {
+		return nil, err
+	}
+
+	return decodeSaga(&saga)
+}
+
+func (r *sagaRepositoryImpl) ListSagasByStatus(ctx context.Context, statuses ...string) (*[]aggregate.Saga, error) {
+	var sagas []model.Saga
+	if err := r.db.WithContext(ctx).Where("status IN ?", statuses).Order("created_at").Find(&sagas).Error; err != nil {
+		return nil, err
+	}
+
+	return decodeSagas(sagas)
+}
+
+// ListSagas returns the most recently updated sagas, only the ones with the given status when it is not empty
+func (r *sagaRepositoryImpl) ListSagas(ctx context.Context, status string, limit, offset int) (*[]aggregate.Saga, error) {
+	db := r.db.WithContext(ctx)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+
+	var sagas []model.Saga
+	if err := db.Order("updated_at DESC").Limit(limit).Offset(offset).Find(&sagas).Error; err != nil {
+		return nil, err
+	}
+
+	return decodeSagas(sagas)
+}
+
+// ListSagaTransitions returns the history of the saga, oldest first
+func (r *sagaRepositoryImpl) ListSagaTransitions(ctx context.Context, purchaseID uint64) (*[]entity.SagaTransition, error) {
+	var transitions []model.SagaTransition
+	if err := r.db.WithContext(ctx).Where("purchase_id = ?", purchaseID).Order("id").Find(&transitions).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]entity.SagaTransition, len(transitions))
+	for i, transition := range transitions {
+		result[i] = entity.SagaTransition{
+			Step:      transition.Step,
+			Status:    transition.Status,
+			Reason:    transition.Reason,
+			CreatedAt: time.UnixMilli(transition.CreatedAt),
+		}
+	}
+
+	return &result, nil
+}
+
+// ListExpiredSagas returns the sagas waiting for a reply whose deadline has passed
+func (r *sagaRepositoryImpl) ListExpiredSagas(ctx context.Context, now time.Time) (*[]aggregate.Saga, error) {
+	var sagas []model.Saga
+	if err := r.db.WithContext(ctx).Where("deadline > 0 AND deadline <= ?", now.UnixMilli()).Order("deadline").Find(&sagas).Error; err != nil {
+		return nil, err
+	}
+
+	return decodeSagas(sagas)
+}
+
+func decodeSagas(sagas []model.Saga) (*[]aggregate.Saga, error) {
+	result := make([]aggregate.Saga, len(sagas))
+	for i := range sagas {
+		saga, err := decodeSaga(&sagas[i])
+		if err != nil {
+			return nil, err
+		}
+		result[i] = *saga
+	}
+
+	return &result, nil
+}
+
+func (r *sagaRepositoryImpl) CreateSaga(ctx context.Context, saga *aggregate.Saga) error {
+	entry, err := encodeSaga(saga)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+		return tx.Create(encodeTransition(saga)).Error
+	})
+	if err != nil {
+		var pgError *pgconn.PgError
+		if errors.As(err, &pgError) && pgError.Code == "23505" {
+			return domain.ErrDuplicateEntry
+		}
+		return err
+	}
+
+	return nil
+}
+
+// UpdateSaga persists the current state of the saga and appends it to the saga history.
+// The update is rejected with domain.ErrStaleSaga when the saga was changed since it was read.
+func (r *sagaRepositoryImpl) UpdateSaga(ctx context.Context, saga *aggregate.Saga) error {
+	attempts, err := json.Marshal(saga.Attempts)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Saga{}).
+			Where("purchase_id = ? AND version = ?", saga.ID, saga.Version).
+			Updates(map[string]interface{}{
+				"step":     saga.Step,
+				"status":   saga.Status,
+				"reason":   saga.Reason,
+				"attempts": attempts,
+				"deadline": encodeDeadline(saga.Deadline),
+				"version":  saga.Version + 1,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrStaleSaga
+		}
+
+		return tx.Create(encodeTransition(saga)).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	saga.Version++
+	return nil
+}
+
+func encodeSaga(saga *aggregate.Saga) (*model.Saga, error) {
+	attempts, err := json.Marshal(saga.Attempts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Saga{
+		PurchaseID:  saga.ID,
+		Step:        saga.Step,
+		Status:      saga.Status,
+		Reason:      saga.Reason,
+		Attempts:    attempts,
+		Payload:     saga.Payload,
+		ContentType: saga.ContentType,
+		Deadline:    encodeDeadline(saga.Deadline),
+	}, nil
+}
+
+func encodeTransition(saga *aggregate.Saga) *model.SagaTransition {
+	return &model.SagaTransition{
+		PurchaseID: saga.ID,
+		Step:       saga.Step,
+		Status:     saga.Status,
+		Reason:     saga.Reason,
+	}
+}
+
+func decodeSaga(saga *model.Saga) (*aggregate.Saga, error) {
+	attempts := make(map[string]uint64)
+	if err := json.Unmarshal(saga.Attempts, &attempts); err != nil {
+		return nil, err
+	}
+
+	return &aggregate.Saga{
+		ID:          saga.PurchaseID,
+		Step:        saga.Step,
+		Status:      saga.Status,
+		Reason:      saga.Reason,
+		Attempts:    attempts,
+		Payload:     saga.Payload,
+		ContentType: saga.ContentType,
+		Deadline:    decodeDeadline(saga.Deadline),
+		Version:     saga.Version,
+		UpdatedAt:   time.UnixMilli(saga.UpdatedAt),
+		CreatedAt:   time.UnixMilli(saga.CreatedAt),
+	}, nil
+}
+
+func encodeDeadline(deadline time.Time) int64 {
+	if deadline.IsZero() {
+		return 0
+	}
+	return deadline.UnixMilli()
+}
+
+func decodeDeadline(deadline int64) time.Time {
+	if deadline == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(deadline)
+}
@@ -0,0 +1,120 @@
+package saga
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrEmptyDefinition = errors.New("saga definition has no step")
+)
+
+// Step declares a saga step: the command topic the participant consumes, the handler name it
+// replies with and, optionally, the compensation which undoes the step.
+// When Timeout is set, the command (or compensation) is re-sent up to MaxRetries times
+// if the participant does not reply in time.
+type Step struct {
+	Name                string
+	CommandTopic        string
+	ReplyHandler        string
+	CompensationTopic   string
+	CompensationHandler string
+	Timeout             time.Duration
+	MaxRetries          uint64
+}
+
+func (s Step) hasCompensation() bool {
+	return s.CompensationTopic != ""
+}
+
+// Definition is the validated, ordered list of steps of a saga
+type Definition struct {
+	steps    []Step
+	handlers map[string]handlerRef
+}
+
+// handlerRef points a reply handler name to the step it answers
+type handlerRef struct {
+	index        int
+	compensation bool
+}
+
+// DefinitionBuilder builds a saga definition step by step
+type DefinitionBuilder struct {
+	steps []Step
+}
+
+func NewDefinitionBuilder() *DefinitionBuilder {
+	return &DefinitionBuilder{}
+}
+
+// AddStep appends a step, steps are executed in the order they are added
+// and compensated in the reverse order
+func (b *DefinitionBuilder) AddStep(step Step) *DefinitionBuilder {
+	b.steps = append(b.steps, step)
+	return b
+}
+
+func (b *DefinitionBuilder) Build() (*Definition, error) {
+	if len(b.steps) == 0 {
+		return nil, ErrEmptyDefinition
+	}
+
+	names := make(map[string]struct{}, len(b.steps))
+	handlers := make(map[string]handlerRef, 2*len(b.steps))
+	for i, step := range b.steps {
+		if step.Name == "" || step.CommandTopic == "" || step.ReplyHandler == "" {
+			return nil, fmt.Errorf("saga step %d: name, command topic and reply handler are required", i)
+		}
+		if (step.CompensationTopic == "") != (step.CompensationHandler == "") {
+			return nil, fmt.Errorf("saga step %s: compensation topic and handler must be set together", step.Name)
+		}
+
+		if _, ok := names[step.Name]; ok {
+			return nil, fmt.Errorf("saga step %s: duplicated step name", step.Name)
+		}
+		names[step.Name] = struct{}{}
+
+		if _, ok := handlers[step.ReplyHandler]; ok {
+			return nil, fmt.Errorf("saga step %s: duplicated handler %s", step.Name, step.ReplyHandler)
+		}
+		handlers[step.ReplyHandler] = handlerRef{index: i}
+
+		if step.hasCompensation() {
+			if _, ok := handlers[step.CompensationHandler]; ok {
+				return nil, fmt.Errorf("saga step %s: duplicated handler %s", step.Name, step.CompensationHandler)
+			}
+			handlers[step.CompensationHandler] = handlerRef{index: i, compensation: true}
+		}
+	}
+
+	steps := make([]Step, len(b.steps))
+	copy(steps, b.steps)
+
+	return &Definition{
+		steps:    steps,
+		handlers: handlers,
+	}, nil
+}
+
+// Steps returns the steps of the definition in execution order
+func (d *Definition) Steps() []Step {
+	steps := make([]Step, len(d.steps))
+	copy(steps, d.steps)
+	return steps
+}
+
+// StepIndex returns the position of the named step
+func (d *Definition) StepIndex(name string) (int, bool) {
+	for i, step := range d.steps {
+		if step.Name == name {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func (d *Definition) isLastStep(index int) bool {
+	return index == len(d.steps)-1
+}
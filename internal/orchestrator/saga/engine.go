@@ -0,0 +1,433 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/scul0405/saga-orchestration/internal/common"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/aggregate"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/event"
+	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"github.com/segmentio/kafka-go"
+	"time"
+)
+
+var (
+	ErrSagaFinished     = errors.New("saga is finished")
+	ErrActionNotAllowed = errors.New("action not allowed in the current saga status")
+)
+
+// Reply is a participant answer to a saga command or compensation
+type Reply struct {
+	SagaID  uint64
+	Handler string
+	Success bool
+	Error   string
+}
+
+// TransitionListener is notified after each persisted transition of a saga
+type TransitionListener func(ctx context.Context, saga *aggregate.Saga) error
+
+// Engine drives the sagas of a definition: it sends the step commands forward and,
+// once a step fails, sends the compensations of the executed steps in reverse order
+type Engine interface {
+	Start(ctx context.Context, sagaID uint64, payload []byte, contentType string) error
+	HandleReply(ctx context.Context, reply *Reply) error
+	Resume(ctx context.Context) error
+	Sweep(ctx context.Context) error
+	Retry(ctx context.Context, sagaID uint64) error
+	Compensate(ctx context.Context, sagaID uint64, reason string) error
+	Resolve(ctx context.Context, sagaID uint64, reason string) error
+}
+
+type engine struct {
+	logger     logger.Logger
+	definition *Definition
+	producer   kafkaClient.Producer
+	sagaRepo   domain.SagaRepository
+	listener   TransitionListener
+}
+
+func NewEngine(
+	logger logger.Logger,
+	definition *Definition,
+	producer kafkaClient.Producer,
+	sagaRepo domain.SagaRepository,
+	listener TransitionListener) Engine {
+	return &engine{
+		logger:     logger,
+		definition: definition,
+		producer:   producer,
+		sagaRepo:   sagaRepo,
+		listener:   listener,
+	}
+}
+
+func (e *engine) Start(ctx context.Context, sagaID uint64, payload []byte, contentType string) error {
+	first := e.definition.steps[0]
+
+	saga := &aggregate.Saga{
+		ID:          sagaID,
+		Step:        first.Name,
+		Status:      event.StatusExecute,
+		Attempts:    map[string]uint64{first.Name: 1},
+		Payload:     payload,
+		ContentType: contentType,
+		Deadline:    deadline(first),
+	}
+	if err := e.sagaRepo.CreateSaga(ctx, saga); err != nil {
+		if errors.Is(err, domain.ErrDuplicateEntry) {
+			return e.restart(ctx, sagaID)
+		}
+		return err
+	}
+
+	if err := e.listener(ctx, saga); err != nil {
+		return err
+	}
+
+	return e.sendCommand(ctx, saga, first)
+}
+
+// restart handles a redelivered start message: the command of the first step may not have been sent,
+// it is sent again while no reply moved the saga on, participants ignore a command they already processed
+func (e *engine) restart(ctx context.Context, sagaID uint64) error {
+	saga, err := e.sagaRepo.GetSaga(ctx, sagaID)
+	if err != nil {
+		return err
+	}
+
+	first := e.definition.steps[0]
+	if saga.Step != first.Name || saga.Status != event.StatusExecute {
+		e.logger.Warnf("Saga.Start: saga %v already exists at step %s with status %s", sagaID, saga.Step, saga.Status)
+		return nil
+	}
+
+	e.logger.Warnf("Saga.Start: saga %v already exists, send the command of step %s again", sagaID, first.Name)
+	return e.sendCommand(ctx, saga, first)
+}
+
+func (e *engine) HandleReply(ctx context.Context, reply *Reply) error {
+	ref, ok := e.definition.handlers[reply.Handler]
+	if !ok {
+		return fmt.Errorf("%w: handle reply: unknown handler: %s", kafkaClient.ErrPoisonMessage, reply.Handler)
+	}
+
+	saga, err := e.sagaRepo.GetSaga(ctx, reply.SagaID)
+	if err != nil {
+		return err
+	}
+
+	step := e.definition.steps[ref.index]
+
+	if ref.compensation {
+		if saga.Step != step.Name || saga.Status != event.StatusRollback {
+			e.logger.Warnf("Saga.HandleReply: ignore stale %s reply of saga %v at step %s with status %s", reply.Handler, saga.ID, saga.Step, saga.Status)
+			return nil
+		}
+
+		if !reply.Success {
+			saga.Reason = reply.Error
+			return e.transit(ctx, saga, step.Name, event.StatusRollbackFailed)
+		}
+
+		return e.compensate(ctx, saga, ref.index-1)
+	}
+
+	if saga.Step != step.Name || saga.Status != event.StatusExecute {
+		e.logger.Warnf("Saga.HandleReply: ignore stale %s reply of saga %v at step %s with status %s", reply.Handler, saga.ID, saga.Step, saga.Status)
+		return nil
+	}
+
+	if !reply.Success {
+		saga.Reason = reply.Error
+		if err = e.transit(ctx, saga, step.Name, event.StatusFailed); err != nil {
+			return err
+		}
+
+		// The failed step is compensated as well, participants may have partially applied it
+		return e.compensate(ctx, saga, ref.index)
+	}
+
+	if err = e.transit(ctx, saga, step.Name, event.StatusSucess); err != nil {
+		return err
+	}
+
+	if e.definition.isLastStep(ref.index) {
+		return nil
+	}
+
+	return e.execute(ctx, saga, ref.index+1)
+}
+
+// Resume re-drives the sagas which were in flight when the orchestrator stopped
+func (e *engine) Resume(ctx context.Context) error {
+	sagas, err := e.sagaRepo.ListSagasByStatus(ctx, event.StatusExecute, event.StatusSucess, event.StatusFailed, event.StatusRollback)
+	if err != nil {
+		return err
+	}
+
+	for i := range *sagas {
+		saga := &(*sagas)[i]
+
+		index, ok := e.definition.StepIndex(saga.Step)
+		if !ok {
+			e.logger.Errorf("Saga.Resume: saga %v is at unknown step %s", saga.ID, saga.Step)
+			continue
+		}
+
+		switch saga.Status {
+		case event.StatusExecute:
+			err = e.execute(ctx, saga, index)
+		case event.StatusSucess:
+			if e.definition.isLastStep(index) {
+				continue
+			}
+			err = e.execute(ctx, saga, index+1)
+		case event.StatusFailed, event.StatusRollback:
+			err = e.compensate(ctx, saga, index)
+		}
+		if err != nil {
+			return err
+		}
+
+		e.logger.Infof("Saga.Resume: resumed saga %v at step %s with status %s", saga.ID, saga.Step, saga.Status)
+	}
+
+	return nil
+}
+
+// Sweep handles the sagas whose participant did not reply before the step deadline.
+// The command or compensation is re-sent until the step retries are exhausted, then a timed out
+// command fails the saga and starts the compensation, while a timed out compensation fails the rollback.
+func (e *engine) Sweep(ctx context.Context) error {
+	sagas, err := e.sagaRepo.ListExpiredSagas(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for i := range *sagas {
+		saga := &(*sagas)[i]
+		if err = e.expire(ctx, saga); err != nil {
+			// The saga may have moved on concurrently, it is checked again on the next sweep
+			e.logger.Errorf("Saga.Sweep: saga %v, err: %v", saga.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Retry re-drives the saga from its current step regardless of the step retries: the command of a step waiting
+// for its participant is sent again, the compensation of a failed or rolling back step is sent again,
+// and a saga stopped after a successful step moves on to the next one.
+// The re-sent messages carry the retry header, so that the participants process again the ones they rejected.
+func (e *engine) Retry(ctx context.Context, sagaID uint64) error {
+	saga, index, err := e.getActiveSaga(ctx, sagaID)
+	if err != nil {
+		return err
+	}
+
+	retry := kafka.Header{Key: common.RetryHeader, Value: []byte("true")}
+	switch saga.Status {
+	case event.StatusExecute:
+		return e.execute(ctx, saga, index, retry)
+	case event.StatusSucess:
+		return e.execute(ctx, saga, index+1)
+	case event.StatusFailed, event.StatusRollback, event.StatusRollbackFailed:
+		return e.compensate(ctx, saga, index, retry)
+	}
+	return ErrActionNotAllowed
+}
+
+// Compensate fails the saga at its current step and rolls back the executed steps,
+// a saga which is already rolling back sends the compensation of its current step again
+func (e *engine) Compensate(ctx context.Context, sagaID uint64, reason string) error {
+	saga, index, err := e.getActiveSaga(ctx, sagaID)
+	if err != nil {
+		return err
+	}
+
+	switch saga.Status {
+	case event.StatusExecute, event.StatusSucess:
+		saga.Reason = reason
+		if err = e.transit(ctx, saga, saga.Step, event.StatusFailed); err != nil {
+			return err
+		}
+		return e.compensate(ctx, saga, index)
+	case event.StatusFailed, event.StatusRollback, event.StatusRollbackFailed:
+		return e.compensate(ctx, saga, index)
+	}
+	return ErrActionNotAllowed
+}
+
+// Resolve closes a saga which an operator settled by hand, the late replies of its participants are ignored
+func (e *engine) Resolve(ctx context.Context, sagaID uint64, reason string) error {
+	saga, _, err := e.getActiveSaga(ctx, sagaID)
+	if err != nil {
+		return err
+	}
+
+	saga.Reason = reason
+	return e.transit(ctx, saga, saga.Step, event.StatusResolved)
+}
+
+// getActiveSaga returns the saga with the index of its current step, or ErrSagaFinished once nothing follows
+func (e *engine) getActiveSaga(ctx context.Context, sagaID uint64) (*aggregate.Saga, int, error) {
+	saga, err := e.sagaRepo.GetSaga(ctx, sagaID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	index, ok := e.definition.StepIndex(saga.Step)
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown step %s", saga.Step)
+	}
+
+	switch {
+	case saga.Status == event.StatusCompensated, saga.Status == event.StatusResolved,
+		saga.Status == event.StatusSucess && e.definition.isLastStep(index):
+		return nil, 0, ErrSagaFinished
+	}
+
+	return saga, index, nil
+}
+
+func (e *engine) expire(ctx context.Context, saga *aggregate.Saga) error {
+	index, ok := e.definition.StepIndex(saga.Step)
+	if !ok {
+		return fmt.Errorf("unknown step %s", saga.Step)
+	}
+	step := e.definition.steps[index]
+
+	switch saga.Status {
+	case event.StatusExecute:
+		attempts := saga.Attempts[step.Name]
+		if attempts <= step.MaxRetries {
+			e.logger.Warnf("Saga.Sweep: saga %v timed out at step %s, retry %d/%d", saga.ID, step.Name, attempts, step.MaxRetries)
+			return e.execute(ctx, saga, index)
+		}
+
+		saga.Reason = fmt.Sprintf("step %s timed out after %d attempts", step.Name, attempts)
+		if err := e.transit(ctx, saga, step.Name, event.StatusFailed); err != nil {
+			return err
+		}
+
+		return e.compensate(ctx, saga, index)
+	case event.StatusRollback:
+		attempts := saga.Attempts[compensationKey(step.Name)]
+		if attempts <= step.MaxRetries {
+			e.logger.Warnf("Saga.Sweep: saga %v compensation timed out at step %s, retry %d/%d", saga.ID, step.Name, attempts, step.MaxRetries)
+			return e.compensate(ctx, saga, index)
+		}
+
+		saga.Reason = fmt.Sprintf("compensation of step %s timed out after %d attempts", step.Name, attempts)
+		return e.transit(ctx, saga, step.Name, event.StatusRollbackFailed)
+	default:
+		return fmt.Errorf("unexpected deadline with status %s", saga.Status)
+	}
+}
+
+func (e *engine) execute(ctx context.Context, saga *aggregate.Saga, index int, headers ...kafka.Header) error {
+	step := e.definition.steps[index]
+
+	saga.Reason = ""
+
+	if err := e.transit(ctx, saga, step.Name, event.StatusExecute); err != nil {
+		return err
+	}
+
+	return e.sendCommand(ctx, saga, step, headers...)
+}
+
+// compensate sends the compensation of the closest step at or before index which declares one.
+// When no step is left to compensate, the saga is finished.
+func (e *engine) compensate(ctx context.Context, saga *aggregate.Saga, index int, extraHeaders ...kafka.Header) error {
+	for i := index; i >= 0; i-- {
+		step := e.definition.steps[i]
+		if !step.hasCompensation() {
+			continue
+		}
+
+		if err := e.transit(ctx, saga, step.Name, event.StatusRollback); err != nil {
+			return err
+		}
+
+		headers := append(payloadHeaders(saga), extraHeaders...)
+		if saga.Reason != "" {
+			headers = append(headers, kafka.Header{Key: common.ReasonHeader, Value: []byte(saga.Reason)})
+		}
+
+		return e.producer.PublishMessage(ctx, kafka.Message{
+			Topic:   step.CompensationTopic,
+			Key:     common.PurchaseKey(saga.ID),
+			Value:   saga.Payload,
+			Headers: headers,
+		})
+	}
+
+	saga.Status = event.StatusCompensated
+	saga.Deadline = time.Time{}
+	if err := e.sagaRepo.UpdateSaga(ctx, saga); err != nil {
+		return err
+	}
+
+	return e.listener(ctx, saga)
+}
+
+func (e *engine) sendCommand(ctx context.Context, saga *aggregate.Saga, step Step, headers ...kafka.Header) error {
+	return e.producer.PublishMessage(ctx, kafka.Message{
+		Topic:   step.CommandTopic,
+		Key:     common.PurchaseKey(saga.ID),
+		Value:   saga.Payload,
+		Headers: append(payloadHeaders(saga), headers...),
+	})
+}
+
+// payloadHeaders returns the content type header of the saga payload,
+// the payload of the sagas started before the Kafka codec is sent without it
+func payloadHeaders(saga *aggregate.Saga) []kafka.Header {
+	if saga.ContentType == "" {
+		return nil
+	}
+
+	return []kafka.Header{{Key: kafkaClient.ContentTypeHeader, Value: []byte(saga.ContentType)}}
+}
+
+// transit persists the saga at the given step and status, then notifies the listener.
+// Sending the command or the compensation of a step counts an attempt and arms the step deadline.
+func (e *engine) transit(ctx context.Context, saga *aggregate.Saga, step, status string) error {
+	saga.Step = step
+	saga.Status = status
+	saga.Deadline = time.Time{}
+
+	switch status {
+	case event.StatusExecute:
+		saga.Attempts[step]++
+	case event.StatusRollback:
+		saga.Attempts[compensationKey(step)]++
+	}
+	if status == event.StatusExecute || status == event.StatusRollback {
+		index, _ := e.definition.StepIndex(step)
+		saga.Deadline = deadline(e.definition.steps[index])
+	}
+
+	if err := e.sagaRepo.UpdateSaga(ctx, saga); err != nil {
+		return err
+	}
+
+	return e.listener(ctx, saga)
+}
+
+// compensationKey is the attempts key counting the compensations sent for a step
+func compensationKey(step string) string {
+	return "compensate:" + step
+}
+
+func deadline(step Step) time.Time {
+	if step.Timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(step.Timeout)
+}
@@ -0,0 +1,368 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"github.com/scul0405/saga-orchestration/internal/common"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/aggregate"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/entity"
+	"github.com/scul0405/saga-orchestration/internal/orchestrator/domain/event"
+	"github.com/scul0405/saga-orchestration/pkg/appconfig"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+type fakeSagaRepository struct {
+	sagas map[uint64]aggregate.Saga
+}
+
+func (r *fakeSagaRepository) GetSaga(_ context.Context, purchaseID uint64) (*aggregate.Saga, error) {
+	saga, ok := r.sagas[purchaseID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	attempts := make(map[string]uint64, len(saga.Attempts))
+	for k, v := range saga.Attempts {
+		attempts[k] = v
+	}
+	saga.Attempts = attempts
+
+	return &saga, nil
+}
+
+func (r *fakeSagaRepository) ListSagasByStatus(_ context.Context, statuses ...string) (*[]aggregate.Saga, error) {
+	var sagas []aggregate.Saga
+	for id := range r.sagas {
+		for _, status := range statuses {
+			if r.sagas[id].Status == status {
+				saga, _ := r.GetSaga(context.Background(), id)
+				sagas = append(sagas, *saga)
+			}
+		}
+	}
+	return &sagas, nil
+}
+
+func (r *fakeSagaRepository) ListSagas(_ context.Context, status string, _, _ int) (*[]aggregate.Saga, error) {
+	if status == "" {
+		var sagas []aggregate.Saga
+		for id := range r.sagas {
+			saga, _ := r.GetSaga(context.Background(), id)
+			sagas = append(sagas, *saga)
+		}
+		return &sagas, nil
+	}
+	return r.ListSagasByStatus(context.Background(), status)
+}
+
+func (r *fakeSagaRepository) ListSagaTransitions(_ context.Context, _ uint64) (*[]entity.SagaTransition, error) {
+	return &[]entity.SagaTransition{}, nil
+}
+
+func (r *fakeSagaRepository) ListExpiredSagas(_ context.Context, now time.Time) (*[]aggregate.Saga, error) {
+	var sagas []aggregate.Saga
+	for id := range r.sagas {
+		deadline := r.sagas[id].Deadline
+		if !deadline.IsZero() && !deadline.After(now) {
+			saga, _ := r.GetSaga(context.Background(), id)
+			sagas = append(sagas, *saga)
+		}
+	}
+	return &sagas, nil
+}
+
+func (r *fakeSagaRepository) CreateSaga(_ context.Context, saga *aggregate.Saga) error {
+	if _, ok := r.sagas[saga.ID]; ok {
+		return domain.ErrDuplicateEntry
+	}
+	r.sagas[saga.ID] = *saga
+	return nil
+}
+
+func (r *fakeSagaRepository) UpdateSaga(_ context.Context, saga *aggregate.Saga) error {
+	stored, ok := r.sagas[saga.ID]
+	if !ok || stored.Version != saga.Version {
+		return domain.ErrStaleSaga
+	}
+	saga.Version++
+	r.sagas[saga.ID] = *saga
+	return nil
+}
+
+// expire moves the deadline of the saga into the past
+func (r *fakeSagaRepository) expire(id uint64) {
+	saga := r.sagas[id]
+	saga.Deadline = time.Now().Add(-time.Second)
+	r.sagas[id] = saga
+}
+
+type fakeProducer struct {
+	topics  []string
+	reasons []string
+	// retries are the topics of the messages re-sent by an operator
+	retries []string
+	// err fails the next publications when set
+	err error
+}
+
+func (p *fakeProducer) PublishMessage(_ context.Context, msgs ...kafka.Message) error {
+	if p.err != nil {
+		return p.err
+	}
+	for _, msg := range msgs {
+		p.topics = append(p.topics, msg.Topic)
+		for _, header := range msg.Headers {
+			if header.Key == common.ReasonHeader {
+				p.reasons = append(p.reasons, string(header.Value))
+			}
+			if header.Key == common.RetryHeader {
+				p.retries = append(p.retries, msg.Topic)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *fakeProducer) Close() error {
+	return nil
+}
+
+func newTestDefinition(t *testing.T) *Definition {
+	definition, err := NewDefinitionBuilder().
+		AddStep(Step{Name: "reserve", CommandTopic: "reserve", ReplyHandler: "reserve-handler", CompensationTopic: "release", CompensationHandler: "release-handler", Timeout: time.Minute, MaxRetries: 1}).
+		AddStep(Step{Name: "notify", CommandTopic: "notify", ReplyHandler: "notify-handler"}).
+		AddStep(Step{Name: "charge", CommandTopic: "charge", ReplyHandler: "charge-handler", CompensationTopic: "refund", CompensationHandler: "refund-handler", Timeout: time.Minute, MaxRetries: 1}).
+		Build()
+	require.NoError(t, err)
+	return definition
+}
+
+func newTestEngine(t *testing.T) (Engine, *fakeSagaRepository, *fakeProducer, *[]string) {
+	apiLogger := logger.NewApiLogger(&appconfig.App{Logger: appconfig.Logger{Development: true, Encoding: "json"}})
+	apiLogger.InitLogger()
+
+	repo := &fakeSagaRepository{sagas: map[uint64]aggregate.Saga{}}
+	producer := &fakeProducer{}
+	transitions := &[]string{}
+	listener := func(_ context.Context, saga *aggregate.Saga) error {
+		*transitions = append(*transitions, saga.Step+":"+saga.Status)
+		return nil
+	}
+
+	return NewEngine(apiLogger, newTestDefinition(t), producer, repo, listener), repo, producer, transitions
+}
+
+func TestDefinitionBuilder(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewDefinitionBuilder().Build()
+	require.ErrorIs(t, err, ErrEmptyDefinition)
+
+	_, err = NewDefinitionBuilder().
+		AddStep(Step{Name: "a", CommandTopic: "a", ReplyHandler: "handler"}).
+		AddStep(Step{Name: "b", CommandTopic: "b", ReplyHandler: "handler"}).
+		Build()
+	require.Error(t, err)
+
+	_, err = NewDefinitionBuilder().
+		AddStep(Step{Name: "a", CommandTopic: "a", ReplyHandler: "a-handler", CompensationTopic: "undo-a"}).
+		Build()
+	require.Error(t, err)
+
+	definition := newTestDefinition(t)
+	index, ok := definition.StepIndex("charge")
+	require.True(t, ok)
+	require.Equal(t, 2, index)
+}
+
+func TestEngineSuccess(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	engine, repo, producer, transitions := newTestEngine(t)
+
+	require.NoError(t, engine.Start(ctx, 1, []byte("{}"), ""))
+	require.NoError(t, engine.HandleReply(ctx, &Reply{SagaID: 1, Handler: "reserve-handler", Success: true}))
+	// redelivered start message does not restart the saga once it moved on
+	require.NoError(t, engine.Start(ctx, 1, []byte("{}"), ""))
+	require.NoError(t, engine.HandleReply(ctx, &Reply{SagaID: 1, Handler: "notify-handler", Success: true}))
+	require.NoError(t, engine.HandleReply(ctx, &Reply{SagaID: 1, Handler: "charge-handler", Success: true}))
+
+	require.Equal(t, []string{"reserve", "notify", "charge"}, producer.topics)
+	require.Equal(t, []string{
+		"reserve:" + event.StatusExecute,
+		"reserve:" + event.StatusSucess,
+		"notify:" + event.StatusExecute,
+		"notify:" + event.StatusSucess,
+		"charge:" + event.StatusExecute,
+		"charge:" + event.StatusSucess,
+	}, *transitions)
+	require.Equal(t, event.StatusSucess, repo.sagas[1].Status)
+	require.Equal(t, uint64(1), repo.sagas[1].Attempts["charge"])
+}
+
+func TestEngineStartRedelivered(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	engine, repo, producer, _ := newTestEngine(t)
+
+	// the saga is created but its first command is not sent
+	producer.err = errors.New("broker unavailable")
+	require.Error(t, engine.Start(ctx, 1, []byte("{}"), ""))
+	require.Equal(t, event.StatusExecute, repo.sagas[1].Status)
+	require.Empty(t, producer.topics)
+
+	// the redelivered start message sends it
+	producer.err = nil
+	require.NoError(t, engine.Start(ctx, 1, []byte("{}"), ""))
+	require.Equal(t, []string{"reserve"}, producer.topics)
+	require.Equal(t, uint64(1), repo.sagas[1].Attempts["reserve"])
+
+	require.NoError(t, engine.HandleReply(ctx, &Reply{SagaID: 1, Handler: "reserve-handler", Success: true}))
+	require.Equal(t, "notify", repo.sagas[1].Step)
+}
+
+func TestEngineCompensation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	engine, repo, producer, transitions := newTestEngine(t)
+
+	require.NoError(t, engine.Start(ctx, 1, []byte("{}"), ""))
+	require.NoError(t, engine.HandleReply(ctx, &Reply{SagaID: 1, Handler: "reserve-handler", Success: true}))
+	require.NoError(t, engine.HandleReply(ctx, &Reply{SagaID: 1, Handler: "notify-handler", Success: true}))
+	require.NoError(t, engine.HandleReply(ctx, &Reply{SagaID: 1, Handler: "charge-handler", Success: false, Error: "card declined"}))
+
+	// a late duplicate reply of a finished step is ignored
+	require.NoError(t, engine.HandleReply(ctx, &Reply{SagaID: 1, Handler: "reserve-handler", Success: true}))
+
+	require.Equal(t, "charge", repo.sagas[1].Step)
+	require.Equal(t, event.StatusRollback, repo.sagas[1].Status)
+
+	// the step without compensation is skipped
+	require.NoError(t, engine.HandleReply(ctx, &Reply{SagaID: 1, Handler: "refund-handler", Success: true}))
+	require.Equal(t, "reserve", repo.sagas[1].Step)
+	require.Equal(t, event.StatusRollback, repo.sagas[1].Status)
+
+	require.NoError(t, engine.HandleReply(ctx, &Reply{SagaID: 1, Handler: "release-handler", Success: true}))
+	require.Equal(t, event.StatusCompensated, repo.sagas[1].Status)
+	require.Equal(t, "reserve:"+event.StatusCompensated, (*transitions)[len(*transitions)-1])
+
+	require.Equal(t, []string{"reserve", "notify", "charge", "refund", "release"}, producer.topics)
+	// the compensations tell the participants why the saga is rolled back
+	require.Equal(t, []string{"card declined", "card declined"}, producer.reasons)
+}
+
+func TestEngineRollbackFailed(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	engine, repo, _, _ := newTestEngine(t)
+
+	require.NoError(t, engine.Start(ctx, 1, []byte("{}"), ""))
+	require.NoError(t, engine.HandleReply(ctx, &Reply{SagaID: 1, Handler: "reserve-handler", Success: false}))
+	require.NoError(t, engine.HandleReply(ctx, &Reply{SagaID: 1, Handler: "release-handler", Success: false}))
+
+	require.Equal(t, "reserve", repo.sagas[1].Step)
+	require.Equal(t, event.StatusRollbackFailed, repo.sagas[1].Status)
+
+	require.Error(t, engine.HandleReply(ctx, &Reply{SagaID: 1, Handler: "unknown-handler", Success: true}))
+}
+
+func TestEngineResume(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	engine, repo, producer, _ := newTestEngine(t)
+
+	repo.sagas[1] = aggregate.Saga{ID: 1, Step: "notify", Status: event.StatusExecute, Attempts: map[string]uint64{"notify": 1}}
+	repo.sagas[2] = aggregate.Saga{ID: 2, Step: "reserve", Status: event.StatusRollback, Attempts: map[string]uint64{}}
+	repo.sagas[3] = aggregate.Saga{ID: 3, Step: "charge", Status: event.StatusSucess, Attempts: map[string]uint64{}}
+
+	require.NoError(t, engine.Resume(ctx))
+
+	require.ElementsMatch(t, []string{"notify", "release"}, producer.topics)
+	require.Equal(t, uint64(2), repo.sagas[1].Attempts["notify"])
+}
+
+func TestEngineSweep(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	engine, repo, producer, transitions := newTestEngine(t)
+
+	require.NoError(t, engine.Start(ctx, 1, []byte("{}"), ""))
+	require.False(t, repo.sagas[1].Deadline.IsZero())
+
+	// nothing expired yet
+	require.NoError(t, engine.Sweep(ctx))
+	require.Equal(t, []string{"reserve"}, producer.topics)
+
+	// first timeout re-sends the command
+	repo.expire(1)
+	require.NoError(t, engine.Sweep(ctx))
+	require.Equal(t, []string{"reserve", "reserve"}, producer.topics)
+	require.Equal(t, uint64(2), repo.sagas[1].Attempts["reserve"])
+
+	// retries are exhausted, the step fails and is compensated
+	repo.expire(1)
+	require.NoError(t, engine.Sweep(ctx))
+	require.Equal(t, []string{"reserve", "reserve", "release"}, producer.topics)
+	require.Equal(t, event.StatusRollback, repo.sagas[1].Status)
+	require.Contains(t, repo.sagas[1].Reason, "timed out")
+	require.Contains(t, *transitions, "reserve:"+event.StatusFailed)
+
+	// the compensation is retried, then the rollback fails
+	repo.expire(1)
+	require.NoError(t, engine.Sweep(ctx))
+	repo.expire(1)
+	require.NoError(t, engine.Sweep(ctx))
+	require.Equal(t, []string{"reserve", "reserve", "release", "release"}, producer.topics)
+	require.Equal(t, event.StatusRollbackFailed, repo.sagas[1].Status)
+	require.True(t, repo.sagas[1].Deadline.IsZero())
+}
+
+func TestEngineAdminActions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	engine, repo, producer, _ := newTestEngine(t)
+
+	// a failed rollback is retried from the compensation of its current step
+	repo.sagas[1] = aggregate.Saga{ID: 1, Step: "reserve", Status: event.StatusRollbackFailed, Attempts: map[string]uint64{}}
+	require.NoError(t, engine.Retry(ctx, 1))
+	require.Equal(t, event.StatusRollback, repo.sagas[1].Status)
+	require.Equal(t, []string{"release"}, producer.topics)
+	// the participant processes it again even if it rejected it
+	require.Equal(t, []string{"release"}, producer.retries)
+
+	// a saga waiting for its participant is failed and rolled back
+	repo.sagas[2] = aggregate.Saga{ID: 2, Step: "charge", Status: event.StatusExecute, Attempts: map[string]uint64{"charge": 1}}
+	require.NoError(t, engine.Compensate(ctx, 2, "stuck payment"))
+	require.Equal(t, "charge", repo.sagas[2].Step)
+	require.Equal(t, event.StatusRollback, repo.sagas[2].Status)
+	require.Equal(t, "stuck payment", repo.sagas[2].Reason)
+	require.Equal(t, []string{"release", "refund"}, producer.topics)
+	require.Equal(t, []string{"release"}, producer.retries)
+
+	require.NoError(t, engine.Resolve(ctx, 1, "stock fixed by hand"))
+	require.Equal(t, event.StatusResolved, repo.sagas[1].Status)
+	require.True(t, repo.sagas[1].Deadline.IsZero())
+
+	// the late reply of a resolved saga is ignored
+	require.NoError(t, engine.HandleReply(ctx, &Reply{SagaID: 1, Handler: "release-handler", Success: true}))
+	require.Equal(t, event.StatusResolved, repo.sagas[1].Status)
+
+	require.ErrorIs(t, engine.Retry(ctx, 1), ErrSagaFinished)
+	require.ErrorIs(t, engine.Compensate(ctx, 1, ""), ErrSagaFinished)
+
+	repo.sagas[3] = aggregate.Saga{ID: 3, Step: "charge", Status: event.StatusSucess, Attempts: map[string]uint64{}}
+	require.ErrorIs(t, engine.Resolve(ctx, 3, ""), ErrSagaFinished)
+}
@@ -11,8 +11,9 @@ type Application struct {
 }
 
 type Commands struct {
-	CreateOrder command.CreateOrderHandler
-	DeleteOrder command.DeleteOrderHandler
+	CreateOrder  command.CreateOrderHandler
+	ConfirmOrder command.ConfirmOrderHandler
+	CancelOrder  command.CancelOrderHandler
 }
 
 type Queries struct {
@@ -0,0 +1,62 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"github.com/scul0405/saga-orchestration/internal/order/domain"
+	"github.com/scul0405/saga-orchestration/internal/order/domain/entity"
+	"github.com/scul0405/saga-orchestration/internal/order/domain/valueobject"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"gorm.io/gorm"
+)
+
+type CancelOrder struct {
+	OrderID uint64
+	Reason  string
+	// Inbox records the command, its reply is published once the order is cancelled
+	Inbox *inbox.Message
+}
+
+type CancelOrderHandler CommandHandler[CancelOrder]
+
+type cancelOrderHandler struct {
+	logger    logger.Logger
+	orderRepo domain.OrderRepository
+}
+
+func NewCancelOrderHandler(logger logger.Logger, orderRepo domain.OrderRepository) CancelOrderHandler {
+	return &cancelOrderHandler{
+		logger:    logger,
+		orderRepo: orderRepo,
+	}
+}
+
+// Handle cancels the order with the reason and keeps it for the customer history.
+// An order which is already cancelled only records the command. An order which was never created is created
+// cancelled, so that a create command delivered later does not leave a pending order behind.
+func (h *cancelOrderHandler) Handle(ctx context.Context, cmd CancelOrder) error {
+	order, err := h.orderRepo.LoadOrder(ctx, cmd.OrderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return h.orderRepo.CreateOrder(ctx, &entity.Order{
+				ID:                cmd.OrderID,
+				Status:            entity.StatusCancelled,
+				Reason:            cmd.Reason,
+				PurchasedProducts: &[]valueobject.PurchasedProduct{},
+			}, cmd.Inbox)
+		}
+		return err
+	}
+
+	if order.Status == entity.StatusCancelled {
+		return h.orderRepo.RecordCommand(ctx, cmd.Inbox)
+	}
+
+	from := order.Status
+	if err = order.Transit(entity.StatusCancelled, cmd.Reason); err != nil {
+		return err
+	}
+
+	return h.orderRepo.TransitOrder(ctx, order, from, cmd.Inbox)
+}
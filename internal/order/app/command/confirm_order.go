@@ -0,0 +1,45 @@
+package command
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/internal/order/domain"
+	"github.com/scul0405/saga-orchestration/internal/order/domain/entity"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+)
+
+type ConfirmOrder struct {
+	OrderID uint64
+	// Inbox records the command, its reply is published once the order is confirmed
+	Inbox *inbox.Message
+}
+
+type ConfirmOrderHandler CommandHandler[ConfirmOrder]
+
+type confirmOrderHandler struct {
+	logger    logger.Logger
+	orderRepo domain.OrderRepository
+}
+
+func NewConfirmOrderHandler(logger logger.Logger, orderRepo domain.OrderRepository) ConfirmOrderHandler {
+	return &confirmOrderHandler{
+		logger:    logger,
+		orderRepo: orderRepo,
+	}
+}
+
+// Handle confirms the pending order once its payment succeeded.
+// It returns entity.ErrInvalidTransition when the order was cancelled meanwhile.
+func (h *confirmOrderHandler) Handle(ctx context.Context, cmd ConfirmOrder) error {
+	order, err := h.orderRepo.LoadOrder(ctx, cmd.OrderID)
+	if err != nil {
+		return err
+	}
+
+	from := order.Status
+	if err = order.Transit(entity.StatusConfirmed, ""); err != nil {
+		return err
+	}
+
+	return h.orderRepo.TransitOrder(ctx, order, from, cmd.Inbox)
+}
@@ -2,21 +2,28 @@ package command
 
 import (
 	"context"
+	"errors"
 	"github.com/scul0405/saga-orchestration/internal/order/domain"
 	"github.com/scul0405/saga-orchestration/internal/order/domain/entity"
 	"github.com/scul0405/saga-orchestration/internal/order/domain/valueobject"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"gorm.io/gorm"
 )
 
 type CreateOrder struct {
-	OrderID    uint64
-	CustomerID uint64
-	Products   *[]PurchasedProduct
+	OrderID      uint64
+	CustomerID   uint64
+	CurrencyCode string
+	Products     *[]PurchasedProduct
+	// Inbox records the command, its reply is published once the order is created
+	Inbox *inbox.Message
 }
 
 type PurchasedProduct struct {
 	ProductID uint64
 	Quantity  uint64
+	Price     uint64
 }
 
 type CreateOrderHandler CommandHandler[CreateOrder]
@@ -33,21 +40,36 @@ func NewCreateOrderHandler(logger logger.Logger, orderRepo domain.OrderRepositor
 	}
 }
 
+// Handle creates the pending order, it is confirmed once the purchase is paid.
+// It returns entity.ErrOrderCancelled when the order was cancelled before being created.
 func (h *createOrderHandler) Handle(ctx context.Context, cmd CreateOrder) error {
+	order, err := h.orderRepo.LoadOrder(ctx, cmd.OrderID)
+	if err == nil && order.Status == entity.StatusCancelled {
+		return entity.ErrOrderCancelled
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
 	products := make([]valueobject.PurchasedProduct, len(*cmd.Products))
 	for i, p := range *cmd.Products {
 		products[i] = valueobject.PurchasedProduct{
 			ID:       p.ProductID,
 			Quantity: p.Quantity,
+			Price:    p.Price,
 		}
 	}
 
-	err := h.orderRepo.CreateOrder(ctx, &entity.Order{
+	order = &entity.Order{
 		ID:                cmd.OrderID,
 		CustomerID:        cmd.CustomerID,
+		CurrencyCode:      cmd.CurrencyCode,
+		Status:            entity.StatusPending,
 		PurchasedProducts: &products,
-	})
+	}
+	order.ComputeTotal()
 
+	err = h.orderRepo.CreateOrder(ctx, order, cmd.Inbox)
 	if err != nil {
 		return err
 	}
@@ -44,9 +44,25 @@ func (h *getDetailedOrderHandler) Handle(ctx context.Context, query GetDetailedO
 		return nil, err
 	}
 
+	// The order shows the quantity and the unit price it was purchased at, not the current price
+	purchased := make(map[uint64]valueobject.PurchasedProduct, len(*order.PurchasedProducts))
+	for _, p := range *order.PurchasedProducts {
+		purchased[p.ID] = p
+	}
+	for i := range *products {
+		product := &(*products)[i]
+		product.Quantity = purchased[product.ID].Quantity
+		product.Price = purchased[product.ID].Price
+	}
+
 	return &valueobject.DetailedOrder{
 		ID:                order.ID,
 		CustomerID:        order.CustomerID,
+		CurrencyCode:      order.CurrencyCode,
+		Total:             order.Total,
+		Status:            order.Status,
+		Reason:            order.Reason,
 		PurchasedProducts: products,
+		CreatedAt:         order.CreatedAt,
 	}, nil
 }
@@ -1,11 +1,65 @@
 package entity
 
 import (
+	"errors"
+	"fmt"
 	"github.com/scul0405/saga-orchestration/internal/order/domain/valueobject"
+	"time"
 )
 
+const (
+	// StatusPending is an order created by a purchase which is not paid yet
+	StatusPending = "PENDING"
+	// StatusConfirmed is an order whose payment succeeded
+	StatusConfirmed = "CONFIRMED"
+	// StatusCancelled is an order whose purchase was rolled back, it is kept for the customer history
+	StatusCancelled = "CANCELLED"
+	StatusShipped   = "SHIPPED"
+	StatusDelivered = "DELIVERED"
+)
+
+var (
+	ErrInvalidTransition = errors.New("invalid order transition")
+	// ErrOrderCancelled is returned to a create command delivered after the cancellation of its order
+	ErrOrderCancelled = errors.New("order is cancelled")
+)
+
+// transitions are the statuses an order can move to from each status
+var transitions = map[string][]string{
+	StatusPending:   {StatusConfirmed, StatusCancelled},
+	StatusConfirmed: {StatusShipped, StatusCancelled},
+	StatusShipped:   {StatusDelivered},
+}
+
 type Order struct {
 	ID                uint64
 	CustomerID        uint64
+	CurrencyCode      string
+	Total             uint64
+	Status            string
+	Reason            string
 	PurchasedProducts *[]valueobject.PurchasedProduct
+	UpdatedAt         time.Time
+	CreatedAt         time.Time
+}
+
+// ComputeTotal sets the total of the order from the unit price of its products
+func (o *Order) ComputeTotal() {
+	o.Total = 0
+	for _, p := range *o.PurchasedProducts {
+		o.Total += p.Quantity * p.Price
+	}
+}
+
+// Transit moves the order to status, reason tells why for the cancelled orders
+func (o *Order) Transit(status, reason string) error {
+	for _, next := range transitions[o.Status] {
+		if next == status {
+			o.Status = status
+			o.Reason = reason
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, o.Status, status)
 }
@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"github.com/scul0405/saga-orchestration/internal/order/domain/valueobject"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestOrderTransit(t *testing.T) {
+	t.Parallel()
+
+	order := &Order{ID: 1, Status: StatusPending}
+	require.ErrorIs(t, order.Transit(StatusShipped, ""), ErrInvalidTransition)
+	require.Equal(t, StatusPending, order.Status)
+
+	require.NoError(t, order.Transit(StatusConfirmed, ""))
+	require.NoError(t, order.Transit(StatusShipped, ""))
+	require.ErrorIs(t, order.Transit(StatusCancelled, "rolled back"), ErrInvalidTransition)
+	require.Equal(t, StatusShipped, order.Status)
+	require.Empty(t, order.Reason)
+	require.NoError(t, order.Transit(StatusDelivered, ""))
+
+	cancelled := &Order{Status: StatusConfirmed}
+	require.NoError(t, cancelled.Transit(StatusCancelled, "rolled back"))
+	require.Equal(t, "rolled back", cancelled.Reason)
+
+	for _, status := range []string{StatusCancelled, StatusDelivered} {
+		final := &Order{Status: status}
+		require.ErrorIs(t, final.Transit(StatusConfirmed, ""), ErrInvalidTransition)
+	}
+}
+
+func TestOrderComputeTotal(t *testing.T) {
+	t.Parallel()
+
+	order := &Order{
+		Total: 42,
+		PurchasedProducts: &[]valueobject.PurchasedProduct{
+			{ID: 1, Quantity: 2, Price: 150},
+			{ID: 2, Quantity: 1, Price: 300},
+		},
+	}
+	order.ComputeTotal()
+	require.Equal(t, uint64(600), order.Total)
+
+	order.PurchasedProducts = &[]valueobject.PurchasedProduct{}
+	order.ComputeTotal()
+	require.Zero(t, order.Total)
+}
@@ -3,10 +3,16 @@ package domain
 import (
 	"context"
 	"github.com/scul0405/saga-orchestration/internal/order/domain/entity"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
 )
 
 type OrderRepository interface {
+	// GetOrder returns the order, possibly from the cache
 	GetOrder(ctx context.Context, id uint64) (*entity.Order, error)
-	CreateOrder(ctx context.Context, order *entity.Order) error
-	DeleteOrder(ctx context.Context, id uint64) error
+	// LoadOrder returns the current order from the database, commands decide on it
+	LoadOrder(ctx context.Context, id uint64) (*entity.Order, error)
+	CreateOrder(ctx context.Context, order *entity.Order, msg *inbox.Message) error
+	TransitOrder(ctx context.Context, order *entity.Order, from string, msg *inbox.Message) error
+	// RecordCommand records a command which leaves the order unchanged
+	RecordCommand(ctx context.Context, msg *inbox.Message) error
 }
@@ -1,8 +1,15 @@
 package valueobject
 
+import "time"
+
 // DetailedOrder value object
 type DetailedOrder struct {
 	ID                uint64
 	CustomerID        uint64
+	CurrencyCode      string
+	Total             uint64
+	Status            string
+	Reason            string
 	PurchasedProducts *[]DetailedPurchasedProduct
+	CreatedAt         time.Time
 }
@@ -4,6 +4,8 @@ package valueobject
 type PurchasedProduct struct {
 	ID       uint64
 	Quantity uint64
+	// Price is the unit price of the product when it was purchased
+	Price uint64
 }
 
 // DetailedPurchasedProduct value object
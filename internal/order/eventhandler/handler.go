@@ -2,25 +2,22 @@ package eventhandler
 
 import (
 	"context"
-	"encoding/json"
-	"github.com/avast/retry-go"
 	"github.com/scul0405/saga-orchestration/cmd/order/config"
 	"github.com/scul0405/saga-orchestration/internal/common"
 	"github.com/scul0405/saga-orchestration/internal/order/app"
 	"github.com/scul0405/saga-orchestration/internal/order/app/command"
+	"github.com/scul0405/saga-orchestration/internal/order/domain/entity"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
 	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
-	"github.com/scul0405/saga-orchestration/pkg/timeconvert"
 	pb "github.com/scul0405/saga-orchestration/proto"
-	"github.com/segmentio/kafka-go"
 	"sync"
-	"time"
 )
 
 var (
-	retryAttempts uint = 10
-	retryDelay         = 1 * time.Second
-	poolSize           = 16
+	poolSize = 16
+	// businessErrors fail the command at once, the other errors are retried
+	businessErrors = []error{entity.ErrInvalidTransition, entity.ErrOrderCancelled}
 )
 
 type EventHandler interface {
@@ -31,7 +28,8 @@ type eventHandler struct {
 	cfg      *config.Config
 	logger   logger.Logger
 	consumer kafkaClient.ConsumerGroup
-	producer kafkaClient.Producer
+	inbox    inbox.Inbox
+	dlq      kafkaClient.DeadLetterPublisher
 	orderSvc app.Application
 }
 
@@ -39,13 +37,15 @@ func NewEventHandler(
 	cfg *config.Config,
 	logger logger.Logger,
 	consumer kafkaClient.ConsumerGroup,
-	producer kafkaClient.Producer,
+	inbox inbox.Inbox,
+	dlq kafkaClient.DeadLetterPublisher,
 	orderSvc app.Application) EventHandler {
 	return &eventHandler{
 		cfg:      cfg,
 		logger:   logger,
 		consumer: consumer,
-		producer: producer,
+		inbox:    inbox,
+		dlq:      dlq,
 		orderSvc: orderSvc,
 	}
 }
@@ -53,11 +53,14 @@ func NewEventHandler(
 func (h *eventHandler) Run(ctx context.Context) {
 	go h.consumer.ConsumeTopic(ctx, poolSize, common.CreateOrderGroupID, common.CreateOrderTopic, h.createOrderWorker)
 	go h.consumer.ConsumeTopic(ctx, poolSize, common.RollbackOrderGroupID, common.RollbackOrderTopic, h.rollbackOrderWorker)
+	go h.consumer.ConsumeTopic(ctx, poolSize, common.ConfirmOrderGroupID, common.ConfirmOrderTopic, h.confirmOrderWorker)
 }
 
-func (h *eventHandler) createOrderWorker(ctx context.Context, r *kafka.Reader, wg *sync.WaitGroup, workerID int) {
+func (h *eventHandler) createOrderWorker(ctx context.Context, r kafkaClient.MessageReader, wg *sync.WaitGroup, workerID int) {
 	defer wg.Done()
 
+	policy := h.cfg.Kafka.Retry.Policy(common.CreateOrderTopic)
+
 	for {
 		m, err := r.FetchMessage(ctx)
 		if err != nil {
@@ -65,57 +68,61 @@ func (h *eventHandler) createOrderWorker(ctx context.Context, r *kafka.Reader, w
 			return
 		}
 
+		msgCtx, span := kafkaClient.StartConsumerSpan(ctx, m, "Order.CreateOrderWorker")
+
 		var purchase pb.CreatePurchaseRequest
-		if err = json.Unmarshal(m.Value, &purchase); err != nil {
+		if err = kafkaClient.Decode(m, &purchase); err != nil {
 			h.logger.Errorf("Order.CreateOrderWorker: UnmarshalProto", err)
+			if err = h.dlq.Publish(msgCtx, m, err, 1, "Order.CreateOrderWorker"); err != nil {
+				h.logger.Errorf("Order.CreateOrderWorker: PublishDeadLetter", err)
+			}
+			if err = r.CommitMessages(msgCtx, m); err != nil {
+				h.logger.Errorf("Order.CreateOrderWorker: CommitMessages", err)
+			}
+			span.End()
 			continue
 		}
 		h.logger.Infof("CreateOrderWorker: %v, message at topic/partition/offset %v/%v/%v: %s = %s\n", workerID, m.Topic, m.Partition, m.Offset, string(m.Key), string(m.Value))
 
-		reply := pb.CreatePurchaseResponse{
-			PurchaseId: purchase.PurchaseId,
-		}
-		if err = retry.Do(func() error {
-			err = h.orderSvc.Commands.CreateOrder.Handle(ctx, decodePb2CreateOrderCmd(&purchase))
-			if err != nil {
-				reply.Success = false
-				reply.ErrorMessage = err.Error()
-			} else {
-				reply.Success = true
+		attempts, err := policy.Do(msgCtx, func() error {
+			// A redelivered command only publishes its stored reply again, unless an operator retries a rejected one
+			replayed, err := h.inbox.Replay(msgCtx, purchase.PurchaseId, common.CreateOrderHandler, isRetried(&m))
+			if err != nil || replayed {
+				return err
 			}
 
-			reply.Purchase = purchase.Purchase
-			reply.Timestamp = timeconvert.Time2pbTimestamp(time.Now())
-			payload, _ := json.Marshal(&reply)
-
-			return h.producer.PublishMessage(ctx, kafka.Message{
-				Topic: common.ReplyTopic,
-				Value: payload,
-				Headers: []kafka.Header{
-					{
-						Key:   common.HandlerHeader,
-						Value: []byte(common.CreateOrderHandler),
-					},
-				},
-			})
-		},
-			retry.Attempts(retryAttempts),
-			retry.Delay(retryDelay),
-			retry.Context(ctx),
-		); err != nil {
-			// TODO: publish error message to error topic
+			cmd := decodePb2CreateOrderCmd(&purchase)
+			cmd.Inbox = encodeInboxMessage(&purchase, common.CreateOrderHandler, nil)
+			err = h.orderSvc.Commands.CreateOrder.Handle(msgCtx, cmd)
+			if kafkaClient.Classify(err, businessErrors...) != kafkaClient.BusinessFailure {
+				// With inbox.ErrProcessed the next attempt replays the reply of a concurrent delivery
+				return err
+			}
+
+			// The purchase was rolled back before the order was created, only the command and its failure reply are recorded
+			return h.inbox.Reject(msgCtx, encodeInboxMessage(&purchase, common.CreateOrderHandler, err))
+		})
+		if err != nil {
+			h.logger.Errorf("Order.CreateOrderWorker: failed after %d attempts: %v", attempts, err)
+			if err = h.dlq.Publish(msgCtx, m, err, attempts, "Order.CreateOrderWorker"); err != nil {
+				h.logger.Errorf("Order.CreateOrderWorker: PublishDeadLetter", err)
+			}
 		}
 
-		err = r.CommitMessages(ctx, m)
+		err = r.CommitMessages(msgCtx, m)
 		if err != nil {
 			h.logger.Errorf("Order.CreateOrderWorker: CommitMessages", err)
 		}
+
+		span.End()
 	}
 }
 
-func (h *eventHandler) rollbackOrderWorker(ctx context.Context, r *kafka.Reader, wg *sync.WaitGroup, workerID int) {
+func (h *eventHandler) rollbackOrderWorker(ctx context.Context, r kafkaClient.MessageReader, wg *sync.WaitGroup, workerID int) {
 	defer wg.Done()
 
+	policy := h.cfg.Kafka.Retry.Policy(common.RollbackOrderTopic)
+
 	for {
 		m, err := r.FetchMessage(ctx)
 		if err != nil {
@@ -123,50 +130,117 @@ func (h *eventHandler) rollbackOrderWorker(ctx context.Context, r *kafka.Reader,
 			return
 		}
 
+		msgCtx, span := kafkaClient.StartConsumerSpan(ctx, m, "Order.RollbackOrderWorker")
+
 		var purchase pb.CreatePurchaseRequest
-		if err = json.Unmarshal(m.Value, &purchase); err != nil {
+		if err = kafkaClient.Decode(m, &purchase); err != nil {
 			h.logger.Errorf("Order.RollbackOrderWorker: UnmarshalProto", err)
+			if err = h.dlq.Publish(msgCtx, m, err, 1, "Order.RollbackOrderWorker"); err != nil {
+				h.logger.Errorf("Order.RollbackOrderWorker: PublishDeadLetter", err)
+			}
+			if err = r.CommitMessages(msgCtx, m); err != nil {
+				h.logger.Errorf("Order.RollbackOrderWorker: CommitMessages", err)
+			}
+			span.End()
 			continue
 		}
 		h.logger.Infof("RollbackOrderWorker: %v, message at topic/partition/offset %v/%v/%v: %s = %s\n", workerID, m.Topic, m.Partition, m.Offset, string(m.Key), string(m.Value))
 
-		reply := pb.CreatePurchaseResponse{
-			PurchaseId: purchase.PurchaseId,
-		}
-		if err = retry.Do(func() error {
-			err = h.orderSvc.Commands.DeleteOrder.Handle(ctx, command.DeleteOrder{OrderID: purchase.PurchaseId})
-			if err != nil {
-				reply.Success = false
-				reply.ErrorMessage = err.Error()
-			} else {
-				reply.Success = true
+		attempts, err := policy.Do(msgCtx, func() error {
+			// A redelivered command only publishes its stored reply again, unless an operator retries a rejected one
+			replayed, err := h.inbox.Replay(msgCtx, purchase.PurchaseId, common.RollbackOrderHandler, isRetried(&m))
+			if err != nil || replayed {
+				return err
 			}
 
-			reply.Purchase = purchase.Purchase
-			reply.Timestamp = timeconvert.Time2pbTimestamp(time.Now())
-			payload, _ := json.Marshal(&reply)
-
-			return h.producer.PublishMessage(ctx, kafka.Message{
-				Topic: common.ReplyTopic,
-				Value: payload,
-				Headers: []kafka.Header{
-					{
-						Key:   common.HandlerHeader,
-						Value: []byte(common.RollbackOrderHandler),
-					},
-				},
+			err = h.orderSvc.Commands.CancelOrder.Handle(msgCtx, command.CancelOrder{
+				OrderID: purchase.PurchaseId,
+				Reason:  decodeReason(&m),
+				Inbox:   encodeInboxMessage(&purchase, common.RollbackOrderHandler, nil),
 			})
-		},
-			retry.Attempts(retryAttempts),
-			retry.Delay(retryDelay),
-			retry.Context(ctx),
-		); err != nil {
-			// TODO: publish error message to error topic
+			if kafkaClient.Classify(err, businessErrors...) != kafkaClient.BusinessFailure {
+				// With inbox.ErrProcessed the next attempt replays the reply of a concurrent delivery
+				return err
+			}
+
+			// The order can not be cancelled anymore, e.g. it is shipped, only the command and its failure reply are recorded
+			return h.inbox.Reject(msgCtx, encodeInboxMessage(&purchase, common.RollbackOrderHandler, err))
+		})
+		if err != nil {
+			h.logger.Errorf("Order.RollbackOrderWorker: failed after %d attempts: %v", attempts, err)
+			if err = h.dlq.Publish(msgCtx, m, err, attempts, "Order.RollbackOrderWorker"); err != nil {
+				h.logger.Errorf("Order.RollbackOrderWorker: PublishDeadLetter", err)
+			}
 		}
 
-		err = r.CommitMessages(ctx, m)
+		err = r.CommitMessages(msgCtx, m)
 		if err != nil {
 			h.logger.Errorf("Order.RollbackOrderWorker: CommitMessages", err)
 		}
+
+		span.End()
+	}
+}
+
+func (h *eventHandler) confirmOrderWorker(ctx context.Context, r kafkaClient.MessageReader, wg *sync.WaitGroup, workerID int) {
+	defer wg.Done()
+
+	policy := h.cfg.Kafka.Retry.Policy(common.ConfirmOrderTopic)
+
+	for {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			h.logger.Errorf("Order.ConfirmOrderWorker: FetchMessage", err)
+			return
+		}
+
+		msgCtx, span := kafkaClient.StartConsumerSpan(ctx, m, "Order.ConfirmOrderWorker")
+
+		var purchase pb.CreatePurchaseRequest
+		if err = kafkaClient.Decode(m, &purchase); err != nil {
+			h.logger.Errorf("Order.ConfirmOrderWorker: UnmarshalProto", err)
+			if err = h.dlq.Publish(msgCtx, m, err, 1, "Order.ConfirmOrderWorker"); err != nil {
+				h.logger.Errorf("Order.ConfirmOrderWorker: PublishDeadLetter", err)
+			}
+			if err = r.CommitMessages(msgCtx, m); err != nil {
+				h.logger.Errorf("Order.ConfirmOrderWorker: CommitMessages", err)
+			}
+			span.End()
+			continue
+		}
+		h.logger.Infof("ConfirmOrderWorker: %v, message at topic/partition/offset %v/%v/%v: %s = %s\n", workerID, m.Topic, m.Partition, m.Offset, string(m.Key), string(m.Value))
+
+		attempts, err := policy.Do(msgCtx, func() error {
+			// A redelivered command only publishes its stored reply again, unless an operator retries a rejected one
+			replayed, err := h.inbox.Replay(msgCtx, purchase.PurchaseId, common.ConfirmOrderHandler, isRetried(&m))
+			if err != nil || replayed {
+				return err
+			}
+
+			err = h.orderSvc.Commands.ConfirmOrder.Handle(msgCtx, command.ConfirmOrder{
+				OrderID: purchase.PurchaseId,
+				Inbox:   encodeInboxMessage(&purchase, common.ConfirmOrderHandler, nil),
+			})
+			if kafkaClient.Classify(err, businessErrors...) != kafkaClient.BusinessFailure {
+				// With inbox.ErrProcessed the next attempt replays the reply of a concurrent delivery
+				return err
+			}
+
+			// The order can not be confirmed anymore, only the command and its failure reply are recorded
+			return h.inbox.Reject(msgCtx, encodeInboxMessage(&purchase, common.ConfirmOrderHandler, err))
+		})
+		if err != nil {
+			h.logger.Errorf("Order.ConfirmOrderWorker: failed after %d attempts: %v", attempts, err)
+			if err = h.dlq.Publish(msgCtx, m, err, attempts, "Order.ConfirmOrderWorker"); err != nil {
+				h.logger.Errorf("Order.ConfirmOrderWorker: PublishDeadLetter", err)
+			}
+		}
+
+		err = r.CommitMessages(msgCtx, m)
+		if err != nil {
+			h.logger.Errorf("Order.ConfirmOrderWorker: CommitMessages", err)
+		}
+
+		span.End()
 	}
 }
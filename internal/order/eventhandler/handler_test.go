@@ -0,0 +1,113 @@
+package eventhandler
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/scul0405/saga-orchestration/cmd/order/config"
+	"github.com/scul0405/saga-orchestration/internal/common"
+	"github.com/scul0405/saga-orchestration/internal/order/app"
+	"github.com/scul0405/saga-orchestration/internal/order/app/command"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
+	"github.com/scul0405/saga-orchestration/pkg/appconfig"
+	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+	pb "github.com/scul0405/saga-orchestration/proto"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"sync"
+	"testing"
+)
+
+// fakeReader returns its messages once, then fails as a closed reader
+type fakeReader struct {
+	messages  []kafka.Message
+	committed []kafka.Message
+}
+
+func (r *fakeReader) FetchMessage(_ context.Context) (kafka.Message, error) {
+	if len(r.messages) == 0 {
+		return kafka.Message{}, errors.New("reader closed")
+	}
+	m := r.messages[0]
+	r.messages = r.messages[1:]
+	return m, nil
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+type fakeDeadLetterPublisher struct {
+	published int
+}
+
+func (p *fakeDeadLetterPublisher) Publish(_ context.Context, _ kafka.Message, _ error, _ uint, _ string) error {
+	p.published++
+	return nil
+}
+
+type fakeCancelOrderHandler struct {
+	commands []command.CancelOrder
+}
+
+func (h *fakeCancelOrderHandler) Handle(_ context.Context, cmd command.CancelOrder) error {
+	h.commands = append(h.commands, cmd)
+	return nil
+}
+
+func TestRollbackOrderWorkerRetried(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db, DriverName: "postgres"}), &gorm.Config{})
+	require.NoError(t, err)
+
+	apiLogger := logger.NewApiLogger(&appconfig.App{Logger: appconfig.Logger{Development: true, Encoding: "json"}})
+	apiLogger.InitLogger()
+
+	value, contentType, err := kafkaClient.Encode(kafkaClient.ContentTypeProtobuf, &pb.CreatePurchaseRequest{PurchaseId: 1})
+	require.NoError(t, err)
+	reader := &fakeReader{messages: []kafka.Message{{
+		Topic: common.RollbackOrderTopic,
+		Key:   common.PurchaseKey(1),
+		Value: value,
+		Headers: []kafka.Header{
+			contentType,
+			{Key: common.RetryHeader, Value: []byte("true")},
+		},
+	}}}
+
+	// The rejected cancellation is forgotten, then processed again
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "inbox_messages" WHERE .*rejected`).
+		WithArgs(1, common.RollbackOrderHandler).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT \* FROM "inbox_messages"`).
+		WillReturnRows(sqlmock.NewRows([]string{"purchase_id", "command", "reply", "rejected"}))
+
+	cancelOrder := &fakeCancelOrderHandler{}
+	dlq := &fakeDeadLetterPublisher{}
+	h := &eventHandler{
+		cfg:      &config.Config{},
+		logger:   apiLogger,
+		inbox:    inbox.NewInbox(gdb),
+		dlq:      dlq,
+		orderSvc: app.Application{Commands: app.Commands{CancelOrder: cancelOrder}},
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	h.rollbackOrderWorker(context.Background(), reader, wg, 0)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Len(t, cancelOrder.commands, 1)
+	require.Equal(t, uint64(1), cancelOrder.commands[0].OrderID)
+	require.Len(t, reader.committed, 1)
+	require.Zero(t, dlq.published)
+}
@@ -3,9 +3,14 @@ package postgres
 import (
 	"github.com/scul0405/saga-orchestration/cmd/order/config"
 	"github.com/scul0405/saga-orchestration/internal/order/infrastructure/db/postgres/model"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
+	"github.com/scul0405/saga-orchestration/internal/pkg/outbox"
 	"gorm.io/gorm"
 )
 
+// legacyOrderTable holds the orders of the former schema, one row per order and product, while they are copied
+const legacyOrderTable = "legacy_orders"
+
 type Migrator struct {
 	db *gorm.DB
 }
@@ -20,10 +25,68 @@ func (m *Migrator) Migrate(migration config.Migration) error {
 	}
 
 	if migration.Recreate {
-		if err := m.db.Migrator().DropTable(&model.Order{}); err != nil {
+		if err := m.db.Migrator().DropTable(&model.OrderItem{}, &model.Order{}, legacyOrderTable, &outbox.OutboxMessage{}, &inbox.InboxMessage{}); err != nil {
 			return err
 		}
 	}
 
-	return m.db.AutoMigrate(&model.Order{})
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		legacy, err := m.moveLegacyOrders(tx)
+		if err != nil {
+			return err
+		}
+
+		if err = tx.AutoMigrate(&model.Order{}, &model.OrderItem{}, &outbox.OutboxMessage{}, &inbox.InboxMessage{}); err != nil {
+			return err
+		}
+
+		if !legacy {
+			return nil
+		}
+		return m.copyLegacyOrders(tx)
+	})
+}
+
+// moveLegacyOrders renames the orders table of the former schema, keyed by order and product,
+// so the header table can take its name. It returns false when there is nothing to migrate.
+func (m *Migrator) moveLegacyOrders(tx *gorm.DB) (bool, error) {
+	if !tx.Migrator().HasColumn("orders", "product_id") {
+		return false, nil
+	}
+
+	if err := tx.Migrator().RenameTable("orders", legacyOrderTable); err != nil {
+		return false, err
+	}
+
+	// Renaming the table keeps the name of its primary key, which the header table needs
+	if tx.Migrator().HasConstraint(legacyOrderTable, "orders_pkey") {
+		if err := tx.Exec("ALTER TABLE " + legacyOrderTable + " RENAME CONSTRAINT orders_pkey TO " + legacyOrderTable + "_pkey").Error; err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// copyLegacyOrders splits the legacy rows into headers and items, then drops the legacy table.
+// The legacy rows stored neither the currency nor the price: they are left empty, and the orders
+// which predate the status were confirmed since the rolled back ones were deleted.
+func (m *Migrator) copyLegacyOrders(tx *gorm.DB) error {
+	status, reason := "'CONFIRMED'", "''"
+	if tx.Migrator().HasColumn(legacyOrderTable, "status") {
+		status, reason = "MIN(status)", "MIN(reason)"
+	}
+
+	if err := tx.Exec(`INSERT INTO orders (id, customer_id, currency_code, total, status, reason, updated_at, created_at)
+		SELECT id, MIN(customer_id), '', 0, ` + status + `, ` + reason + `, MAX(updated_at), MIN(created_at)
+		FROM ` + legacyOrderTable + ` GROUP BY id`).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Exec(`INSERT INTO order_items (order_id, product_id, quantity, unit_price, created_at)
+		SELECT id, product_id, quantity, 0, created_at FROM ` + legacyOrderTable).Error; err != nil {
+		return err
+	}
+
+	return tx.Migrator().DropTable(legacyOrderTable)
 }
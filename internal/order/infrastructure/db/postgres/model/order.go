@@ -1,10 +1,23 @@
 package model
 
+// Order is the header of an order, Total is the sum of its items at their unit price
 type Order struct {
-	ID         uint64 `gorm:"primaryKey"`
-	ProductID  uint64 `gorm:"primaryKey"`
-	Quantity   uint64 `gorm:"not null"`
-	CustomerID uint64 `gorm:"not null"`
-	UpdatedAt  int64  `gorm:"autoUpdateTime:milli"`
-	CreatedAt  int64  `gorm:"autoCreateTime:milli"`
+	ID           uint64      `gorm:"primaryKey"`
+	CustomerID   uint64      `gorm:"not null;index"`
+	CurrencyCode string      `gorm:"type:varchar(3);not null"`
+	Total        uint64      `gorm:"not null"`
+	Status       string      `gorm:"type:varchar(20);not null"`
+	Reason       string      `gorm:"type:text"`
+	Items        []OrderItem `gorm:"foreignKey:OrderID"`
+	UpdatedAt    int64       `gorm:"autoUpdateTime:milli"`
+	CreatedAt    int64       `gorm:"autoCreateTime:milli"`
+}
+
+// OrderItem is a product of an order, UnitPrice is the price of the product when it was purchased
+type OrderItem struct {
+	OrderID   uint64 `gorm:"primaryKey"`
+	ProductID uint64 `gorm:"primaryKey"`
+	Quantity  uint64 `gorm:"not null"`
+	UnitPrice uint64 `gorm:"not null"`
+	CreatedAt int64  `gorm:"autoCreateTime:milli"`
 }
@@ -1,6 +1,13 @@
 package dto
 
+import "time"
+
 type Order struct {
-	OrderID  uint64    `json:"order_id"`
-	Products []Product `json:"products"`
+	OrderID      uint64    `json:"order_id"`
+	CurrencyCode string    `json:"currency_code"`
+	Total        uint64    `json:"total"`
+	Status       string    `json:"status"`
+	Reason       string    `json:"reason,omitempty"`
+	Products     []Product `json:"products"`
+	CreatedAt    time.Time `json:"created_at"`
 }
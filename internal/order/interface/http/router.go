@@ -1,11 +1,13 @@
 package http
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/scul0405/saga-orchestration/internal/order/app"
 	"github.com/scul0405/saga-orchestration/internal/order/app/query"
 	"github.com/scul0405/saga-orchestration/internal/order/infrastructure/grpc"
 	"github.com/scul0405/saga-orchestration/internal/order/interface/http/dto"
+	"gorm.io/gorm"
 	"net/http"
 	"strconv"
 )
@@ -13,6 +15,7 @@ import (
 var (
 	OkMessage       = "success"
 	ErrInvalidID    = "invalid id"
+	ErrNotFound     = "not found"
 	ErrInvalidJSON  = "invalid json"
 	ErrForbidden    = "forbidden"
 	ErrInternal     = "internal error"
@@ -46,6 +49,11 @@ func (r *Router) GetDetailedOrder(c *gin.Context) {
 
 	order, err := r.app.Queries.GetDetailedOrder.Handle(c, query.GetDetailedOrder{OrderID: orderID})
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": ErrNotFound})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
 		return
 	}
@@ -56,8 +64,13 @@ func (r *Router) GetDetailedOrder(c *gin.Context) {
 	}
 
 	resp := &dto.Order{
-		OrderID:  orderID,
-		Products: make([]dto.Product, len(*(order.PurchasedProducts))),
+		OrderID:      orderID,
+		CurrencyCode: order.CurrencyCode,
+		Total:        order.Total,
+		Status:       order.Status,
+		Reason:       order.Reason,
+		Products:     make([]dto.Product, len(*(order.PurchasedProducts))),
+		CreatedAt:    order.CreatedAt,
 	}
 
 	for i, p := range *(order.PurchasedProducts) {
@@ -2,16 +2,25 @@ package pgrepo
 
 import (
 	"context"
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/scul0405/saga-orchestration/internal/order/domain/entity"
 	"github.com/scul0405/saga-orchestration/internal/order/domain/valueobject"
 	"github.com/scul0405/saga-orchestration/internal/order/infrastructure/db/postgres/model"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
 	"gorm.io/gorm"
+	"time"
+)
+
+var (
+	ErrStaleOrder = errors.New("order was updated concurrently")
 )
 
 type OrderRepository interface {
 	GetOrder(ctx context.Context, id uint64) (*entity.Order, error)
-	CreateOrder(ctx context.Context, order *entity.Order) error
-	DeleteOrder(ctx context.Context, id uint64) error
+	CreateOrder(ctx context.Context, order *entity.Order, msg *inbox.Message) error
+	TransitOrder(ctx context.Context, order *entity.Order, from string, msg *inbox.Message) error
+	RecordCommand(ctx context.Context, msg *inbox.Message) error
 }
 
 type orderRepositoryImpl struct {
@@ -23,49 +32,95 @@ func NewOrderRepository(db *gorm.DB) OrderRepository {
 }
 
 func (r *orderRepositoryImpl) GetOrder(ctx context.Context, id uint64) (*entity.Order, error) {
-	var order []model.Order
-	if err := r.db.Where("id = ?", id).Find(&order).WithContext(ctx).Error; err != nil {
+	var order model.Order
+	if err := r.db.WithContext(ctx).Preload("Items").Where("id = ?", id).First(&order).Error; err != nil {
 		return nil, err
 	}
 
-	purchasedProducts := make([]valueobject.PurchasedProduct, len(order))
-
-	for i, entry := range order {
+	purchasedProducts := make([]valueobject.PurchasedProduct, len(order.Items))
+	for i, item := range order.Items {
 		purchasedProducts[i] = valueobject.PurchasedProduct{
-			ID:       entry.ProductID,
-			Quantity: entry.Quantity,
+			ID:       item.ProductID,
+			Quantity: item.Quantity,
+			Price:    item.UnitPrice,
 		}
 	}
 
 	return &entity.Order{
-		ID:                order[0].ID,
-		CustomerID:        order[0].CustomerID,
+		ID:                order.ID,
+		CustomerID:        order.CustomerID,
+		CurrencyCode:      order.CurrencyCode,
+		Total:             order.Total,
+		Status:            order.Status,
+		Reason:            order.Reason,
 		PurchasedProducts: &purchasedProducts,
+		UpdatedAt:         time.UnixMilli(order.UpdatedAt),
+		CreatedAt:         time.UnixMilli(order.CreatedAt),
 	}, nil
 }
 
-func (r *orderRepositoryImpl) CreateOrder(ctx context.Context, order *entity.Order) error {
-	entries := make([]model.Order, len(*(order.PurchasedProducts)))
-
+// CreateOrder creates the order with its items and records the command in the inbox in the same transaction.
+// It returns ErrStaleOrder when the order was created concurrently.
+func (r *orderRepositoryImpl) CreateOrder(ctx context.Context, order *entity.Order, msg *inbox.Message) error {
+	items := make([]model.OrderItem, len(*(order.PurchasedProducts)))
 	for i, product := range *(order.PurchasedProducts) {
-		entries[i] = model.Order{
-			ID:         order.ID,
-			ProductID:  product.ID,
-			CustomerID: order.CustomerID,
-			Quantity:   product.Quantity,
+		items[i] = model.OrderItem{
+			ProductID: product.ID,
+			Quantity:  product.Quantity,
+			UnitPrice: product.Price,
 		}
 	}
 
-	if err := r.db.Create(&entries).WithContext(ctx).Error; err != nil {
-		return err
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := inbox.Add(tx, msg); err != nil {
+			return err
+		}
+
+		return tx.Create(&model.Order{
+			ID:           order.ID,
+			CustomerID:   order.CustomerID,
+			CurrencyCode: order.CurrencyCode,
+			Total:        order.Total,
+			Status:       order.Status,
+			Reason:       order.Reason,
+			Items:        items,
+		}).Error
+	})
+
+	var pgError *pgconn.PgError
+	if errors.As(err, &pgError) && pgError.Code == "23505" {
+		return ErrStaleOrder
 	}
+	return err
+}
+
+// TransitOrder saves the status of the order moved from the status from and records the command in the inbox
+// in the same transaction. It returns ErrStaleOrder when the order is no longer in the status from.
+func (r *orderRepositoryImpl) TransitOrder(ctx context.Context, order *entity.Order, from string, msg *inbox.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := inbox.Add(tx, msg); err != nil {
+			return err
+		}
 
-	return nil
+		result := tx.Model(&model.Order{}).
+			Where("id = ? AND status = ?", order.ID, from).
+			Updates(map[string]interface{}{
+				"status": order.Status,
+				"reason": order.Reason,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrStaleOrder
+		}
+
+		return nil
+	})
 }
 
-func (r *orderRepositoryImpl) DeleteOrder(ctx context.Context, id uint64) error {
-	if err := r.db.Exec("DELETE FROM orders WHERE id = ?", id).WithContext(ctx).Error; err != nil {
-		return err
-	}
-	return nil
+func (r *orderRepositoryImpl) RecordCommand(ctx context.Context, msg *inbox.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return inbox.Add(tx, msg)
+	})
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/scul0405/saga-orchestration/internal/order/domain/entity"
 	"github.com/scul0405/saga-orchestration/internal/order/repository/pgrepo"
 	"github.com/scul0405/saga-orchestration/internal/pkg/cache"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
 	"github.com/scul0405/saga-orchestration/pkg/strjoin"
 	"gorm.io/gorm"
@@ -88,8 +89,12 @@ func (r *orderRepositoryImpl) GetOrder(ctx context.Context, id uint64) (*entity.
 	return order, nil
 }
 
-func (r *orderRepositoryImpl) CreateOrder(ctx context.Context, order *entity.Order) error {
-	err := r.pgRepo.CreateOrder(ctx, order)
+func (r *orderRepositoryImpl) LoadOrder(ctx context.Context, id uint64) (*entity.Order, error) {
+	return r.pgRepo.GetOrder(ctx, id)
+}
+
+func (r *orderRepositoryImpl) CreateOrder(ctx context.Context, order *entity.Order, msg *inbox.Message) error {
+	err := r.pgRepo.CreateOrder(ctx, order, msg)
 	if err != nil {
 		return err
 	}
@@ -98,14 +103,17 @@ func (r *orderRepositoryImpl) CreateOrder(ctx context.Context, order *entity.Ord
 	return nil
 }
 
-func (r *orderRepositoryImpl) DeleteOrder(ctx context.Context, id uint64) error {
-	if err := r.pgRepo.DeleteOrder(ctx, id); err != nil {
+func (r *orderRepositoryImpl) TransitOrder(ctx context.Context, order *entity.Order, from string, msg *inbox.Message) error {
+	if err := r.pgRepo.TransitOrder(ctx, order, from, msg); err != nil {
 		return err
 	}
 
-	key := strjoin.Join(getOrderKey, strconv.FormatUint(id, 10))
+	key := strjoin.Join(getOrderKey, strconv.FormatUint(order.ID, 10))
 	r.logger.Error(r.lc.Delete(key))
 	r.logger.Error(r.rc.Delete(ctx, key))
-	r.logger.Error(r.rc.CFDel(ctx, cuckooFilter, id))
 	return nil
 }
+
+func (r *orderRepositoryImpl) RecordCommand(ctx context.Context, msg *inbox.Message) error {
+	return r.pgRepo.RecordCommand(ctx, msg)
+}
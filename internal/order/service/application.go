@@ -12,8 +12,9 @@ import (
 func NewOrderService(logger logger.Logger, orderRepo domain.OrderRepository, productSvc grpc.ProductService) app.Application {
 	return app.Application{
 		Commands: app.Commands{
-			CreateOrder: command.NewCreateOrderHandler(logger, orderRepo),
-			DeleteOrder: command.NewDeleteOrderHandler(logger, orderRepo),
+			CreateOrder:  command.NewCreateOrderHandler(logger, orderRepo),
+			ConfirmOrder: command.NewConfirmOrderHandler(logger, orderRepo),
+			CancelOrder:  command.NewCancelOrderHandler(logger, orderRepo),
 		},
 		Queries: app.Queries{
 			GetDetailedOrder: query.NewGetDetailedOrderHandler(logger, orderRepo, productSvc),
@@ -11,8 +11,9 @@ type Application struct {
 }
 
 type Commands struct {
-	CreatePayment   command.CreatePaymentHandler
-	RollbackPayment command.RollbackPaymentHandler
+	CreatePayment      command.CreatePaymentHandler
+	RollbackPayment    command.RollbackPaymentHandler
+	HandleGatewayEvent command.HandleGatewayEventHandler
 }
 
 type Queries struct {
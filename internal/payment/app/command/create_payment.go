@@ -2,8 +2,11 @@ package command
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/scul0405/saga-orchestration/internal/payment/domain"
 	"github.com/scul0405/saga-orchestration/internal/payment/domain/entity"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
 )
 
@@ -12,6 +15,8 @@ type CreatePayment struct {
 	CustomerID   uint64
 	Amount       uint64
 	CurrencyCode string
+	// Inbox records the command, its reply is published once the payment is captured
+	Inbox *inbox.Message
 }
 
 type CreatePaymentHandler CommandHandler[CreatePayment]
@@ -19,26 +24,93 @@ type CreatePaymentHandler CommandHandler[CreatePayment]
 type createPaymentHandler struct {
 	logger      logger.Logger
 	paymentRepo domain.PaymentRepository
+	gateway     domain.PaymentGateway
 }
 
-func NewCreatePaymentHandler(logger logger.Logger, paymentRepo domain.PaymentRepository) CreatePaymentHandler {
+func NewCreatePaymentHandler(logger logger.Logger, paymentRepo domain.PaymentRepository, gateway domain.PaymentGateway) CreatePaymentHandler {
 	return &createPaymentHandler{
 		logger:      logger,
 		paymentRepo: paymentRepo,
+		gateway:     gateway,
 	}
 }
 
+// Handle creates the pending payment, then authorizes and captures it with the gateway.
+// Every step is saved, a retried command goes on from the status the payment reached.
+// It returns domain.ErrPaymentDeclined when the provider refuses the payment.
 func (h *createPaymentHandler) Handle(ctx context.Context, cmd CreatePayment) error {
+	payment, err := h.loadOrCreate(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	for {
+		from := payment.Status
+		switch from {
+		case entity.StatusPending:
+			authorizationID, err := h.gateway.Authorize(ctx, payment)
+			if err != nil {
+				if !errors.Is(err, domain.ErrPaymentDeclined) {
+					return err
+				}
+				if err = payment.Transit(entity.StatusFailed, err.Error()); err != nil {
+					return err
+				}
+				if err = h.paymentRepo.TransitPayment(ctx, payment, from, nil); err != nil {
+					return err
+				}
+				continue
+			}
+
+			payment.AuthorizationID = authorizationID
+			if err = payment.Transit(entity.StatusAuthorized, ""); err != nil {
+				return err
+			}
+			if err = h.paymentRepo.TransitPayment(ctx, payment, from, nil); err != nil {
+				return err
+			}
+		case entity.StatusAuthorized:
+			if err = h.gateway.Capture(ctx, payment.AuthorizationID, payment.Amount); err != nil {
+				if !errors.Is(err, domain.ErrPaymentDeclined) {
+					return err
+				}
+				// The held amount is released, the customer is not charged
+				if voidErr := h.gateway.Void(ctx, payment.AuthorizationID); voidErr != nil {
+					return voidErr
+				}
+				if err = payment.Transit(entity.StatusVoided, err.Error()); err != nil {
+					return err
+				}
+				if err = h.paymentRepo.TransitPayment(ctx, payment, from, nil); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err = payment.Transit(entity.StatusCaptured, ""); err != nil {
+				return err
+			}
+			return h.paymentRepo.TransitPayment(ctx, payment, from, cmd.Inbox)
+		case entity.StatusCaptured:
+			return h.paymentRepo.RecordCommand(ctx, cmd.Inbox)
+		default:
+			// A failed, voided or refunded payment does not charge the customer anymore
+			return fmt.Errorf("%w: payment is %s: %s", domain.ErrPaymentDeclined, payment.Status, payment.Reason)
+		}
+	}
+}
+
+func (h *createPaymentHandler) loadOrCreate(ctx context.Context, cmd CreatePayment) (*entity.Payment, error) {
 	err := h.paymentRepo.CreatePayment(ctx, &entity.Payment{
 		ID:           cmd.ID,
 		CustomerID:   cmd.CustomerID,
 		Amount:       cmd.Amount,
 		CurrencyCode: cmd.CurrencyCode,
-	})
-
+		Status:       entity.StatusPending,
+	}, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return h.paymentRepo.LoadPayment(ctx, cmd.ID)
 }
@@ -0,0 +1,53 @@
+package command
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/internal/payment/domain"
+	"github.com/scul0405/saga-orchestration/internal/payment/domain/entity"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+)
+
+// HandleGatewayEvent applies a webhook notification of the payment provider
+type HandleGatewayEvent struct {
+	Type      string
+	PaymentID uint64
+}
+
+type HandleGatewayEventHandler CommandHandler[HandleGatewayEvent]
+
+type handleGatewayEventHandler struct {
+	logger      logger.Logger
+	paymentRepo domain.PaymentRepository
+}
+
+func NewHandleGatewayEventHandler(logger logger.Logger, paymentRepo domain.PaymentRepository) HandleGatewayEventHandler {
+	return &handleGatewayEventHandler{
+		logger:      logger,
+		paymentRepo: paymentRepo,
+	}
+}
+
+// Handle records the refunds made by the provider, the other events are ignored
+func (h *handleGatewayEventHandler) Handle(ctx context.Context, cmd HandleGatewayEvent) error {
+	if cmd.Type != domain.GatewayEventRefunded {
+		h.logger.Infof("Payment.HandleGatewayEvent: ignore %s event of payment %v", cmd.Type, cmd.PaymentID)
+		return nil
+	}
+
+	payment, err := h.paymentRepo.LoadPayment(ctx, cmd.PaymentID)
+	if err != nil {
+		return err
+	}
+
+	if payment.Status != entity.StatusCaptured {
+		h.logger.Infof("Payment.HandleGatewayEvent: ignore %s event of %s payment %v", cmd.Type, payment.Status, cmd.PaymentID)
+		return nil
+	}
+
+	if err = payment.Transit(entity.StatusRefunded, "refunded by the provider"); err != nil {
+		return err
+	}
+
+	// The refund was not requested by a saga, there is no command to record
+	return h.paymentRepo.TransitPayment(ctx, payment, entity.StatusCaptured, nil)
+}
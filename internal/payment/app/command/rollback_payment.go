@@ -2,12 +2,21 @@ package command
 
 import (
 	"context"
+	"errors"
 	"github.com/scul0405/saga-orchestration/internal/payment/domain"
+	"github.com/scul0405/saga-orchestration/internal/payment/domain/entity"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"gorm.io/gorm"
 )
 
+// reasonRolledBack is the reason of the payments compensated by a saga
+const reasonRolledBack = "rolled back"
+
 type RollbackPayment struct {
 	PaymentID uint64
+	// Inbox records the command, its reply is published once the payment is compensated
+	Inbox *inbox.Message
 }
 
 type RollbackPaymentHandler CommandHandler[RollbackPayment]
@@ -15,20 +24,53 @@ type RollbackPaymentHandler CommandHandler[RollbackPayment]
 type rollbackPaymentHandler struct {
 	logger      logger.Logger
 	paymentRepo domain.PaymentRepository
+	gateway     domain.PaymentGateway
 }
 
-func NewRollbackPaymentHandler(logger logger.Logger, paymentRepo domain.PaymentRepository) RollbackPaymentHandler {
+func NewRollbackPaymentHandler(logger logger.Logger, paymentRepo domain.PaymentRepository, gateway domain.PaymentGateway) RollbackPaymentHandler {
 	return &rollbackPaymentHandler{
 		logger:      logger,
 		paymentRepo: paymentRepo,
+		gateway:     gateway,
 	}
 }
 
+// Handle compensates the payment: a pending payment fails, an authorized one is voided and a captured one is refunded.
+// A payment which is already final did not charge the customer, only the command is recorded then.
+// A payment which was never created is created failed, so that a create command delivered later is declined.
 func (h *rollbackPaymentHandler) Handle(ctx context.Context, cmd RollbackPayment) error {
-	err := h.paymentRepo.DeletePayment(ctx, cmd.PaymentID)
+	payment, err := h.paymentRepo.LoadPayment(ctx, cmd.PaymentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return h.paymentRepo.CreatePayment(ctx, &entity.Payment{
+				ID:     cmd.PaymentID,
+				Status: entity.StatusFailed,
+				Reason: reasonRolledBack,
+			}, cmd.Inbox)
+		}
+		return err
+	}
+
+	from := payment.Status
+	switch from {
+	case entity.StatusPending:
+		err = payment.Transit(entity.StatusFailed, reasonRolledBack)
+	case entity.StatusAuthorized:
+		if err = h.gateway.Void(ctx, payment.AuthorizationID); err != nil {
+			return err
+		}
+		err = payment.Transit(entity.StatusVoided, reasonRolledBack)
+	case entity.StatusCaptured:
+		if err = h.gateway.Refund(ctx, payment.AuthorizationID, payment.Amount); err != nil {
+			return err
+		}
+		err = payment.Transit(entity.StatusRefunded, reasonRolledBack)
+	default:
+		return h.paymentRepo.RecordCommand(ctx, cmd.Inbox)
+	}
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return h.paymentRepo.TransitPayment(ctx, payment, from, cmd.Inbox)
 }
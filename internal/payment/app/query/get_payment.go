@@ -25,16 +25,19 @@ func NewGetPaymentHandler(logger logger.Logger, paymentRepo domain.PaymentReposi
 	}
 }
 
+// Handle returns the current state of the payment with its history
 func (h *getPaymentHandler) Handle(ctx context.Context, query GetPayment) (*entity.Payment, error) {
 	payment, err := h.paymentRepo.GetPayment(ctx, query.PaymentID)
 	if err != nil {
 		return nil, err
 	}
 
-	return &entity.Payment{
-		ID:           payment.ID,
-		CustomerID:   payment.CustomerID,
-		Amount:       payment.Amount,
-		CurrencyCode: payment.CurrencyCode,
-	}, nil
+	history, err := h.paymentRepo.ListPaymentTransitions(ctx, query.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := *payment
+	result.History = history
+	return &result, nil
 }
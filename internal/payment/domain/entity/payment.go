@@ -1,8 +1,72 @@
 package entity
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// StatusPending is a payment which is not authorized yet
+	StatusPending = "PENDING"
+	// StatusAuthorized is a payment whose amount is held on the customer account
+	StatusAuthorized = "AUTHORIZED"
+	// StatusCaptured is a payment whose amount is collected
+	StatusCaptured = "CAPTURED"
+	// StatusVoided is an authorized payment released without being captured
+	StatusVoided = "VOIDED"
+	// StatusRefunded is a captured payment paid back to the customer
+	StatusRefunded = "REFUNDED"
+	// StatusFailed is a payment declined by the provider, or rolled back before being authorized
+	StatusFailed = "FAILED"
+)
+
+var (
+	ErrInvalidTransition = errors.New("invalid payment transition")
+)
+
+// transitions are the statuses a payment can move to from each status
+var transitions = map[string][]string{
+	StatusPending:    {StatusAuthorized, StatusFailed},
+	StatusAuthorized: {StatusCaptured, StatusVoided},
+	StatusCaptured:   {StatusRefunded},
+}
+
+// Payment of a purchase, AuthorizationID identifies it at the payment provider once it is authorized
 type Payment struct {
-	ID           uint64
-	CustomerID   uint64
-	CurrencyCode string
-	Amount       uint64
+	ID              uint64
+	CustomerID      uint64
+	CurrencyCode    string
+	Amount          uint64
+	Status          string
+	Reason          string
+	AuthorizationID string
+	UpdatedAt       time.Time
+	CreatedAt       time.Time
+	History         *[]PaymentTransition
+}
+
+// PaymentTransition is an entry of the payment history
+type PaymentTransition struct {
+	Status    string
+	Reason    string
+	CreatedAt time.Time
+}
+
+// Transit moves the payment to status, reason tells why for the failed and rolled back payments
+func (p *Payment) Transit(status, reason string) error {
+	for _, next := range transitions[p.Status] {
+		if next == status {
+			p.Status = status
+			p.Reason = reason
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, p.Status, status)
+}
+
+// IsFinal tells whether the payment can not move anymore
+func (p *Payment) IsFinal() bool {
+	return len(transitions[p.Status]) == 0
 }
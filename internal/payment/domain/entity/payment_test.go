@@ -0,0 +1,27 @@
+package entity
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPaymentTransit(t *testing.T) {
+	t.Parallel()
+
+	payment := &Payment{ID: 1, Status: StatusPending}
+	require.NoError(t, payment.Transit(StatusAuthorized, ""))
+	require.ErrorIs(t, payment.Transit(StatusRefunded, "rolled back"), ErrInvalidTransition)
+	require.Equal(t, StatusAuthorized, payment.Status)
+
+	require.NoError(t, payment.Transit(StatusCaptured, ""))
+	require.False(t, payment.IsFinal())
+	require.NoError(t, payment.Transit(StatusRefunded, "rolled back"))
+	require.Equal(t, "rolled back", payment.Reason)
+	require.True(t, payment.IsFinal())
+
+	for _, status := range []string{StatusFailed, StatusVoided, StatusRefunded} {
+		final := &Payment{Status: status}
+		require.True(t, final.IsFinal())
+		require.ErrorIs(t, final.Transit(StatusCaptured, ""), ErrInvalidTransition)
+	}
+}
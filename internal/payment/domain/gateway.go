@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"github.com/scul0405/saga-orchestration/internal/payment/domain/entity"
+)
+
+var (
+	// ErrPaymentDeclined is returned when the provider refuses an operation, retrying it can not succeed
+	ErrPaymentDeclined = errors.New("payment declined")
+)
+
+// GatewayEventRefunded is notified by the provider when it refunded a payment outside of a saga, e.g. a chargeback
+const GatewayEventRefunded = "refund.succeeded"
+
+// PaymentGateway moves the money of the payments with the payment provider.
+// Authorize is keyed by the payment ID and the other operations by the authorization,
+// so a redelivered command does not charge or refund the customer twice.
+type PaymentGateway interface {
+	// Authorize holds the amount of the payment on the customer account and returns the authorization ID
+	Authorize(ctx context.Context, payment *entity.Payment) (string, error)
+	// Capture collects the amount held by the authorization
+	Capture(ctx context.Context, authorizationID string, amount uint64) error
+	// Void releases an authorization which was not captured
+	Void(ctx context.Context, authorizationID string) error
+	// Refund pays the captured amount back to the customer
+	Refund(ctx context.Context, authorizationID string, amount uint64) error
+}
@@ -3,10 +3,18 @@ package domain
 import (
 	"context"
 	"github.com/scul0405/saga-orchestration/internal/payment/domain/entity"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
 )
 
 type PaymentRepository interface {
+	// GetPayment returns the payment, possibly from the cache
 	GetPayment(ctx context.Context, paymentID uint64) (*entity.Payment, error)
-	CreatePayment(ctx context.Context, payment *entity.Payment) error
-	DeletePayment(ctx context.Context, paymentID uint64) error
+	// LoadPayment returns the current payment from the database, commands decide on it
+	LoadPayment(ctx context.Context, paymentID uint64) (*entity.Payment, error)
+	ListPaymentTransitions(ctx context.Context, paymentID uint64) (*[]entity.PaymentTransition, error)
+	// CreatePayment creates the payment unless it exists, msg is recorded with a payment created by a command
+	CreatePayment(ctx context.Context, payment *entity.Payment, msg *inbox.Message) error
+	TransitPayment(ctx context.Context, payment *entity.Payment, from string, msg *inbox.Message) error
+	// RecordCommand records a command which leaves the payment unchanged
+	RecordCommand(ctx context.Context, msg *inbox.Message) error
 }
@@ -2,25 +2,22 @@ package eventhandler
 
 import (
 	"context"
-	"encoding/json"
-	"github.com/avast/retry-go"
 	"github.com/scul0405/saga-orchestration/cmd/payment/config"
 	"github.com/scul0405/saga-orchestration/internal/common"
 	"github.com/scul0405/saga-orchestration/internal/payment/app"
 	"github.com/scul0405/saga-orchestration/internal/payment/app/command"
+	"github.com/scul0405/saga-orchestration/internal/payment/domain"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
 	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
-	"github.com/scul0405/saga-orchestration/pkg/timeconvert"
 	pb "github.com/scul0405/saga-orchestration/proto"
-	"github.com/segmentio/kafka-go"
 	"sync"
-	"time"
 )
 
 var (
-	retryAttempts uint = 10
-	retryDelay         = 1 * time.Second
-	poolSize           = 16
+	poolSize = 16
+	// businessErrors are the failures replied to the orchestrator at once, retrying the command can not fix them
+	businessErrors = []error{domain.ErrPaymentDeclined}
 )
 
 type EventHandler interface {
@@ -31,7 +28,8 @@ type eventHandler struct {
 	cfg        *config.Config
 	logger     logger.Logger
 	consumer   kafkaClient.ConsumerGroup
-	producer   kafkaClient.Producer
+	inbox      inbox.Inbox
+	dlq        kafkaClient.DeadLetterPublisher
 	paymentSvc app.Application
 }
 
@@ -39,13 +37,15 @@ func NewEventHandler(
 	cfg *config.Config,
 	logger logger.Logger,
 	consumer kafkaClient.ConsumerGroup,
-	producer kafkaClient.Producer,
+	inbox inbox.Inbox,
+	dlq kafkaClient.DeadLetterPublisher,
 	paymentSvc app.Application) EventHandler {
 	return &eventHandler{
 		cfg:        cfg,
 		logger:     logger,
 		consumer:   consumer,
-		producer:   producer,
+		inbox:      inbox,
+		dlq:        dlq,
 		paymentSvc: paymentSvc,
 	}
 }
@@ -55,9 +55,11 @@ func (h *eventHandler) Run(ctx context.Context) {
 	go h.consumer.ConsumeTopic(ctx, poolSize, common.RollbackPaymentGroupID, common.RollbackPaymentTopic, h.rollbackPaymentWorker)
 }
 
-func (h *eventHandler) createPaymentWorker(ctx context.Context, r *kafka.Reader, wg *sync.WaitGroup, workerID int) {
+func (h *eventHandler) createPaymentWorker(ctx context.Context, r kafkaClient.MessageReader, wg *sync.WaitGroup, workerID int) {
 	defer wg.Done()
 
+	policy := h.cfg.Kafka.Retry.Policy(common.CreatePaymentTopic)
+
 	for {
 		m, err := r.FetchMessage(ctx)
 		if err != nil {
@@ -65,57 +67,61 @@ func (h *eventHandler) createPaymentWorker(ctx context.Context, r *kafka.Reader,
 			return
 		}
 
+		msgCtx, span := kafkaClient.StartConsumerSpan(ctx, m, "Payment.CreatePaymentWorker")
+
 		var purchase pb.CreatePurchaseRequest
-		if err = json.Unmarshal(m.Value, &purchase); err != nil {
+		if err = kafkaClient.Decode(m, &purchase); err != nil {
 			h.logger.Errorf("Payment.CreatePaymentWorker: UnmarshalProto", err)
+			if err = h.dlq.Publish(msgCtx, m, err, 1, "Payment.CreatePaymentWorker"); err != nil {
+				h.logger.Errorf("Payment.CreatePaymentWorker: PublishDeadLetter", err)
+			}
+			if err = r.CommitMessages(msgCtx, m); err != nil {
+				h.logger.Errorf("Payment.CreatePaymentWorker: CommitMessages", err)
+			}
+			span.End()
 			continue
 		}
 		h.logger.Infof("CreatePaymentWorker: %v, message at topic/partition/offset %v/%v/%v: %s = %s\n", workerID, m.Topic, m.Partition, m.Offset, string(m.Key), string(m.Value))
 
-		reply := pb.CreatePurchaseResponse{
-			PurchaseId: purchase.PurchaseId,
-		}
-		if err = retry.Do(func() error {
-			err = h.paymentSvc.Commands.CreatePayment.Handle(ctx, decodePb2CreatePaymentCmd(&purchase))
-			if err != nil {
-				reply.Success = false
-				reply.ErrorMessage = err.Error()
-			} else {
-				reply.Success = true
+		attempts, err := policy.Do(msgCtx, func() error {
+			// A redelivered command only publishes its stored reply again, unless an operator retries a rejected one
+			replayed, err := h.inbox.Replay(msgCtx, purchase.PurchaseId, common.CreatePaymentHandler, isRetried(&m))
+			if err != nil || replayed {
+				return err
 			}
 
-			reply.Purchase = purchase.Purchase
-			reply.Timestamp = timeconvert.Time2pbTimestamp(time.Now())
-			payload, _ := json.Marshal(&reply)
-
-			return h.producer.PublishMessage(ctx, kafka.Message{
-				Topic: common.ReplyTopic,
-				Value: payload,
-				Headers: []kafka.Header{
-					{
-						Key:   common.HandlerHeader,
-						Value: []byte(common.CreatePaymentHandler),
-					},
-				},
-			})
-		},
-			retry.Attempts(retryAttempts),
-			retry.Delay(retryDelay),
-			retry.Context(ctx),
-		); err != nil {
-			// TODO: publish error message to error topic
+			cmd := decodePb2CreatePaymentCmd(&purchase)
+			cmd.Inbox = encodeInboxMessage(&purchase, common.CreatePaymentHandler, nil)
+			err = h.paymentSvc.Commands.CreatePayment.Handle(msgCtx, cmd)
+			if kafkaClient.Classify(err, businessErrors...) != kafkaClient.BusinessFailure {
+				// With inbox.ErrProcessed the next attempt replays the reply of a concurrent delivery
+				return err
+			}
+
+			// The declined payment is kept with its reason, the command is recorded with its failure reply
+			return h.inbox.Reject(msgCtx, encodeInboxMessage(&purchase, common.CreatePaymentHandler, err))
+		})
+		if err != nil {
+			h.logger.Errorf("Payment.CreatePaymentWorker: failed after %d attempts: %v", attempts, err)
+			if err = h.dlq.Publish(msgCtx, m, err, attempts, "Payment.CreatePaymentWorker"); err != nil {
+				h.logger.Errorf("Payment.CreatePaymentWorker: PublishDeadLetter", err)
+			}
 		}
 
-		err = r.CommitMessages(ctx, m)
+		err = r.CommitMessages(msgCtx, m)
 		if err != nil {
 			h.logger.Errorf("Order.CreateOrderWorker: CommitMessages", err)
 		}
+
+		span.End()
 	}
 }
 
-func (h *eventHandler) rollbackPaymentWorker(ctx context.Context, r *kafka.Reader, wg *sync.WaitGroup, workerID int) {
+func (h *eventHandler) rollbackPaymentWorker(ctx context.Context, r kafkaClient.MessageReader, wg *sync.WaitGroup, workerID int) {
 	defer wg.Done()
 
+	policy := h.cfg.Kafka.Retry.Policy(common.RollbackPaymentTopic)
+
 	for {
 		m, err := r.FetchMessage(ctx)
 		if err != nil {
@@ -123,50 +129,53 @@ func (h *eventHandler) rollbackPaymentWorker(ctx context.Context, r *kafka.Reade
 			return
 		}
 
+		msgCtx, span := kafkaClient.StartConsumerSpan(ctx, m, "Payment.RollbackPaymentWorker")
+
 		var purchase pb.CreatePurchaseRequest
-		if err = json.Unmarshal(m.Value, &purchase); err != nil {
+		if err = kafkaClient.Decode(m, &purchase); err != nil {
 			h.logger.Errorf("Payment.RollbackPaymentWorker: UnmarshalProto", err)
+			if err = h.dlq.Publish(msgCtx, m, err, 1, "Payment.RollbackPaymentWorker"); err != nil {
+				h.logger.Errorf("Payment.RollbackPaymentWorker: PublishDeadLetter", err)
+			}
+			if err = r.CommitMessages(msgCtx, m); err != nil {
+				h.logger.Errorf("Payment.RollbackPaymentWorker: CommitMessages", err)
+			}
+			span.End()
 			continue
 		}
 		h.logger.Infof("RollbackPaymentWorker: %v, message at topic/partition/offset %v/%v/%v: %s = %s\n", workerID, m.Topic, m.Partition, m.Offset, string(m.Key), string(m.Value))
 
-		reply := pb.CreatePurchaseResponse{
-			PurchaseId: purchase.PurchaseId,
-		}
-		if err = retry.Do(func() error {
-			err = h.paymentSvc.Commands.RollbackPayment.Handle(ctx, command.RollbackPayment{PaymentID: purchase.PurchaseId})
-			if err != nil {
-				reply.Success = false
-				reply.ErrorMessage = err.Error()
-			} else {
-				reply.Success = true
+		attempts, err := policy.Do(msgCtx, func() error {
+			// A redelivered command only publishes its stored reply again, unless an operator retries a rejected one
+			replayed, err := h.inbox.Replay(msgCtx, purchase.PurchaseId, common.RollbackPaymentHandler, isRetried(&m))
+			if err != nil || replayed {
+				return err
 			}
 
-			reply.Purchase = purchase.Purchase
-			reply.Timestamp = timeconvert.Time2pbTimestamp(time.Now())
-			payload, _ := json.Marshal(&reply)
-
-			return h.producer.PublishMessage(ctx, kafka.Message{
-				Topic: common.ReplyTopic,
-				Value: payload,
-				Headers: []kafka.Header{
-					{
-						Key:   common.HandlerHeader,
-						Value: []byte(common.RollbackPaymentHandler),
-					},
-				},
+			err = h.paymentSvc.Commands.RollbackPayment.Handle(msgCtx, command.RollbackPayment{
+				PaymentID: purchase.PurchaseId,
+				Inbox:     encodeInboxMessage(&purchase, common.RollbackPaymentHandler, nil),
 			})
-		},
-			retry.Attempts(retryAttempts),
-			retry.Delay(retryDelay),
-			retry.Context(ctx),
-		); err != nil {
-			// TODO: publish error message to error topic
+			if kafkaClient.Classify(err, businessErrors...) != kafkaClient.BusinessFailure {
+				// With inbox.ErrProcessed the next attempt replays the reply of a concurrent delivery
+				return err
+			}
+
+			// The payment was not refunded, only the command and its failure reply are recorded
+			return h.inbox.Reject(msgCtx, encodeInboxMessage(&purchase, common.RollbackPaymentHandler, err))
+		})
+		if err != nil {
+			h.logger.Errorf("Payment.RollbackPaymentWorker: failed after %d attempts: %v", attempts, err)
+			if err = h.dlq.Publish(msgCtx, m, err, attempts, "Payment.RollbackPaymentWorker"); err != nil {
+				h.logger.Errorf("Payment.RollbackPaymentWorker: PublishDeadLetter", err)
+			}
 		}
 
-		err = r.CommitMessages(ctx, m)
+		err = r.CommitMessages(msgCtx, m)
 		if err != nil {
 			h.logger.Errorf("Payment.RollbackPaymentWorker: CommitMessages", err)
 		}
+
+		span.End()
 	}
 }
@@ -1,8 +1,15 @@
 package eventhandler
 
 import (
+	"github.com/scul0405/saga-orchestration/internal/common"
 	"github.com/scul0405/saga-orchestration/internal/payment/app/command"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
+	"github.com/scul0405/saga-orchestration/internal/pkg/outbox"
+	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
+	"github.com/scul0405/saga-orchestration/pkg/timeconvert"
 	pb "github.com/scul0405/saga-orchestration/proto"
+	"github.com/segmentio/kafka-go"
+	"time"
 )
 
 func decodePb2CreatePaymentCmd(purchase *pb.CreatePurchaseRequest) command.CreatePayment {
@@ -13,3 +20,49 @@ func decodePb2CreatePaymentCmd(purchase *pb.CreatePurchaseRequest) command.Creat
 		CurrencyCode: purchase.Purchase.Payment.CurrencyCode,
 	}
 }
+
+// encodeReply builds the reply of a saga step for the given handler, the step failed when err is not nil
+func encodeReply(purchase *pb.CreatePurchaseRequest, handler string, err error) *outbox.Message {
+	reply := pb.CreatePurchaseResponse{
+		PurchaseId: purchase.PurchaseId,
+		Purchase:   purchase.Purchase,
+		Success:    err == nil,
+		Timestamp:  timeconvert.Time2pbTimestamp(time.Now()),
+	}
+	if err != nil {
+		reply.ErrorMessage = err.Error()
+	}
+	payload, contentType, _ := kafkaClient.Encode(kafkaClient.ContentTypeProtobuf, &reply)
+
+	return &outbox.Message{
+		Topic: common.ReplyTopic,
+		Key:   common.PurchaseKey(purchase.PurchaseId),
+		Value: payload,
+		Headers: []kafka.Header{
+			{
+				Key:   common.HandlerHeader,
+				Value: []byte(handler),
+			},
+			contentType,
+		},
+	}
+}
+
+// encodeInboxMessage records the command of the purchase for the given handler with its reply
+func encodeInboxMessage(purchase *pb.CreatePurchaseRequest, handler string, err error) *inbox.Message {
+	return &inbox.Message{
+		PurchaseID: purchase.PurchaseId,
+		Command:    handler,
+		Reply:      encodeReply(purchase, handler, err),
+	}
+}
+
+// isRetried tells whether an operator re-sent the command
+func isRetried(m *kafka.Message) bool {
+	for _, header := range m.Headers {
+		if header.Key == common.RetryHeader {
+			return true
+		}
+	}
+	return false
+}
@@ -3,6 +3,8 @@ package postgres
 import (
 	"github.com/scul0405/saga-orchestration/cmd/payment/config"
 	"github.com/scul0405/saga-orchestration/internal/payment/infrastructure/db/postgres/model"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
+	"github.com/scul0405/saga-orchestration/internal/pkg/outbox"
 	"gorm.io/gorm"
 )
 
@@ -20,10 +22,27 @@ func (m *Migrator) Migrate(migration config.Migration) error {
 	}
 
 	if migration.Recreate {
-		if err := m.db.Migrator().DropTable(&model.Payment{}); err != nil {
+		if err := m.db.Migrator().DropTable(&model.Payment{}, &model.PaymentTransition{}, &outbox.OutboxMessage{}, &inbox.InboxMessage{}); err != nil {
 			return err
 		}
 	}
 
-	return m.db.AutoMigrate(&model.Payment{})
+	if err := m.db.AutoMigrate(&model.Payment{}, &model.PaymentTransition{}, &outbox.OutboxMessage{}, &inbox.InboxMessage{}); err != nil {
+		return err
+	}
+
+	return m.migrateRefunded()
+}
+
+// migrateRefunded turns the refunded flag of the payments rolled back before the status into the REFUNDED status
+func (m *Migrator) migrateRefunded() error {
+	if !m.db.Migrator().HasColumn(&model.Payment{}, "refunded") {
+		return nil
+	}
+
+	if err := m.db.Exec("UPDATE payments SET status = 'REFUNDED' WHERE refunded").Error; err != nil {
+		return err
+	}
+
+	return m.db.Migrator().DropColumn(&model.Payment{}, "refunded")
 }
@@ -1,10 +1,23 @@
 package model
 
+// Payment defaults to the CAPTURED status, the payments created before the status were captured
 type Payment struct {
-	ID           uint64 `gorm:"primaryKey"`
-	CustomerID   uint64 `gorm:"index;not null"`
-	CurrencyCode string `gorm:"not null"`
-	Amount       uint64 `gorm:"not null"`
-	UpdatedAt    int64  `gorm:"autoUpdateTime:milli"`
-	CreatedAt    int64  `gorm:"autoCreateTime:milli"`
+	ID              uint64 `gorm:"primaryKey"`
+	CustomerID      uint64 `gorm:"index;not null"`
+	CurrencyCode    string `gorm:"not null"`
+	Amount          uint64 `gorm:"not null"`
+	Status          string `gorm:"type:varchar(20);not null;default:'CAPTURED'"`
+	Reason          string
+	AuthorizationID string `gorm:"type:varchar(64);not null;default:''"`
+	UpdatedAt       int64  `gorm:"autoUpdateTime:milli"`
+	CreatedAt       int64  `gorm:"autoCreateTime:milli"`
+}
+
+// PaymentTransition is an entry of the payment history, one per status the payment moved to
+type PaymentTransition struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement"`
+	PaymentID uint64 `gorm:"index;not null"`
+	Status    string `gorm:"type:varchar(20);not null"`
+	Reason    string
+	CreatedAt int64 `gorm:"autoCreateTime:milli"`
 }
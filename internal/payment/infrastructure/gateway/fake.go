@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/scul0405/saga-orchestration/cmd/payment/config"
+	"github.com/scul0405/saga-orchestration/internal/payment/domain"
+	"github.com/scul0405/saga-orchestration/internal/payment/domain/entity"
+	"strconv"
+	"sync"
+)
+
+var (
+	ErrUnknownAuthorization = errors.New("unknown authorization")
+)
+
+// fakeGateway is an in-process payment provider for local runs and tests, it keeps the authorizations in memory
+// and declines the payments matching its rules
+type fakeGateway struct {
+	rules          config.DeclineRules
+	mu             sync.Mutex
+	authorizations map[string]*fakeAuthorization
+}
+
+type fakeAuthorization struct {
+	amount   uint64
+	captured bool
+	voided   bool
+	refunded bool
+}
+
+func NewFakeGateway(rules config.DeclineRules) domain.PaymentGateway {
+	return &fakeGateway{
+		rules:          rules,
+		authorizations: make(map[string]*fakeAuthorization),
+	}
+}
+
+func (g *fakeGateway) Authorize(_ context.Context, payment *entity.Payment) (string, error) {
+	if reason := g.declineReason(payment); reason != "" {
+		return "", fmt.Errorf("%w: %s", domain.ErrPaymentDeclined, reason)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// The authorization is keyed by the payment, authorizing it again returns the same authorization
+	id := "fake_auth_" + strconv.FormatUint(payment.ID, 10)
+	if _, ok := g.authorizations[id]; !ok {
+		g.authorizations[id] = &fakeAuthorization{amount: payment.Amount}
+	}
+
+	return id, nil
+}
+
+func (g *fakeGateway) Capture(_ context.Context, authorizationID string, amount uint64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	auth, ok := g.authorizations[authorizationID]
+	if !ok {
+		return ErrUnknownAuthorization
+	}
+
+	switch {
+	case auth.voided:
+		return fmt.Errorf("%w: authorization is voided", domain.ErrPaymentDeclined)
+	case amount > auth.amount:
+		return fmt.Errorf("%w: capture exceeds the authorized amount", domain.ErrPaymentDeclined)
+	}
+
+	auth.captured = true
+	return nil
+}
+
+func (g *fakeGateway) Void(_ context.Context, authorizationID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	auth, ok := g.authorizations[authorizationID]
+	if !ok {
+		return ErrUnknownAuthorization
+	}
+
+	if auth.captured {
+		return fmt.Errorf("%w: authorization is captured, it must be refunded", domain.ErrPaymentDeclined)
+	}
+
+	auth.voided = true
+	return nil
+}
+
+func (g *fakeGateway) Refund(_ context.Context, authorizationID string, amount uint64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	auth, ok := g.authorizations[authorizationID]
+	if !ok {
+		return ErrUnknownAuthorization
+	}
+
+	switch {
+	case auth.refunded:
+		// Refunding again is a no-op, the customer is only paid back once
+		return nil
+	case !auth.captured:
+		return fmt.Errorf("%w: authorization is not captured", domain.ErrPaymentDeclined)
+	case amount > auth.amount:
+		return fmt.Errorf("%w: refund exceeds the captured amount", domain.ErrPaymentDeclined)
+	}
+
+	auth.refunded = true
+	return nil
+}
+
+// declineReason returns why the payment is declined, or an empty string when it is accepted
+func (g *fakeGateway) declineReason(payment *entity.Payment) string {
+	if g.rules.MaxAmount != 0 && payment.Amount > g.rules.MaxAmount {
+		return "amount over limit"
+	}
+
+	for _, currency := range g.rules.Currencies {
+		if currency == payment.CurrencyCode {
+			return "currency not supported"
+		}
+	}
+
+	for _, customer := range g.rules.Customers {
+		if customer == payment.CustomerID {
+			return "customer blocked"
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,22 @@
+package gateway
+
+import (
+	"fmt"
+	"github.com/scul0405/saga-orchestration/cmd/payment/config"
+	"github.com/scul0405/saga-orchestration/internal/payment/domain"
+)
+
+// NewGateway creates the gateway of the configured provider, the fake gateway is the default
+func NewGateway(cfg config.Gateway) (domain.PaymentGateway, error) {
+	switch cfg.Provider {
+	case "", "fake":
+		return NewFakeGateway(cfg.Decline), nil
+	case "http":
+		if cfg.Endpoint == "" {
+			return nil, fmt.Errorf("gateway: endpoint of the http provider is not configured")
+		}
+		return NewHTTPGateway(cfg), nil
+	default:
+		return nil, fmt.Errorf("gateway: unknown provider %q", cfg.Provider)
+	}
+}
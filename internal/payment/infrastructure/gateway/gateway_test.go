@@ -0,0 +1,108 @@
+package gateway
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/scul0405/saga-orchestration/cmd/payment/config"
+	"github.com/scul0405/saga-orchestration/internal/payment/domain"
+	"github.com/scul0405/saga-orchestration/internal/payment/domain/entity"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFakeGateway(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	g := NewFakeGateway(config.DeclineRules{
+		MaxAmount:  1000,
+		Currencies: []string{"XXX"},
+		Customers:  []uint64{13},
+	})
+
+	for _, declined := range []entity.Payment{
+		{ID: 1, CustomerID: 1, Amount: 1001, CurrencyCode: "USD"},
+		{ID: 2, CustomerID: 1, Amount: 10, CurrencyCode: "XXX"},
+		{ID: 3, CustomerID: 13, Amount: 10, CurrencyCode: "USD"},
+	} {
+		_, err := g.Authorize(ctx, &declined)
+		require.ErrorIs(t, err, domain.ErrPaymentDeclined)
+	}
+
+	payment := &entity.Payment{ID: 4, CustomerID: 1, Amount: 1000, CurrencyCode: "USD"}
+	authorizationID, err := g.Authorize(ctx, payment)
+	require.NoError(t, err)
+
+	// authorizing the same payment again returns the same authorization
+	again, err := g.Authorize(ctx, payment)
+	require.NoError(t, err)
+	require.Equal(t, authorizationID, again)
+
+	require.ErrorIs(t, g.Refund(ctx, authorizationID, 1000), domain.ErrPaymentDeclined)
+	require.ErrorIs(t, g.Capture(ctx, authorizationID, 1001), domain.ErrPaymentDeclined)
+	require.NoError(t, g.Capture(ctx, authorizationID, 1000))
+	require.ErrorIs(t, g.Void(ctx, authorizationID), domain.ErrPaymentDeclined)
+	require.NoError(t, g.Refund(ctx, authorizationID, 1000))
+	require.NoError(t, g.Refund(ctx, authorizationID, 1000))
+
+	require.ErrorIs(t, g.Capture(ctx, "fake_auth_5", 10), ErrUnknownAuthorization)
+}
+
+func TestHTTPGateway(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+
+		switch r.URL.Path {
+		case "/authorizations":
+			require.Equal(t, "authorize-1", r.Header.Get("Idempotency-Key"))
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(authorizeResponse{ID: "auth_1"})
+		case "/authorizations/auth_1/capture":
+			w.WriteHeader(http.StatusPaymentRequired)
+			_ = json.NewEncoder(w).Encode(errorResponse{Reason: "insufficient funds"})
+		case "/authorizations/auth_1/void":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	g := NewHTTPGateway(config.Gateway{Endpoint: srv.URL, APIKey: "key", Timeout: time.Second})
+
+	authorizationID, err := g.Authorize(ctx, &entity.Payment{ID: 1, CustomerID: 1, Amount: 10, CurrencyCode: "USD"})
+	require.NoError(t, err)
+	require.Equal(t, "auth_1", authorizationID)
+
+	err = g.Capture(ctx, authorizationID, 10)
+	require.ErrorIs(t, err, domain.ErrPaymentDeclined)
+	require.ErrorContains(t, err, "insufficient funds")
+
+	require.NoError(t, g.Void(ctx, authorizationID))
+
+	err = g.Refund(ctx, authorizationID, 10)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, domain.ErrPaymentDeclined)
+}
+
+func TestVerifySignature(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{"type":"refund.succeeded","reference":"1"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	require.True(t, VerifySignature("secret", body, signature))
+	require.False(t, VerifySignature("other", body, signature))
+	require.False(t, VerifySignature("secret", body, "not hex"))
+}
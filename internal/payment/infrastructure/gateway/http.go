@@ -0,0 +1,152 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/scul0405/saga-orchestration/cmd/payment/config"
+	"github.com/scul0405/saga-orchestration/internal/payment/domain"
+	"github.com/scul0405/saga-orchestration/internal/payment/domain/entity"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the webhook body, keyed with the webhook secret
+const SignatureHeader = "X-Signature"
+
+// WebhookEvent is a notification of the payment provider, Reference is the ID of the payment
+type WebhookEvent struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	Reference       string `json:"reference"`
+	AuthorizationID string `json:"authorization_id"`
+}
+
+// PaymentID returns the payment the event is about
+func (e *WebhookEvent) PaymentID() (uint64, error) {
+	return strconv.ParseUint(e.Reference, 10, 64)
+}
+
+// VerifySignature tells whether signature is the signature of the webhook body with secret
+func VerifySignature(secret string, body []byte, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// httpGateway calls the REST API of the payment provider. Requests are retried by the saga workers,
+// the provider deduplicates them with the Idempotency-Key header.
+type httpGateway struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+func NewHTTPGateway(cfg config.Gateway) domain.PaymentGateway {
+	return &httpGateway{
+		endpoint: cfg.Endpoint,
+		apiKey:   cfg.APIKey,
+		client:   &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+type authorizeRequest struct {
+	Reference    string `json:"reference"`
+	CustomerID   uint64 `json:"customer_id"`
+	Amount       uint64 `json:"amount"`
+	CurrencyCode string `json:"currency_code"`
+}
+
+type authorizeResponse struct {
+	ID string `json:"id"`
+}
+
+type amountRequest struct {
+	Amount uint64 `json:"amount"`
+}
+
+type errorResponse struct {
+	Reason string `json:"reason"`
+}
+
+func (g *httpGateway) Authorize(ctx context.Context, payment *entity.Payment) (string, error) {
+	reference := strconv.FormatUint(payment.ID, 10)
+
+	var resp authorizeResponse
+	err := g.post(ctx, "/authorizations", "authorize-"+reference, &authorizeRequest{
+		Reference:    reference,
+		CustomerID:   payment.CustomerID,
+		Amount:       payment.Amount,
+		CurrencyCode: payment.CurrencyCode,
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+
+	return resp.ID, nil
+}
+
+func (g *httpGateway) Capture(ctx context.Context, authorizationID string, amount uint64) error {
+	return g.post(ctx, "/authorizations/"+url.PathEscape(authorizationID)+"/capture", "capture-"+authorizationID, &amountRequest{Amount: amount}, nil)
+}
+
+func (g *httpGateway) Void(ctx context.Context, authorizationID string) error {
+	return g.post(ctx, "/authorizations/"+url.PathEscape(authorizationID)+"/void", "void-"+authorizationID, nil, nil)
+}
+
+func (g *httpGateway) Refund(ctx context.Context, authorizationID string, amount uint64) error {
+	return g.post(ctx, "/authorizations/"+url.PathEscape(authorizationID)+"/refunds", "refund-"+authorizationID, &amountRequest{Amount: amount}, nil)
+}
+
+// post sends body to the provider and decodes its answer into out. A 402 or 422 answer is a declined operation,
+// the other failures are transient and retried.
+func (g *httpGateway) post(ctx context.Context, path, idempotencyKey string, body, out interface{}) error {
+	payload := []byte("{}")
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.endpoint+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+g.apiKey)
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("gateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPaymentRequired || resp.StatusCode == http.StatusUnprocessableEntity:
+		var declined errorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&declined)
+		return fmt.Errorf("%w: %s", domain.ErrPaymentDeclined, declined.Reason)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("gateway: POST %s: unexpected status %d", path, resp.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -1,8 +1,19 @@
 package dto
 
+import "time"
+
 type Payment struct {
-	ID           uint64 `json:"id"`
-	CustomerID   uint64 `json:"customer_id"`
-	Amount       uint64 `json:"amount"`
-	CurrencyCode string `json:"currency_code"`
+	ID           uint64              `json:"id"`
+	CustomerID   uint64              `json:"customer_id"`
+	Amount       uint64              `json:"amount"`
+	CurrencyCode string              `json:"currency_code"`
+	Status       string              `json:"status"`
+	Reason       string              `json:"reason,omitempty"`
+	History      []PaymentTransition `json:"history"`
+}
+
+type PaymentTransition struct {
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
 }
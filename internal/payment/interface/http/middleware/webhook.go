@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/scul0405/saga-orchestration/internal/payment/infrastructure/gateway"
+	"io"
+	"net/http"
+)
+
+// WebhookSignatureMiddleware only lets through the webhooks signed by the payment provider with secret
+func WebhookSignatureMiddleware(secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+
+		if secret == "" || !gateway.VerifySignature(secret, body, c.GetHeader(gateway.SignatureHeader)) {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
@@ -1,11 +1,15 @@
 package http
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/scul0405/saga-orchestration/internal/payment/app"
+	"github.com/scul0405/saga-orchestration/internal/payment/app/command"
 	"github.com/scul0405/saga-orchestration/internal/payment/app/query"
+	"github.com/scul0405/saga-orchestration/internal/payment/infrastructure/gateway"
 	"github.com/scul0405/saga-orchestration/internal/payment/infrastructure/grpc"
 	"github.com/scul0405/saga-orchestration/internal/payment/interface/http/dto"
+	"gorm.io/gorm"
 	"net/http"
 	"strconv"
 )
@@ -13,6 +17,7 @@ import (
 var (
 	OkMessage       = "success"
 	ErrInvalidID    = "invalid id"
+	ErrNotFound     = "not found"
 	ErrInvalidJSON  = "invalid json"
 	ErrForbidden    = "forbidden"
 	ErrInternal     = "internal error"
@@ -20,14 +25,16 @@ var (
 )
 
 type Router struct {
-	app     app.Application
-	authSvc grpc.AuthService
+	app           app.Application
+	authSvc       grpc.AuthService
+	webhookSecret string
 }
 
-func NewRouter(app app.Application, authSvc grpc.AuthService) *Router {
+func NewRouter(app app.Application, authSvc grpc.AuthService, webhookSecret string) *Router {
 	return &Router{
-		app:     app,
-		authSvc: authSvc,
+		app:           app,
+		authSvc:       authSvc,
+		webhookSecret: webhookSecret,
 	}
 }
 
@@ -46,6 +53,11 @@ func (r *Router) GetPayment(c *gin.Context) {
 
 	payment, err := r.app.Queries.GetPayment.Handle(c, query.GetPayment{PaymentID: paymentID})
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": ErrNotFound})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
 		return
 	}
@@ -55,14 +67,57 @@ func (r *Router) GetPayment(c *gin.Context) {
 		return
 	}
 
+	history := make([]dto.PaymentTransition, len(*payment.History))
+	for i, t := range *payment.History {
+		history[i] = dto.PaymentTransition{
+			Status:    t.Status,
+			Reason:    t.Reason,
+			CreatedAt: t.CreatedAt,
+		}
+	}
+
 	c.JSON(http.StatusOK, &dto.Payment{
 		ID:           payment.ID,
 		CustomerID:   payment.CustomerID,
 		Amount:       payment.Amount,
 		CurrencyCode: payment.CurrencyCode,
+		Status:       payment.Status,
+		Reason:       payment.Reason,
+		History:      history,
 	})
 }
 
+// HandleGatewayWebhook receives the notifications of the payment provider
+func (r *Router) HandleGatewayWebhook(c *gin.Context) {
+	var event gateway.WebhookEvent
+	if err := c.ShouldBindJSON(&event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidJSON})
+		return
+	}
+
+	paymentID, err := event.PaymentID()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidID})
+		return
+	}
+
+	err = r.app.Commands.HandleGatewayEvent.Handle(c, command.HandleGatewayEvent{
+		Type:      event.Type,
+		PaymentID: paymentID,
+	})
+	if err != nil {
+		// An event of an unknown payment can not succeed later, the provider must not deliver it again
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": ErrNotFound})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": OkMessage})
+}
+
 func (r *Router) extractCustomerID(c *gin.Context) uint64 {
 	id, exists := c.Get("customer_id")
 	if !exists {
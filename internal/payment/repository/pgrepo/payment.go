@@ -2,9 +2,17 @@ package pgrepo
 
 import (
 	"context"
+	"errors"
 	"github.com/scul0405/saga-orchestration/internal/payment/domain/entity"
 	"github.com/scul0405/saga-orchestration/internal/payment/infrastructure/db/postgres/model"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var (
+	ErrStalePayment = errors.New("payment was updated concurrently")
 )
 
 type paymentRepositoryImpl struct {
@@ -13,8 +21,10 @@ type paymentRepositoryImpl struct {
 
 type PaymentRepository interface {
 	GetPayment(ctx context.Context, paymentID uint64) (*entity.Payment, error)
-	CreatePayment(ctx context.Context, payment *entity.Payment) error
-	DeletePayment(ctx context.Context, paymentID uint64) error
+	ListPaymentTransitions(ctx context.Context, paymentID uint64) (*[]entity.PaymentTransition, error)
+	CreatePayment(ctx context.Context, payment *entity.Payment, msg *inbox.Message) error
+	TransitPayment(ctx context.Context, payment *entity.Payment, from string, msg *inbox.Message) error
+	RecordCommand(ctx context.Context, msg *inbox.Message) error
 }
 
 func NewOrderRepository(db *gorm.DB) PaymentRepository {
@@ -28,31 +38,108 @@ func (r *paymentRepositoryImpl) GetPayment(ctx context.Context, paymentID uint64
 	}
 
 	return &entity.Payment{
-		ID:           payment.ID,
-		CustomerID:   payment.CustomerID,
-		Amount:       payment.Amount,
-		CurrencyCode: payment.CurrencyCode,
+		ID:              payment.ID,
+		CustomerID:      payment.CustomerID,
+		Amount:          payment.Amount,
+		CurrencyCode:    payment.CurrencyCode,
+		Status:          payment.Status,
+		Reason:          payment.Reason,
+		AuthorizationID: payment.AuthorizationID,
+		UpdatedAt:       time.UnixMilli(payment.UpdatedAt),
+		CreatedAt:       time.UnixMilli(payment.CreatedAt),
 	}, nil
 }
 
-func (r *paymentRepositoryImpl) CreatePayment(ctx context.Context, payment *entity.Payment) error {
+// ListPaymentTransitions returns the history of the payment, oldest first
+func (r *paymentRepositoryImpl) ListPaymentTransitions(ctx context.Context, paymentID uint64) (*[]entity.PaymentTransition, error) {
+	var transitions []model.PaymentTransition
+	if err := r.db.WithContext(ctx).Where("payment_id = ?", paymentID).Order("id").Find(&transitions).Error; err != nil {
+		return nil, err
+	}
+
+	history := make([]entity.PaymentTransition, len(transitions))
+	for i, t := range transitions {
+		history[i] = entity.PaymentTransition{
+			Status:    t.Status,
+			Reason:    t.Reason,
+			CreatedAt: time.UnixMilli(t.CreatedAt),
+		}
+	}
+
+	return &history, nil
+}
+
+// CreatePayment creates the payment with the first entry of its history, and records the command in the inbox
+// in the same transaction when msg is not nil.
+// A payment which already exists is kept as is, a retried command goes on from its current status.
+// A command recorded with the payment decided on its absence, ErrStalePayment is returned then.
+func (r *paymentRepositoryImpl) CreatePayment(ctx context.Context, payment *entity.Payment, msg *inbox.Message) error {
 	paymentModel := model.Payment{
 		ID:           payment.ID,
 		CustomerID:   payment.CustomerID,
 		Amount:       payment.Amount,
 		CurrencyCode: payment.CurrencyCode,
+		Status:       payment.Status,
+		Reason:       payment.Reason,
 	}
 
-	if err := r.db.Create(&paymentModel).WithContext(ctx).Error; err != nil {
-		return err
-	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&paymentModel)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if msg != nil {
+				return ErrStalePayment
+			}
+			return nil
+		}
+
+		if err := tx.Create(encodeTransition(payment)).Error; err != nil {
+			return err
+		}
+
+		return inbox.Add(tx, msg)
+	})
+}
+
+// TransitPayment saves the status of the payment moved from the status from, with an entry of its history,
+// and records the command in the inbox in the same transaction.
+// It returns ErrStalePayment when the payment is no longer in the status from.
+func (r *paymentRepositoryImpl) TransitPayment(ctx context.Context, payment *entity.Payment, from string, msg *inbox.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := inbox.Add(tx, msg); err != nil {
+			return err
+		}
+
+		result := tx.Model(&model.Payment{}).
+			Where("id = ? AND status = ?", payment.ID, from).
+			Updates(map[string]interface{}{
+				"status":           payment.Status,
+				"reason":           payment.Reason,
+				"authorization_id": payment.AuthorizationID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrStalePayment
+		}
+
+		return tx.Create(encodeTransition(payment)).Error
+	})
+}
 
-	return nil
+func (r *paymentRepositoryImpl) RecordCommand(ctx context.Context, msg *inbox.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return inbox.Add(tx, msg)
+	})
 }
 
-func (r *paymentRepositoryImpl) DeletePayment(ctx context.Context, paymentID uint64) error {
-	if err := r.db.Exec("DELETE FROM payments WHERE id = ?", paymentID).WithContext(ctx).Error; err != nil {
-		return err
+func encodeTransition(payment *entity.Payment) *model.PaymentTransition {
+	return &model.PaymentTransition{
+		PaymentID: payment.ID,
+		Status:    payment.Status,
+		Reason:    payment.Reason,
 	}
-	return nil
 }
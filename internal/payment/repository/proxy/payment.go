@@ -6,6 +6,7 @@ import (
 	"github.com/scul0405/saga-orchestration/internal/payment/domain/entity"
 	"github.com/scul0405/saga-orchestration/internal/payment/repository/pgrepo"
 	"github.com/scul0405/saga-orchestration/internal/pkg/cache"
+	"github.com/scul0405/saga-orchestration/internal/pkg/inbox"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
 	"github.com/scul0405/saga-orchestration/pkg/strjoin"
 	"gorm.io/gorm"
@@ -88,8 +89,16 @@ func (r *paymentRepositoryImpl) GetPayment(ctx context.Context, id uint64) (*ent
 	return payment, nil
 }
 
-func (r *paymentRepositoryImpl) CreatePayment(ctx context.Context, payment *entity.Payment) error {
-	err := r.pgRepo.CreatePayment(ctx, payment)
+func (r *paymentRepositoryImpl) LoadPayment(ctx context.Context, id uint64) (*entity.Payment, error) {
+	return r.pgRepo.GetPayment(ctx, id)
+}
+
+func (r *paymentRepositoryImpl) ListPaymentTransitions(ctx context.Context, id uint64) (*[]entity.PaymentTransition, error) {
+	return r.pgRepo.ListPaymentTransitions(ctx, id)
+}
+
+func (r *paymentRepositoryImpl) CreatePayment(ctx context.Context, payment *entity.Payment, msg *inbox.Message) error {
+	err := r.pgRepo.CreatePayment(ctx, payment, msg)
 	if err != nil {
 		return err
 	}
@@ -98,14 +107,17 @@ func (r *paymentRepositoryImpl) CreatePayment(ctx context.Context, payment *enti
 	return nil
 }
 
-func (r *paymentRepositoryImpl) DeletePayment(ctx context.Context, id uint64) error {
-	if err := r.pgRepo.DeletePayment(ctx, id); err != nil {
+func (r *paymentRepositoryImpl) TransitPayment(ctx context.Context, payment *entity.Payment, from string, msg *inbox.Message) error {
+	if err := r.pgRepo.TransitPayment(ctx, payment, from, msg); err != nil {
 		return err
 	}
 
-	key := strjoin.Join(getPaymentKey, strconv.FormatUint(id, 10))
+	key := strjoin.Join(getPaymentKey, strconv.FormatUint(payment.ID, 10))
 	r.logger.Error(r.lc.Delete(key))
 	r.logger.Error(r.rc.Delete(ctx, key))
-	r.logger.Error(r.rc.CFDel(ctx, cuckooFilter, id))
 	return nil
 }
+
+func (r *paymentRepositoryImpl) RecordCommand(ctx context.Context, msg *inbox.Message) error {
+	return r.pgRepo.RecordCommand(ctx, msg)
+}
@@ -8,11 +8,12 @@ import (
 	"github.com/scul0405/saga-orchestration/pkg/logger"
 )
 
-func NewPaymentService(logger logger.Logger, paymentRepo domain.PaymentRepository) app.Application {
+func NewPaymentService(logger logger.Logger, paymentRepo domain.PaymentRepository, gateway domain.PaymentGateway) app.Application {
 	return app.Application{
 		Commands: app.Commands{
-			CreatePayment:   command.NewCreatePaymentHandler(logger, paymentRepo),
-			RollbackPayment: command.NewRollbackPaymentHandler(logger, paymentRepo),
+			CreatePayment:      command.NewCreatePaymentHandler(logger, paymentRepo, gateway),
+			RollbackPayment:    command.NewRollbackPaymentHandler(logger, paymentRepo, gateway),
+			HandleGatewayEvent: command.NewHandleGatewayEventHandler(logger, paymentRepo),
 		},
 		Queries: app.Queries{
 			GetPayment: query.NewGetPaymentHandler(logger, paymentRepo),
@@ -11,10 +11,16 @@ import (
 
 type RedisCache interface {
 	Get(ctx context.Context, key string, value interface{}) (bool, error)
+	// GetMany gets the keys in one pipeline into values, it returns which keys were found
+	GetMany(ctx context.Context, keys []string, values []interface{}) ([]bool, error)
 	Set(ctx context.Context, key string, value interface{}) error
+	// SetMany sets the keys to values in one pipeline
+	SetMany(ctx context.Context, keys []string, values []interface{}) error
 	Delete(ctx context.Context, key string) error
 	CFReserve(ctx context.Context, key string, capacity, bucketSize, maxIterations int64) error
 	CFExist(ctx context.Context, key string, value interface{}) (bool, error)
+	// CFMExist tells for each value whether it may be in the cuckoo filter
+	CFMExist(ctx context.Context, key string, values ...interface{}) ([]bool, error)
 	CFAdd(ctx context.Context, key string, value interface{}) error
 	CFDel(ctx context.Context, key string, value interface{}) error
 	GetMutex(mutexName string) *redsync.Mutex
@@ -67,6 +73,50 @@ func (c *redisCache) Set(ctx context.Context, key string, value interface{}) err
 	return c.client.Set(ctx, key, val, c.expirationTime).Err()
 }
 
+// GetMany pipelines the GET commands, a cluster client sends them to the node of each key
+func (c *redisCache) GetMany(ctx context.Context, keys []string, values []interface{}) ([]bool, error) {
+	pipe := c.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Get(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	found := make([]bool, len(keys))
+	for i, cmd := range cmds {
+		val, err := cmd.Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err = json.Unmarshal([]byte(val), values[i]); err != nil {
+			return nil, err
+		}
+		found[i] = true
+	}
+
+	return found, nil
+}
+
+func (c *redisCache) SetMany(ctx context.Context, keys []string, values []interface{}) error {
+	pipe := c.client.Pipeline()
+	for i, key := range keys {
+		val, err := json.Marshal(values[i])
+		if err != nil {
+			return err
+		}
+		pipe.Set(ctx, key, val, c.expirationTime)
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
 func (c *redisCache) Delete(ctx context.Context, key string) error {
 	return c.client.Del(ctx, key).Err()
 }
@@ -87,6 +137,10 @@ func (c *redisCache) CFExist(ctx context.Context, key string, value interface{})
 	return val, nil
 }
 
+func (c *redisCache) CFMExist(ctx context.Context, key string, values ...interface{}) ([]bool, error) {
+	return c.client.CFMExists(ctx, key, values...).Result()
+}
+
 func (c *redisCache) CFAdd(ctx context.Context, key string, value interface{}) error {
 	return c.client.CFAdd(ctx, key, value).Err()
 }
@@ -0,0 +1,28 @@
+package grpcconn
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sony/gobreaker"
+)
+
+// breakerState is the state of the circuit breaker of each client endpoint:
+// 0 closed, 1 half-open, 2 open
+var breakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "grpc_client_circuit_breaker_state",
+	Help: "State of the circuit breaker of a gRPC client endpoint: 0 closed, 1 half-open, 2 open.",
+}, []string{"service", "method"})
+
+var breakerTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "grpc_client_circuit_breaker_transitions_total",
+	Help: "State changes of the circuit breaker of a gRPC client endpoint, by the state it moved to.",
+}, []string{"service", "method", "state"})
+
+func observeBreakerState(svcName, methodName string, state gobreaker.State) {
+	breakerState.WithLabelValues(svcName, methodName).Set(float64(state))
+}
+
+func observeBreakerTransition(svcName, methodName string, state gobreaker.State) {
+	observeBreakerState(svcName, methodName, state)
+	breakerTransitions.WithLabelValues(svcName, methodName, state.String()).Inc()
+}
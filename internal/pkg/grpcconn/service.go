@@ -27,9 +27,13 @@ func NewGRPCClient[GRPCReply any](svcName string, methodName string, conn *GRPCC
 			append(opts, grpc.ClientBefore(grpc.SetRequestHeader("Service-Name", svcName)))...,
 		).Endpoint()
 
+		observeBreakerState(svcName, methodName, gobreaker.StateClosed)
 		grpcEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
 			Name:    svcName,
 			Timeout: timeout,
+			OnStateChange: func(_ string, _ gobreaker.State, to gobreaker.State) {
+				observeBreakerTransition(svcName, methodName, to)
+			},
 		}))(grpcEndpoint)
 	}
 
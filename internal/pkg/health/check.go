@@ -0,0 +1,69 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/scul0405/saga-orchestration/internal/pkg/grpcconn"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/grpc/connectivity"
+	"gorm.io/gorm"
+)
+
+// Postgres pings the database of the service
+func Postgres(db *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// Redis pings every shard of a cluster, or the server of a single node client
+func Redis(client redis.UniversalClient) Check {
+	return func(ctx context.Context) error {
+		if cluster, ok := client.(*redis.ClusterClient); ok {
+			return cluster.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+				return shard.Ping(ctx).Err()
+			})
+		}
+		return client.Ping(ctx).Err()
+	}
+}
+
+// Kafka succeeds once one of the brokers accepts a connection
+func Kafka(brokers []string) Check {
+	return func(ctx context.Context) error {
+		var errs []error
+		for _, broker := range brokers {
+			conn, err := kafka.DialContext(ctx, "tcp", broker)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			return conn.Close()
+		}
+		if len(errs) == 0 {
+			return errors.New("no kafka broker configured")
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// GRPCConn fails while the connection to the upstream is broken. An idle connection is asked to reconnect,
+// it only connects again on the next call otherwise.
+func GRPCConn(conn *grpcconn.GRPCClientConn) Check {
+	return func(ctx context.Context) error {
+		switch state := conn.Conn.GetState(); state {
+		case connectivity.Idle:
+			conn.Conn.Connect()
+			return nil
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return fmt.Errorf("grpc connection to %s is %s", conn.Conn.Target(), state)
+		}
+		return nil
+	}
+}
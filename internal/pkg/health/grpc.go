@@ -0,0 +1,36 @@
+package health
+
+import (
+	"context"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"time"
+)
+
+const watchInterval = 10 * time.Second
+
+// Watch runs the checks every watchInterval and sets the serving status of the grpc.health.v1 server,
+// for the whole server and for each of the given services. The server stops serving once ctx is done.
+func (c *Checker) Watch(ctx context.Context, srv *health.Server, services ...string) {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		status := healthpb.HealthCheckResponse_SERVING
+		if report := c.Run(ctx); !report.Ready() {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+
+		srv.SetServingStatus("", status)
+		for _, service := range services {
+			srv.SetServingStatus(service, status)
+		}
+
+		select {
+		case <-ctx.Done():
+			srv.Shutdown()
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	checkTimeout = 3 * time.Second
+)
+
+// Check returns an error when a dependency of the service is not available
+type Check func(ctx context.Context) error
+
+// Checker runs the checks of the dependencies a service needs to serve requests
+type Checker struct {
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+func NewChecker() *Checker {
+	return &Checker{checks: make(map[string]Check)}
+}
+
+// Add registers the check of a dependency under its name
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks[name] = check
+}
+
+// Report is the result of every check, the service is ready when all of them are up
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func (r *Report) Ready() bool {
+	return r.Status == StatusUp
+}
+
+// Run runs the checks concurrently, each one is given checkTimeout to complete
+func (c *Checker) Run(ctx context.Context) *Report {
+	c.mu.RLock()
+	checks := make(map[string]Check, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.RUnlock()
+
+	report := &Report{Status: StatusUp, Checks: make(map[string]string, len(checks))}
+
+	var mu sync.Mutex
+	wg := &sync.WaitGroup{}
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			status := StatusUp
+			if err := check(checkCtx); err != nil {
+				status = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = status
+			if status != StatusUp {
+				report.Status = StatusDown
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	return report
+}
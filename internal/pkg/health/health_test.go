@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChecker(t *testing.T) {
+	t.Parallel()
+
+	checker := NewChecker()
+	checker.Add("postgres", func(ctx context.Context) error { return nil })
+
+	report := checker.Run(context.Background())
+	require.True(t, report.Ready())
+	require.Equal(t, map[string]string{"postgres": StatusUp}, report.Checks)
+
+	checker.Add("kafka", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	report = checker.Run(context.Background())
+	require.False(t, report.Ready())
+	require.Equal(t, map[string]string{"postgres": StatusUp, "kafka": "connection refused"}, report.Checks)
+
+	rec := httptest.NewRecorder()
+	checker.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	rec = httptest.NewRecorder()
+	LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestCheckTimeout(t *testing.T) {
+	t.Parallel()
+
+	checker := NewChecker()
+	checker.Add("redis", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report := checker.Run(ctx)
+	require.False(t, report.Ready())
+	require.Equal(t, context.Canceled.Error(), report.Checks["redis"])
+}
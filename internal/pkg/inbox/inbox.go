@@ -0,0 +1,97 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"github.com/scul0405/saga-orchestration/internal/pkg/outbox"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrProcessed = errors.New("inbox: command already processed")
+)
+
+// Add records the command within the transaction of the change it makes and writes its reply to the outbox.
+// It returns ErrProcessed when the command has already been recorded, the transaction must then be rolled back.
+func Add(tx *gorm.DB, msg *Message) error {
+	return add(tx, msg, false)
+}
+
+func add(tx *gorm.DB, msg *Message, rejected bool) error {
+	if msg == nil {
+		return nil
+	}
+
+	entry, err := encodeMessage(msg)
+	if err != nil {
+		return err
+	}
+	entry.Rejected = rejected
+
+	// A concurrent delivery of the same command waits here until the first one is committed
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(entry)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrProcessed
+	}
+
+	return outbox.Add(tx, msg.Reply)
+}
+
+// Inbox deduplicates the commands consumed from Kafka
+type Inbox interface {
+	// Replay writes the stored reply of an already processed command to the outbox again.
+	// It returns false when the command has not been processed yet, or when it was rejected and is retried:
+	// the rejected command is then forgotten to be processed again.
+	Replay(ctx context.Context, purchaseID uint64, command string, retried bool) (bool, error)
+	// Reject records a command which failed without changing the service data, with its failure reply
+	Reject(ctx context.Context, msg *Message) error
+}
+
+type inboxImpl struct {
+	db *gorm.DB
+}
+
+func NewInbox(db *gorm.DB) Inbox {
+	return &inboxImpl{db: db}
+}
+
+func (i *inboxImpl) Replay(ctx context.Context, purchaseID uint64, command string, retried bool) (bool, error) {
+	if retried {
+		if err := i.db.WithContext(ctx).
+			Where("purchase_id = ? AND command = ? AND rejected", purchaseID, command).
+			Delete(&InboxMessage{}).Error; err != nil {
+			return false, err
+		}
+	}
+
+	var entry InboxMessage
+	if err := i.db.WithContext(ctx).
+		Where("purchase_id = ? AND command = ?", purchaseID, command).
+		First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	msg, err := decodeMessage(&entry)
+	if err != nil {
+		return false, err
+	}
+
+	if err = outbox.Add(i.db.WithContext(ctx), msg.Reply); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (i *inboxImpl) Reject(ctx context.Context, msg *Message) error {
+	return i.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return add(tx, msg, true)
+	})
+}
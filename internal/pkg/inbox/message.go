@@ -0,0 +1,49 @@
+package inbox
+
+import (
+	"encoding/json"
+	"github.com/scul0405/saga-orchestration/internal/pkg/outbox"
+)
+
+// Message is a command consumed by the service together with its reply
+type Message struct {
+	PurchaseID uint64
+	Command    string
+	Reply      *outbox.Message
+}
+
+// InboxMessage is the inbox table, a row means the command of the purchase has been processed
+type InboxMessage struct {
+	PurchaseID uint64 `gorm:"primaryKey;autoIncrement:false"`
+	Command    string `gorm:"primaryKey"`
+	Reply      []byte `gorm:"type:jsonb;not null"`
+	// Rejected is a command which failed without changing the service data, it can be processed again
+	Rejected  bool  `gorm:"not null;default:false"`
+	CreatedAt int64 `gorm:"autoCreateTime:milli"`
+}
+
+func encodeMessage(msg *Message) (*InboxMessage, error) {
+	reply, err := json.Marshal(msg.Reply)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InboxMessage{
+		PurchaseID: msg.PurchaseID,
+		Command:    msg.Command,
+		Reply:      reply,
+	}, nil
+}
+
+func decodeMessage(msg *InboxMessage) (*Message, error) {
+	var reply *outbox.Message
+	if err := json.Unmarshal(msg.Reply, &reply); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		PurchaseID: msg.PurchaseID,
+		Command:    msg.Command,
+		Reply:      reply,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package outbox
+
+import (
+	"encoding/json"
+	"github.com/segmentio/kafka-go"
+)
+
+// Message is a Kafka message waiting in the outbox to be published
+type Message struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers []kafka.Header
+}
+
+// OutboxMessage is the outbox table, a message is pending until SentAt is set
+type OutboxMessage struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement"`
+	Topic     string `gorm:"not null"`
+	Key       []byte
+	Value     []byte `gorm:"not null"`
+	Headers   []byte `gorm:"type:jsonb;not null"`
+	SentAt    int64  `gorm:"not null;default:0;index"`
+	CreatedAt int64  `gorm:"autoCreateTime:milli"`
+}
+
+type header struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+func encodeMessage(msg *Message) (*OutboxMessage, error) {
+	headers := make([]header, len(msg.Headers))
+	for i, h := range msg.Headers {
+		headers[i] = header{Key: h.Key, Value: h.Value}
+	}
+
+	encodedHeaders, err := json.Marshal(headers)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxMessage{
+		Topic:   msg.Topic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: encodedHeaders,
+	}, nil
+}
+
+func decodeMessage(msg *OutboxMessage) (kafka.Message, error) {
+	var headers []header
+	if err := json.Unmarshal(msg.Headers, &headers); err != nil {
+		return kafka.Message{}, err
+	}
+
+	kafkaHeaders := make([]kafka.Header, len(headers))
+	for i, h := range headers {
+		kafkaHeaders[i] = kafka.Header{Key: h.Key, Value: h.Value}
+	}
+
+	return kafka.Message{
+		Topic:   msg.Topic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: kafkaHeaders,
+	}, nil
+}
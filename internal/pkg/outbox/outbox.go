@@ -0,0 +1,53 @@
+package outbox
+
+import (
+	"context"
+	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
+	"github.com/segmentio/kafka-go"
+	"gorm.io/gorm"
+)
+
+// Add writes the messages to the outbox within the transaction of the change they announce.
+// The messages carry the trace context of the transaction, so the relay publishes them in the same trace.
+func Add(tx *gorm.DB, msgs ...*Message) error {
+	entries := make([]*OutboxMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+
+		traced := *msg
+		traced.Headers = append([]kafka.Header(nil), msg.Headers...)
+		kafkaClient.InjectTraceContext(tx.Statement.Context, &traced.Headers)
+
+		entry, err := encodeMessage(&traced)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	return tx.Create(&entries).Error
+}
+
+// Repository writes messages which do not come with a change of the service data,
+// e.g. the reply of a failed saga step
+type Repository interface {
+	Enqueue(ctx context.Context, msgs ...*Message) error
+}
+
+type repositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repositoryImpl{db: db}
+}
+
+func (r *repositoryImpl) Enqueue(ctx context.Context, msgs ...*Message) error {
+	return Add(r.db.WithContext(ctx), msgs...)
+}
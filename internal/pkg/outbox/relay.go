@@ -0,0 +1,137 @@
+package outbox
+
+import (
+	"context"
+	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"github.com/segmentio/kafka-go"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+const (
+	// DefaultPollInterval is the default delay between two reads of the outbox
+	DefaultPollInterval = 200 * time.Millisecond
+	// DefaultBatchSize is the default number of messages published at once
+	DefaultBatchSize = 100
+	// retention is how long sent messages are kept in the outbox
+	retention     = 24 * time.Hour
+	purgeInterval = time.Minute
+	// keyLockSpace is the first key of the advisory locks taken on the message keys, apart from the other advisory locks
+	keyLockSpace = 0x6f7574
+)
+
+// Relay publishes the pending outbox messages to Kafka
+type Relay interface {
+	Run(ctx context.Context)
+}
+
+type relay struct {
+	db           *gorm.DB
+	producer     kafkaClient.Producer
+	logger       logger.Logger
+	pollInterval time.Duration
+	batchSize    int
+}
+
+func NewRelay(db *gorm.DB, producer kafkaClient.Producer, logger logger.Logger, pollInterval time.Duration, batchSize int) Relay {
+	if pollInterval == 0 {
+		pollInterval = DefaultPollInterval
+	}
+	if batchSize == 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	return &relay{
+		db:           db,
+		producer:     producer,
+		logger:       logger,
+		pollInterval: pollInterval,
+		batchSize:    batchSize,
+	}
+}
+
+// Run publishes the outbox until the context is done.
+// Messages are delivered at least once: a crash between publishing and marking them sent publishes them again.
+func (r *relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	var lastPurge time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Drain the outbox before waiting for the next tick
+		for {
+			sent, err := r.publishBatch(ctx)
+			if err != nil {
+				r.logger.Errorf("Outbox.Relay: PublishBatch", err)
+				break
+			}
+			if sent < r.batchSize {
+				break
+			}
+		}
+
+		if time.Since(lastPurge) >= purgeInterval {
+			if err := r.purge(ctx); err != nil {
+				r.logger.Errorf("Outbox.Relay: Purge", err)
+			}
+			lastPurge = time.Now()
+		}
+	}
+}
+
+// publishBatch publishes the oldest pending messages and marks them sent.
+// The rows stay locked until they are marked, so several relays can share the outbox. Each relay also holds
+// an advisory lock on the keys it reads until then: the messages of a key are all published by the relay which
+// holds its lock, in order, while the other relays skip them.
+func (r *relay) publishBatch(ctx context.Context) (int, error) {
+	var sent int
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var entries []OutboxMessage
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("sent_at = 0").
+			Where("pg_try_advisory_xact_lock(?, hashtext(encode(coalesce(key, ''::bytea), 'hex')))", keyLockSpace).
+			Order("id").Limit(r.batchSize).
+			Find(&entries).Error; err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+
+		msgs := make([]kafka.Message, len(entries))
+		ids := make([]uint64, len(entries))
+		for i := range entries {
+			msg, err := decodeMessage(&entries[i])
+			if err != nil {
+				return err
+			}
+			msgs[i] = msg
+			ids[i] = entries[i].ID
+		}
+
+		if err := r.producer.PublishMessage(ctx, msgs...); err != nil {
+			return err
+		}
+
+		sent = len(entries)
+		return tx.Model(&OutboxMessage{}).Where("id IN ?", ids).Update("sent_at", time.Now().UnixMilli()).Error
+	})
+
+	return sent, err
+}
+
+func (r *relay) purge(ctx context.Context) error {
+	return r.db.WithContext(ctx).
+		Where("sent_at > 0 AND sent_at < ?", time.Now().Add(-retention).UnixMilli()).
+		Delete(&OutboxMessage{}).Error
+}
@@ -16,22 +16,28 @@ type CategoryApplication struct {
 }
 
 type ProductCommands struct {
-	CreateProduct            command.CreateProductHandler
-	UpdateProductDetail      command.UpdateProductDetailHandler
-	UpdateProductInventory   command.UpdateProductInventoryHandler
-	RollbackProductInventory command.RollbackProductInventoryHandler
+	CreateProduct              command.CreateProductHandler
+	UpdateProductDetail        command.UpdateProductDetailHandler
+	ReserveProductInventory    command.ReserveProductInventoryHandler
+	ConfirmProductInventory    command.ConfirmProductInventoryHandler
+	ReleaseProductInventory    command.ReleaseProductInventoryHandler
+	ReleaseExpiredReservations command.ReleaseExpiredReservationsHandler
 }
 
 type ProductQueries struct {
-	CheckProducts query.CheckProductsHandler
-	GetProducts   query.GetProductsHandler
-	ListProducts  query.ListProductsHandler
+	CheckProducts  query.CheckProductsHandler
+	GetProducts    query.GetProductsHandler
+	ListProducts   query.ListProductsHandler
+	SearchProducts query.SearchProductsHandler
 }
 
 type CategoryCommands struct {
 	CreateCategory command.CreateCategoryHandler
+	UpdateCategory command.UpdateCategoryHandler
+	DeleteCategory command.DeleteCategoryHandler
 }
 
 type CategoryQueries struct {
-
+	GetCategory    query.GetCategoryHandler
+	ListCategories query.ListCategoriesHandler
 }
\ No newline at end of file
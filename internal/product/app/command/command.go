@@ -5,3 +5,8 @@ import "context"
 type CommandHandler[C any] interface {
 	Handle(ctx context.Context, cmd C) error
 }
+
+// CommandHandlerWithResult is a command handler which returns what the command created
+type CommandHandlerWithResult[C any, R any] interface {
+	Handle(ctx context.Context, cmd C) (R, error)
+}
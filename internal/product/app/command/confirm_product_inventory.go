@@ -0,0 +1,34 @@
+package command
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/internal/pkg/outbox"
+	"github.com/scul0405/saga-orchestration/internal/product/domain"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+)
+
+// ConfirmProductInventory takes the stock reserved by a purchase, once its payment succeeded
+type ConfirmProductInventory struct {
+	PurchaseID uint64
+	// Reply is published once the reservations are confirmed
+	Reply *outbox.Message
+}
+
+type ConfirmProductInventoryHandler CommandHandler[ConfirmProductInventory]
+
+type confirmProductInventoryHandler struct {
+	logger      logger.Logger
+	productRepo domain.ProductRepository
+}
+
+func NewConfirmProductInventoryHandler(logger logger.Logger, productRepo domain.ProductRepository) ConfirmProductInventoryHandler {
+	return &confirmProductInventoryHandler{
+		logger:      logger,
+		productRepo: productRepo,
+	}
+}
+
+// Handle returns entity.ErrReservationExpired when the reservations expired before the confirmation
+func (h *confirmProductInventoryHandler) Handle(ctx context.Context, cmd ConfirmProductInventory) error {
+	return h.productRepo.ConfirmProductInventory(ctx, cmd.PurchaseID, cmd.Reply)
+}
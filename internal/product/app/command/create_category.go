@@ -9,12 +9,15 @@ import (
 	"github.com/scul0405/saga-orchestration/pkg/sonyflake"
 )
 
+// CreateCategory creates a category under ParentID, or a root category when ParentID is 0
 type CreateCategory struct {
+	ParentID    uint64
 	Name        string
 	Description string
 }
 
-type CreateCategoryHandler CommandHandler[CreateCategory]
+// CreateCategoryHandler returns the ID of the created category
+type CreateCategoryHandler CommandHandlerWithResult[CreateCategory, uint64]
 
 type createCategoryHandler struct {
 	sf           sonyflake.IDGenerator
@@ -30,21 +33,22 @@ func NewCreateCategoryHandler(sf sonyflake.IDGenerator, logger logger.Logger, ca
 	}
 }
 
-func (h *createCategoryHandler) Handle(ctx context.Context, cmd CreateCategory) error {
-	productID, err := h.sf.NextID()
+func (h *createCategoryHandler) Handle(ctx context.Context, cmd CreateCategory) (uint64, error) {
+	categoryID, err := h.sf.NextID()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	err = h.categoryRepo.CreateCategory(ctx, &entity.Category{
-		ID:          productID,
+		ID:          categoryID,
+		ParentID:    cmd.ParentID,
 		Name:        cmd.Name,
 		Description: cmd.Description,
 	})
 
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return nil
+	return categoryID, nil
 }
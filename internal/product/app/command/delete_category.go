@@ -0,0 +1,30 @@
+package command
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/internal/product/domain"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+)
+
+// DeleteCategory deletes a category, which must have neither subcategories nor products
+type DeleteCategory struct {
+	CategoryID uint64
+}
+
+type DeleteCategoryHandler CommandHandler[DeleteCategory]
+
+type deleteCategoryHandler struct {
+	logger       logger.Logger
+	categoryRepo domain.CategoryRepository
+}
+
+func NewDeleteCategoryHandler(logger logger.Logger, categoryRepo domain.CategoryRepository) DeleteCategoryHandler {
+	return &deleteCategoryHandler{
+		logger:       logger,
+		categoryRepo: categoryRepo,
+	}
+}
+
+func (h *deleteCategoryHandler) Handle(ctx context.Context, cmd DeleteCategory) error {
+	return h.categoryRepo.DeleteCategory(ctx, cmd.CategoryID)
+}
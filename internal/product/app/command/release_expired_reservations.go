@@ -0,0 +1,49 @@
+package command
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/internal/product/domain"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"time"
+)
+
+// DefaultReapBatchSize is the default number of reservations expired at once
+const DefaultReapBatchSize = 100
+
+// ReleaseExpiredReservations releases a batch of the reservations which were not confirmed in time
+type ReleaseExpiredReservations struct {
+	BatchSize int
+}
+
+// ReleaseExpiredReservationsHandler returns the number of expired reservations
+type ReleaseExpiredReservationsHandler CommandHandlerWithResult[ReleaseExpiredReservations, int]
+
+type releaseExpiredReservationsHandler struct {
+	logger      logger.Logger
+	productRepo domain.ProductRepository
+}
+
+func NewReleaseExpiredReservationsHandler(logger logger.Logger, productRepo domain.ProductRepository) ReleaseExpiredReservationsHandler {
+	return &releaseExpiredReservationsHandler{
+		logger:      logger,
+		productRepo: productRepo,
+	}
+}
+
+func (h *releaseExpiredReservationsHandler) Handle(ctx context.Context, cmd ReleaseExpiredReservations) (int, error) {
+	if cmd.BatchSize == 0 {
+		cmd.BatchSize = DefaultReapBatchSize
+	}
+
+	expired, err := h.productRepo.ReleaseExpiredReservations(ctx, time.Now(), cmd.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, reservation := range *expired {
+		h.logger.Infof("Product: reservation of purchase %v expired, released %v of product %v",
+			reservation.ID, reservation.Quantity, reservation.ProductID)
+	}
+
+	return len(*expired), nil
+}
@@ -0,0 +1,34 @@
+package command
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/internal/pkg/outbox"
+	"github.com/scul0405/saga-orchestration/internal/product/domain"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+)
+
+// ReleaseProductInventory gives back the products of a rolled back purchase, reserved or already confirmed
+type ReleaseProductInventory struct {
+	PurchaseID uint64
+	// Reply is published once the products are released
+	Reply *outbox.Message
+}
+
+type ReleaseProductInventoryHandler CommandHandler[ReleaseProductInventory]
+
+type releaseProductInventoryHandler struct {
+	logger      logger.Logger
+	productRepo domain.ProductRepository
+}
+
+func NewReleaseProductInventoryHandler(logger logger.Logger, productRepo domain.ProductRepository) ReleaseProductInventoryHandler {
+	return &releaseProductInventoryHandler{
+		logger:      logger,
+		productRepo: productRepo,
+	}
+}
+
+func (h *releaseProductInventoryHandler) Handle(ctx context.Context, cmd ReleaseProductInventory) error {
+	_, err := h.productRepo.ReleaseProductInventory(ctx, cmd.PurchaseID, cmd.Reply)
+	return err
+}
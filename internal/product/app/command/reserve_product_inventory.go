@@ -0,0 +1,59 @@
+package command
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/internal/pkg/outbox"
+	"github.com/scul0405/saga-orchestration/internal/product/domain"
+	"github.com/scul0405/saga-orchestration/internal/product/domain/valueobject"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"time"
+)
+
+// DefaultReservationTTL is how long the products are reserved when no TTL is configured
+const DefaultReservationTTL = 15 * time.Minute
+
+type ReserveProductInventory struct {
+	PurchaseID        uint64
+	PurchasedProducts *[]PurchasedProduct
+	// Reply is published once the products are reserved
+	Reply *outbox.Message
+}
+
+type PurchasedProduct struct {
+	ID       uint64
+	Quantity uint64
+}
+
+type ReserveProductInventoryHandler CommandHandler[ReserveProductInventory]
+
+type reserveProductInventoryHandler struct {
+	logger      logger.Logger
+	productRepo domain.ProductRepository
+	ttl         time.Duration
+}
+
+func NewReserveProductInventoryHandler(logger logger.Logger, productRepo domain.ProductRepository, ttl time.Duration) ReserveProductInventoryHandler {
+	if ttl == 0 {
+		ttl = DefaultReservationTTL
+	}
+
+	return &reserveProductInventoryHandler{
+		logger:      logger,
+		productRepo: productRepo,
+		ttl:         ttl,
+	}
+}
+
+// Handle holds the purchased products for the TTL of the reservations, they are released by the reaper
+// unless the purchase confirms them before
+func (h *reserveProductInventoryHandler) Handle(ctx context.Context, cmd ReserveProductInventory) error {
+	purchaseProducts := make([]valueobject.PurchasedProduct, len(*cmd.PurchasedProducts))
+	for i, p := range *cmd.PurchasedProducts {
+		purchaseProducts[i] = valueobject.PurchasedProduct{
+			ID:       p.ID,
+			Quantity: p.Quantity,
+		}
+	}
+
+	return h.productRepo.ReserveProductInventory(ctx, cmd.PurchaseID, &purchaseProducts, time.Now().Add(h.ttl), cmd.Reply)
+}
@@ -0,0 +1,44 @@
+package command
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/internal/product/domain"
+	"github.com/scul0405/saga-orchestration/internal/product/domain/entity"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+)
+
+// UpdateCategory updates the detail of a category, and moves it with its subcategories
+// under ParentID, or to the root when ParentID is 0. A nil ParentID keeps the category under its parent.
+type UpdateCategory struct {
+	CategoryID  uint64
+	ParentID    *uint64
+	Name        string
+	Description string
+}
+
+type UpdateCategoryHandler CommandHandler[UpdateCategory]
+
+type updateCategoryHandler struct {
+	logger       logger.Logger
+	categoryRepo domain.CategoryRepository
+}
+
+func NewUpdateCategoryHandler(logger logger.Logger, categoryRepo domain.CategoryRepository) UpdateCategoryHandler {
+	return &updateCategoryHandler{
+		logger:       logger,
+		categoryRepo: categoryRepo,
+	}
+}
+
+func (h *updateCategoryHandler) Handle(ctx context.Context, cmd UpdateCategory) error {
+	category := &entity.Category{
+		ID:          cmd.CategoryID,
+		Name:        cmd.Name,
+		Description: cmd.Description,
+	}
+	if cmd.ParentID != nil {
+		category.ParentID = *cmd.ParentID
+	}
+
+	return h.categoryRepo.UpdateCategory(ctx, category, cmd.ParentID != nil)
+}
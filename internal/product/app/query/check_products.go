@@ -31,19 +31,13 @@ func NewCheckProductsHandler(logger logger.Logger, productRepo domain.ProductRep
 }
 
 func (h *checkProductsHandler) Handle(ctx context.Context, query CheckProducts) (*[]valueobject.ProductStatus, error) {
-	productStatuses := make([]valueobject.ProductStatus, len(*(query.Items)))
-
+	purchasedProducts := make([]valueobject.PurchasedProduct, len(*(query.Items)))
 	for i, item := range *(query.Items) {
-		status, err := h.productRepo.CheckProduct(ctx, item.ProductID, item.Quantity)
-		if err != nil {
-			return nil, err
-		}
-		productStatuses[i] = valueobject.ProductStatus{
-			ID:     status.ID,
-			Status: status.Status,
-			Price:  status.Price,
+		purchasedProducts[i] = valueobject.PurchasedProduct{
+			ID:       item.ProductID,
+			Quantity: item.Quantity,
 		}
 	}
 
-	return &productStatuses, nil
+	return h.productRepo.CheckProducts(ctx, &purchasedProducts)
 }
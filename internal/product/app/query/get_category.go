@@ -0,0 +1,30 @@
+package query
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/internal/product/domain"
+	"github.com/scul0405/saga-orchestration/internal/product/domain/entity"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+)
+
+type GetCategory struct {
+	CategoryID uint64
+}
+
+type GetCategoryHandler QueryHandler[GetCategory, *entity.Category]
+
+type getCategoryHandler struct {
+	logger       logger.Logger
+	categoryRepo domain.CategoryRepository
+}
+
+func NewGetCategoryHandler(logger logger.Logger, categoryRepo domain.CategoryRepository) GetCategoryHandler {
+	return &getCategoryHandler{
+		logger:       logger,
+		categoryRepo: categoryRepo,
+	}
+}
+
+func (h *getCategoryHandler) Handle(ctx context.Context, query GetCategory) (*entity.Category, error) {
+	return h.categoryRepo.GetCategory(ctx, query.CategoryID)
+}
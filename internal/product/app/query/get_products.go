@@ -26,15 +26,5 @@ func NewGetProductsHandler(logger logger.Logger, productRepo domain.ProductRepos
 }
 
 func (h *getProductsHandler) Handle(ctx context.Context, query GetProducts) (*[]entity.Product, error) {
-	products := make([]entity.Product, len(*(query.ProductIDs)))
-
-	for i, productID := range *(query.ProductIDs) {
-		product, err := h.productRepo.GetProduct(ctx, productID)
-		if err != nil {
-			return nil, err
-		}
-		products[i] = *product
-	}
-
-	return &products, nil
+	return h.productRepo.GetProducts(ctx, query.ProductIDs)
 }
@@ -0,0 +1,29 @@
+package query
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/internal/product/domain"
+	"github.com/scul0405/saga-orchestration/internal/product/domain/entity"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+)
+
+// ListCategories lists the whole category tree, each category before its subcategories
+type ListCategories struct{}
+
+type ListCategoriesHandler QueryHandler[ListCategories, *[]entity.Category]
+
+type listCategoriesHandler struct {
+	logger       logger.Logger
+	categoryRepo domain.CategoryRepository
+}
+
+func NewListCategoriesHandler(logger logger.Logger, categoryRepo domain.CategoryRepository) ListCategoriesHandler {
+	return &listCategoriesHandler{
+		logger:       logger,
+		categoryRepo: categoryRepo,
+	}
+}
+
+func (h *listCategoriesHandler) Handle(ctx context.Context, _ ListCategories) (*[]entity.Category, error) {
+	return h.categoryRepo.ListCategories(ctx)
+}
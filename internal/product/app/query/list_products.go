@@ -7,12 +7,25 @@ import (
 	"github.com/scul0405/saga-orchestration/pkg/logger"
 )
 
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// ListProducts selects a page of the catalog, Cursor is the NextCursor of the previous page
 type ListProducts struct {
-	Limit  uint64
-	Offset uint64
+	CategoryID    uint64
+	Subcategories bool
+	BrandName     string
+	MinPrice      uint64
+	MaxPrice      uint64
+	InStock       bool
+	Sort          string
+	Limit         uint64
+	Cursor        string
 }
 
-type ListProductsHandler QueryHandler[ListProducts, *[]valueobject.ProductCatalog]
+type ListProductsHandler QueryHandler[ListProducts, *valueobject.ProductPage]
 
 type listProductsHandler struct {
 	logger      logger.Logger
@@ -26,11 +39,58 @@ func NewListProductsHandler(logger logger.Logger, productRepo domain.ProductRepo
 	}
 }
 
-func (h *listProductsHandler) Handle(ctx context.Context, query ListProducts) (*[]valueobject.ProductCatalog, error) {
-	products, err := h.productRepo.ListProducts(ctx, query.Offset, query.Offset)
+// Handle returns a page of the catalog. It returns valueobject.ErrInvalidSort or valueobject.ErrInvalidCursor
+// when the query does not select a page.
+func (h *listProductsHandler) Handle(ctx context.Context, query ListProducts) (*valueobject.ProductPage, error) {
+	if query.Sort == "" {
+		query.Sort = valueobject.SortNewest
+	}
+	if !valueobject.ValidSort(query.Sort) {
+		return nil, valueobject.ErrInvalidSort
+	}
+
+	switch {
+	case query.Limit == 0:
+		query.Limit = DefaultListLimit
+	case query.Limit > MaxListLimit:
+		query.Limit = MaxListLimit
+	}
+
+	filter := &valueobject.ProductFilter{
+		CategoryID:    query.CategoryID,
+		Subcategories: query.Subcategories,
+		BrandName:     query.BrandName,
+		MinPrice:      query.MinPrice,
+		MaxPrice:      query.MaxPrice,
+		InStock:       query.InStock,
+		Sort:          query.Sort,
+		// One more product tells whether a next page exists
+		Limit: query.Limit + 1,
+	}
+	if query.Cursor != "" {
+		after, err := valueobject.DecodeProductCursor(query.Cursor, query.Sort)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = after
+	}
+
+	products, err := h.productRepo.ListProducts(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	return products, nil
+	page := &valueobject.ProductPage{Products: products}
+	if uint64(len(*products)) > query.Limit {
+		*products = (*products)[:query.Limit]
+		last := (*products)[query.Limit-1]
+		page.NextCursor = (&valueobject.ProductCursor{
+			Sort:      query.Sort,
+			Price:     last.Price,
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+		}).Encode()
+	}
+
+	return page, nil
 }
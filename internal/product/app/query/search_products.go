@@ -0,0 +1,57 @@
+package query
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/internal/product/domain"
+	"github.com/scul0405/saga-orchestration/internal/product/domain/valueobject"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+)
+
+// SearchProducts searches the products by the terms of Query, the last one being matched as a prefix
+type SearchProducts struct {
+	Query      string
+	CategoryID uint64
+	MinPrice   uint64
+	MaxPrice   uint64
+	Limit      uint64
+	Offset     uint64
+}
+
+type SearchProductsHandler QueryHandler[SearchProducts, *[]valueobject.ProductSearchResult]
+
+type searchProductsHandler struct {
+	logger      logger.Logger
+	productRepo domain.ProductRepository
+}
+
+func NewSearchProductsHandler(logger logger.Logger, productRepo domain.ProductRepository) SearchProductsHandler {
+	return &searchProductsHandler{
+		logger:      logger,
+		productRepo: productRepo,
+	}
+}
+
+// Handle returns the products matching the query, the most relevant first.
+// It returns valueobject.ErrInvalidSearch when the query has no word to search.
+func (h *searchProductsHandler) Handle(ctx context.Context, query SearchProducts) (*[]valueobject.ProductSearchResult, error) {
+	tsquery, err := valueobject.SearchQuery(query.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case query.Limit == 0:
+		query.Limit = DefaultListLimit
+	case query.Limit > MaxListLimit:
+		query.Limit = MaxListLimit
+	}
+
+	return h.productRepo.SearchProducts(ctx, &valueobject.ProductSearch{
+		Query:      tsquery,
+		CategoryID: query.CategoryID,
+		MinPrice:   query.MinPrice,
+		MaxPrice:   query.MaxPrice,
+		Limit:      query.Limit,
+		Offset:     query.Offset,
+	})
+}
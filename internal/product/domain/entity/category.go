@@ -1,8 +1,50 @@
 package entity
 
-// Category entity
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrParentNotFound   = errors.New("parent category not found")
+	ErrCategoryCycle    = errors.New("category can not be moved under itself or its subcategories")
+	ErrCategoryNotEmpty = errors.New("category has subcategories or products")
+)
+
+// Category entity, Path is its materialized path: the IDs from the root category down to it, as in /1/5/,
+// so that its subtree is the categories whose path starts with its own. ParentID is 0 for a root category.
 type Category struct {
 	ID          uint64
+	ParentID    uint64
 	Name        string
 	Description string
+	Path        string
+	// ProductCount is the number of products of the category and its subcategories
+	ProductCount uint64
+	UpdatedAt    time.Time
+	CreatedAt    time.Time
+}
+
+// Place puts the category under parent, or at the root when parent is nil
+func (c *Category) Place(parent *Category) error {
+	if parent == nil {
+		c.ParentID = 0
+		c.Path = "/" + strconv.FormatUint(c.ID, 10) + "/"
+		return nil
+	}
+
+	if c.Contains(parent) {
+		return ErrCategoryCycle
+	}
+
+	c.ParentID = parent.ID
+	c.Path = parent.Path + strconv.FormatUint(c.ID, 10) + "/"
+	return nil
+}
+
+// Contains tells whether other is the category or one of its subcategories
+func (c *Category) Contains(other *Category) bool {
+	return c.Path != "" && strings.HasPrefix(other.Path, c.Path)
 }
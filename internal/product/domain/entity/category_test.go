@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCategoryPlace(t *testing.T) {
+	t.Parallel()
+
+	root := &Category{ID: 1}
+	require.NoError(t, root.Place(nil))
+	require.Equal(t, "/1/", root.Path)
+
+	child := &Category{ID: 5}
+	require.NoError(t, child.Place(root))
+	require.Equal(t, uint64(1), child.ParentID)
+	require.Equal(t, "/1/5/", child.Path)
+
+	grandchild := &Category{ID: 12}
+	require.NoError(t, grandchild.Place(child))
+	require.Equal(t, "/1/5/12/", grandchild.Path)
+
+	require.True(t, root.Contains(grandchild))
+	require.False(t, grandchild.Contains(root))
+	require.False(t, (&Category{ID: 15, Path: "/15/"}).Contains(&Category{ID: 150, Path: "/150/"}))
+
+	require.ErrorIs(t, root.Place(grandchild), ErrCategoryCycle)
+	require.ErrorIs(t, root.Place(root), ErrCategoryCycle)
+	require.Equal(t, "/1/", root.Path)
+
+	require.NoError(t, child.Place(nil))
+	require.Equal(t, uint64(0), child.ParentID)
+	require.Equal(t, "/5/", child.Path)
+}
@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// ReservationReserved holds the stock of a product until the reservation is confirmed, released or expires
+	ReservationReserved = "RESERVED"
+	// ReservationConfirmed took the stock of the product
+	ReservationConfirmed = "CONFIRMED"
+	// ReservationReleased gave the stock back, before or after its confirmation
+	ReservationReleased = "RELEASED"
+	// ReservationExpired gave the stock back after it was not confirmed in time
+	ReservationExpired = "EXPIRED"
+)
+
+var (
+	ErrReservationExpired = errors.New("reservation expired or released")
+)
+
+// Reservation of a product for a purchase, ID is the id of the purchase
+type Reservation struct {
+	ID        uint64
+	ProductID uint64
+	Quantity  uint64
+	Status    string
+	ExpiresAt time.Time
+}
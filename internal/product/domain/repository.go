@@ -2,27 +2,39 @@ package domain
 
 import (
 	"context"
+	"github.com/scul0405/saga-orchestration/internal/pkg/outbox"
 	"github.com/scul0405/saga-orchestration/internal/product/domain/entity"
 	"github.com/scul0405/saga-orchestration/internal/product/domain/valueobject"
+	"time"
 )
 
 // ProductRepository is an interface for product repository
 type ProductRepository interface {
 	CheckProduct(ctx context.Context, productID uint64, quantity uint64) (*valueobject.ProductStatus, error)
+	// CheckProducts checks the products of a cart in a few round trips, in the order of purchasedProducts
+	CheckProducts(ctx context.Context, purchasedProducts *[]valueobject.PurchasedProduct) (*[]valueobject.ProductStatus, error)
 	GetProductDetail(ctx context.Context, productID uint64) (*valueobject.ProductDetail, error)
 	GetProductInventory(ctx context.Context, productID uint64) (uint64, error)
 	GetProduct(ctx context.Context, productID uint64) (*entity.Product, error)
-	ListProducts(ctx context.Context, limit, offset uint64) (*[]valueobject.ProductCatalog, error)
+	// GetProducts returns the products in a few round trips, in the order of productIDs
+	GetProducts(ctx context.Context, productIDs *[]uint64) (*[]entity.Product, error)
+	ListProducts(ctx context.Context, filter *valueobject.ProductFilter) (*[]valueobject.ProductCatalog, error)
+	SearchProducts(ctx context.Context, search *valueobject.ProductSearch) (*[]valueobject.ProductSearchResult, error)
 	CreateProduct(ctx context.Context, product *entity.Product) error
 	UpdateProductDetail(ctx context.Context, productID uint64, product *valueobject.ProductDetail) error
-	UpdateProductInventory(ctx context.Context, idempotencyKey uint64, purchasedProducts *[]valueobject.PurchasedProduct) error
-	RollbackProductInventory(ctx context.Context, idempotencyKey uint64, purchasedProducts *[]valueobject.PurchasedProduct) error
+	ReserveProductInventory(ctx context.Context, purchaseID uint64, purchasedProducts *[]valueobject.PurchasedProduct, expiresAt time.Time, reply *outbox.Message) error
+	ConfirmProductInventory(ctx context.Context, purchaseID uint64, reply *outbox.Message) error
+	ReleaseProductInventory(ctx context.Context, purchaseID uint64, reply *outbox.Message) (*[]entity.Reservation, error)
+	ReleaseExpiredReservations(ctx context.Context, now time.Time, limit int) (*[]entity.Reservation, error)
 }
 
 // CategoryRepository is an interface for category repository
 type CategoryRepository interface {
 	CheckCategory(ctx context.Context, categoryID uint64) (bool, error)
 	GetCategory(ctx context.Context, categoryID uint64) (*entity.Category, error)
+	ListCategories(ctx context.Context) (*[]entity.Category, error)
 	CreateCategory(ctx context.Context, category *entity.Category) error
-	UpdateCategory(ctx context.Context, categoryID uint64, category *entity.Category) error
+	// UpdateCategory moves the category under category.ParentID only when move is true
+	UpdateCategory(ctx context.Context, category *entity.Category, move bool) error
+	DeleteCategory(ctx context.Context, categoryID uint64) error
 }
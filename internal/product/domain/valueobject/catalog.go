@@ -0,0 +1,82 @@
+package valueobject
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	// SortNewest lists the latest products first, it is the default order
+	SortNewest = "newest"
+	SortOldest = "oldest"
+	// SortPriceAsc and SortPriceDesc order by price, then by creation like SortOldest and SortNewest
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort")
+)
+
+// ProductFilter selects a page of the catalog, the zero value of a filter does not filter
+type ProductFilter struct {
+	CategoryID uint64
+	// Subcategories extends CategoryID to the products of its subcategories
+	Subcategories bool
+	BrandName     string
+	MinPrice      uint64
+	MaxPrice      uint64
+	InStock       bool
+	Sort          string
+	Limit         uint64
+	// After is the position of the last product of the previous page, nil for the first page
+	After *ProductCursor
+}
+
+// ProductCursor is the position of a product in the catalog order: its creation time and ID,
+// preceded by its price when the catalog is sorted by price
+type ProductCursor struct {
+	Sort      string `json:"s"`
+	Price     uint64 `json:"p,omitempty"`
+	CreatedAt int64  `json:"c"`
+	ID        uint64 `json:"i"`
+}
+
+// ProductPage is a page of the catalog, NextCursor is empty on the last page
+type ProductPage struct {
+	Products   *[]ProductCatalog
+	NextCursor string
+}
+
+// ValidSort tells whether sort is a catalog order
+func ValidSort(sort string) bool {
+	switch sort {
+	case SortNewest, SortOldest, SortPriceAsc, SortPriceDesc:
+		return true
+	}
+	return false
+}
+
+// Encode returns the opaque cursor handed to the clients
+func (c *ProductCursor) Encode() string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// DecodeProductCursor parses a cursor returned by Encode for the given sort,
+// it returns ErrInvalidCursor when the cursor is malformed or was made for another sort
+func DecodeProductCursor(cursor, sort string) (*ProductCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c ProductCursor
+	if err = json.Unmarshal(payload, &c); err != nil || c.Sort != sort || c.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
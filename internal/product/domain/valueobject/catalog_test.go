@@ -0,0 +1,23 @@
+package valueobject
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestProductCursor(t *testing.T) {
+	t.Parallel()
+
+	cursor := &ProductCursor{Sort: SortPriceAsc, Price: 1500, CreatedAt: 1700000000000, ID: 42}
+	decoded, err := DecodeProductCursor(cursor.Encode(), SortPriceAsc)
+	require.NoError(t, err)
+	require.Equal(t, cursor, decoded)
+
+	_, err = DecodeProductCursor(cursor.Encode(), SortNewest)
+	require.ErrorIs(t, err, ErrInvalidCursor)
+
+	for _, malformed := range []string{"not base64!", "bm90IGpzb24", (&ProductCursor{Sort: SortNewest}).Encode()} {
+		_, err = DecodeProductCursor(malformed, SortNewest)
+		require.ErrorIs(t, err, ErrInvalidCursor)
+	}
+}
@@ -26,6 +26,8 @@ type ProductCatalog struct {
 	ID         uint64
 	CategoryID uint64
 	Name       string
+	BrandName  string
 	Price      uint64
 	Inventory  uint64
+	CreatedAt  int64
 }
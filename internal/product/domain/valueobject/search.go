@@ -0,0 +1,48 @@
+package valueobject
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidSearch = errors.New("invalid search")
+
+// ProductSearch selects a page of the products matching Query, the zero value of a filter does not filter
+type ProductSearch struct {
+	// Query is a tsquery, see SearchQuery
+	Query      string
+	CategoryID uint64
+	MinPrice   uint64
+	MaxPrice   uint64
+	Limit      uint64
+	Offset     uint64
+}
+
+// ProductSearchResult is a product matching a search, the highlights wrap the matched terms in <mark></mark>
+type ProductSearchResult struct {
+	ID                   uint64
+	CategoryID           uint64
+	Name                 string
+	BrandName            string
+	Price                uint64
+	Inventory            uint64
+	Rank                 float32
+	NameHighlight        string
+	DescriptionHighlight string
+}
+
+// SearchQuery turns the terms typed by a customer into a tsquery matching all of them,
+// the last term is matched as a prefix so that a partially typed word already matches.
+// It returns ErrInvalidSearch when terms has no word.
+func SearchQuery(terms string) (string, error) {
+	words := strings.FieldsFunc(strings.ToLower(terms), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return "", ErrInvalidSearch
+	}
+
+	words[len(words)-1] += ":*"
+	return strings.Join(words, " & "), nil
+}
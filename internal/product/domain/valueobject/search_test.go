@@ -0,0 +1,23 @@
+package valueobject
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSearchQuery(t *testing.T) {
+	t.Parallel()
+
+	query, err := SearchQuery("  Gaming LAPTOP, 16-in")
+	require.NoError(t, err)
+	require.Equal(t, "gaming & laptop & 16 & in:*", query)
+
+	query, err = SearchQuery("café")
+	require.NoError(t, err)
+	require.Equal(t, "café:*", query)
+
+	for _, terms := range []string{"", "   ", "&|!:*()'"} {
+		_, err = SearchQuery(terms)
+		require.ErrorIs(t, err, ErrInvalidSearch)
+	}
+}
@@ -2,24 +2,26 @@ package eventhandler
 
 import (
 	"context"
-	"encoding/json"
-	"github.com/avast/retry-go"
+	"errors"
 	"github.com/scul0405/saga-orchestration/cmd/product/config"
 	"github.com/scul0405/saga-orchestration/internal/common"
+	"github.com/scul0405/saga-orchestration/internal/pkg/outbox"
 	"github.com/scul0405/saga-orchestration/internal/product/app"
+	"github.com/scul0405/saga-orchestration/internal/product/app/command"
+	"github.com/scul0405/saga-orchestration/internal/product/domain/entity"
+	"github.com/scul0405/saga-orchestration/internal/product/repository/pgrepo"
 	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
-	"github.com/scul0405/saga-orchestration/pkg/timeconvert"
 	pb "github.com/scul0405/saga-orchestration/proto"
-	"github.com/segmentio/kafka-go"
+	"gorm.io/gorm"
 	"sync"
 	"time"
 )
 
 var (
-	retryAttempts uint = 10
-	retryDelay         = 1 * time.Second
-	poolSize           = 16
+	poolSize = 16
+	// businessErrors are the failures replied to the orchestrator at once, retrying the command can not fix them
+	businessErrors = []error{pgrepo.ErrInsufficientInventory, entity.ErrReservationExpired, gorm.ErrRecordNotFound}
 )
 
 type EventHandler interface {
@@ -30,7 +32,8 @@ type eventHandler struct {
 	cfg        *config.Config
 	logger     logger.Logger
 	consumer   kafkaClient.ConsumerGroup
-	producer   kafkaClient.Producer
+	outbox     outbox.Repository
+	dlq        kafkaClient.DeadLetterPublisher
 	productSvc app.ProductApplication
 }
 
@@ -38,13 +41,15 @@ func NewEventHandler(
 	cfg *config.Config,
 	logger logger.Logger,
 	consumer kafkaClient.ConsumerGroup,
-	producer kafkaClient.Producer,
+	outbox outbox.Repository,
+	dlq kafkaClient.DeadLetterPublisher,
 	productSvc app.ProductApplication) EventHandler {
 	return &eventHandler{
 		cfg:        cfg,
 		logger:     logger,
 		consumer:   consumer,
-		producer:   producer,
+		outbox:     outbox,
+		dlq:        dlq,
 		productSvc: productSvc,
 	}
 }
@@ -52,11 +57,15 @@ func NewEventHandler(
 func (h *eventHandler) Run(ctx context.Context) {
 	go h.consumer.ConsumeTopic(ctx, poolSize, common.UpdateProductInventoryGroupID, common.UpdateProductInventoryTopic, h.updateProductInventoryWorker)
 	go h.consumer.ConsumeTopic(ctx, poolSize, common.RollbackProductInventoryGroupID, common.RollbackProductInventoryTopic, h.rollbackProductInventoryWorker)
+	go h.consumer.ConsumeTopic(ctx, poolSize, common.ConfirmProductInventoryGroupID, common.ConfirmProductInventoryTopic, h.confirmProductInventoryWorker)
+	go h.reapWorker(ctx)
 }
 
-func (h *eventHandler) updateProductInventoryWorker(ctx context.Context, r *kafka.Reader, wg *sync.WaitGroup, workerID int) {
+func (h *eventHandler) updateProductInventoryWorker(ctx context.Context, r kafkaClient.MessageReader, wg *sync.WaitGroup, workerID int) {
 	defer wg.Done()
 
+	policy := h.cfg.Kafka.Retry.Policy(common.UpdateProductInventoryTopic)
+
 	for {
 		m, err := r.FetchMessage(ctx)
 		if err != nil {
@@ -64,57 +73,61 @@ func (h *eventHandler) updateProductInventoryWorker(ctx context.Context, r *kafk
 			return
 		}
 
+		msgCtx, span := kafkaClient.StartConsumerSpan(ctx, m, "Product.UpdateProductInventoryWorker")
+
 		var purchase pb.CreatePurchaseRequest
-		if err = json.Unmarshal(m.Value, &purchase); err != nil {
+		if err = kafkaClient.Decode(m, &purchase); err != nil {
 			h.logger.Errorf("Product.UpdateProductInventoryWorker: UnmarshalProto", err)
+			if err = h.dlq.Publish(msgCtx, m, err, 1, "Product.UpdateProductInventoryWorker"); err != nil {
+				h.logger.Errorf("Product.UpdateProductInventoryWorker: PublishDeadLetter", err)
+			}
+			if err = r.CommitMessages(msgCtx, m); err != nil {
+				h.logger.Errorf("Product.UpdateProductInventoryWorker: CommitMessages", err)
+			}
+			span.End()
 			continue
 		}
 		h.logger.Infof("UpdateProductInventoryWorker: %v, message at topic/partition/offset %v/%v/%v: %s = %s\n", workerID, m.Topic, m.Partition, m.Offset, string(m.Key), string(m.Value))
 
-		reply := pb.CreatePurchaseResponse{
-			PurchaseId: purchase.PurchaseId,
-		}
-		if err = retry.Do(func() error {
-			err = h.productSvc.Commands.UpdateProductInventory.Handle(ctx, decodePb2UpdateProductInventoryCmd(&purchase))
-			if err != nil {
-				reply.Success = false
-				reply.ErrorMessage = err.Error()
-			} else {
-				reply.Success = true
-			}
-
-			reply.Purchase = purchase.Purchase
-			reply.Timestamp = timeconvert.Time2pbTimestamp(time.Now())
-			payload, _ := json.Marshal(&reply)
-
-			return h.producer.PublishMessage(ctx, kafka.Message{
-				Topic: common.ReplyTopic,
-				Value: payload,
-				Headers: []kafka.Header{
-					{
-						Key:   common.HandlerHeader,
-						Value: []byte(common.UpdateProductInventoryHandler),
-					},
-				},
-			})
-		},
-			retry.Attempts(retryAttempts),
-			retry.Delay(retryDelay),
-			retry.Context(ctx),
-		); err != nil {
-			// TODO: publish error message to error topic
+		attempts, err := policy.Do(msgCtx, func() error {
+			cmd := decodePb2ReserveProductInventoryCmd(&purchase)
+			cmd.Reply = encodeReply(&purchase, common.UpdateProductInventoryHandler, nil)
+			err = h.productSvc.Commands.ReserveProductInventory.Handle(msgCtx, cmd)
+			if err == nil {
+				return nil
+			}
+			if errors.Is(err, pgrepo.ErrInvalidIdempotency) {
+				// Redelivered command, the products are already reserved and the success reply is written again
+				return h.outbox.Enqueue(msgCtx, cmd.Reply)
+			}
+			if kafkaClient.Classify(err, businessErrors...) != kafkaClient.BusinessFailure {
+				return err
+			}
+
+			// The products were not reserved, only the failure reply is written
+			return h.outbox.Enqueue(msgCtx, encodeReply(&purchase, common.UpdateProductInventoryHandler, err))
+		})
+		if err != nil {
+			h.logger.Errorf("Product.UpdateProductInventoryWorker: failed after %d attempts: %v", attempts, err)
+			if err = h.dlq.Publish(msgCtx, m, err, attempts, "Product.UpdateProductInventoryWorker"); err != nil {
+				h.logger.Errorf("Product.UpdateProductInventoryWorker: PublishDeadLetter", err)
+			}
 		}
 
-		err = r.CommitMessages(ctx, m)
+		err = r.CommitMessages(msgCtx, m)
 		if err != nil {
 			h.logger.Errorf("Product.UpdateProductInventoryWorker: CommitMessages", err)
 		}
+
+		span.End()
 	}
 }
 
-func (h *eventHandler) rollbackProductInventoryWorker(ctx context.Context, r *kafka.Reader, wg *sync.WaitGroup, workerID int) {
+func (h *eventHandler) rollbackProductInventoryWorker(ctx context.Context, r kafkaClient.MessageReader, wg *sync.WaitGroup, workerID int) {
 	defer wg.Done()
 
+	policy := h.cfg.Kafka.Retry.Policy(common.RollbackProductInventoryTopic)
+
 	for {
 		m, err := r.FetchMessage(ctx)
 		if err != nil {
@@ -122,50 +135,145 @@ func (h *eventHandler) rollbackProductInventoryWorker(ctx context.Context, r *ka
 			return
 		}
 
+		msgCtx, span := kafkaClient.StartConsumerSpan(ctx, m, "Product.RollbackProductInventoryWorker")
+
 		var purchase pb.CreatePurchaseRequest
-		if err = json.Unmarshal(m.Value, &purchase); err != nil {
+		if err = kafkaClient.Decode(m, &purchase); err != nil {
 			h.logger.Errorf("Product.RollbackProductInventoryWorker: UnmarshalProto", err)
+			if err = h.dlq.Publish(msgCtx, m, err, 1, "Product.RollbackProductInventoryWorker"); err != nil {
+				h.logger.Errorf("Product.RollbackProductInventoryWorker: PublishDeadLetter", err)
+			}
+			if err = r.CommitMessages(msgCtx, m); err != nil {
+				h.logger.Errorf("Product.RollbackProductInventoryWorker: CommitMessages", err)
+			}
+			span.End()
 			continue
 		}
 		h.logger.Infof("RollbackProductInventoryWorker: %v, message at topic/partition/offset %v/%v/%v: %s = %s\n", workerID, m.Topic, m.Partition, m.Offset, string(m.Key), string(m.Value))
 
-		reply := pb.CreatePurchaseResponse{
-			PurchaseId: purchase.PurchaseId,
-		}
-		if err = retry.Do(func() error {
-			err = h.productSvc.Commands.RollbackProductInventory.Handle(ctx, decodePb2RollbackProductInventoryCmd(&purchase))
-			if err != nil {
-				reply.Success = false
-				reply.ErrorMessage = err.Error()
-			} else {
-				reply.Success = true
-			}
-
-			reply.Purchase = purchase.Purchase
-			reply.Timestamp = timeconvert.Time2pbTimestamp(time.Now())
-			payload, _ := json.Marshal(&reply)
-
-			return h.producer.PublishMessage(ctx, kafka.Message{
-				Topic: common.ReplyTopic,
-				Value: payload,
-				Headers: []kafka.Header{
-					{
-						Key:   common.HandlerHeader,
-						Value: []byte(common.RollbackProductInventoryHandler),
-					},
-				},
+		attempts, err := policy.Do(msgCtx, func() error {
+			err = h.productSvc.Commands.ReleaseProductInventory.Handle(msgCtx, command.ReleaseProductInventory{
+				PurchaseID: purchase.PurchaseId,
+				Reply:      encodeReply(&purchase, common.RollbackProductInventoryHandler, nil),
 			})
-		},
-			retry.Attempts(retryAttempts),
-			retry.Delay(retryDelay),
-			retry.Context(ctx),
-		); err != nil {
-			// TODO: publish error message to error topic
+			if err == nil {
+				return nil
+			}
+			if kafkaClient.Classify(err, businessErrors...) != kafkaClient.BusinessFailure {
+				return err
+			}
+
+			// The products were not released, only the failure reply is written
+			return h.outbox.Enqueue(msgCtx, encodeReply(&purchase, common.RollbackProductInventoryHandler, err))
+		})
+		if err != nil {
+			h.logger.Errorf("Product.RollbackProductInventoryWorker: failed after %d attempts: %v", attempts, err)
+			if err = h.dlq.Publish(msgCtx, m, err, attempts, "Product.RollbackProductInventoryWorker"); err != nil {
+				h.logger.Errorf("Product.RollbackProductInventoryWorker: PublishDeadLetter", err)
+			}
 		}
 
-		err = r.CommitMessages(ctx, m)
+		err = r.CommitMessages(msgCtx, m)
 		if err != nil {
 			h.logger.Errorf("Product.RollbackProductInventoryWorker: CommitMessages", err)
 		}
+
+		span.End()
+	}
+}
+
+func (h *eventHandler) confirmProductInventoryWorker(ctx context.Context, r kafkaClient.MessageReader, wg *sync.WaitGroup, workerID int) {
+	defer wg.Done()
+
+	policy := h.cfg.Kafka.Retry.Policy(common.ConfirmProductInventoryTopic)
+
+	for {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			h.logger.Errorf("Product.ConfirmProductInventoryWorker: FetchMessage", err)
+			return
+		}
+
+		msgCtx, span := kafkaClient.StartConsumerSpan(ctx, m, "Product.ConfirmProductInventoryWorker")
+
+		var purchase pb.CreatePurchaseRequest
+		if err = kafkaClient.Decode(m, &purchase); err != nil {
+			h.logger.Errorf("Product.ConfirmProductInventoryWorker: UnmarshalProto", err)
+			if err = h.dlq.Publish(msgCtx, m, err, 1, "Product.ConfirmProductInventoryWorker"); err != nil {
+				h.logger.Errorf("Product.ConfirmProductInventoryWorker: PublishDeadLetter", err)
+			}
+			if err = r.CommitMessages(msgCtx, m); err != nil {
+				h.logger.Errorf("Product.ConfirmProductInventoryWorker: CommitMessages", err)
+			}
+			span.End()
+			continue
+		}
+		h.logger.Infof("ConfirmProductInventoryWorker: %v, message at topic/partition/offset %v/%v/%v: %s = %s\n", workerID, m.Topic, m.Partition, m.Offset, string(m.Key), string(m.Value))
+
+		attempts, err := policy.Do(msgCtx, func() error {
+			err = h.productSvc.Commands.ConfirmProductInventory.Handle(msgCtx, command.ConfirmProductInventory{
+				PurchaseID: purchase.PurchaseId,
+				Reply:      encodeReply(&purchase, common.ConfirmProductInventoryHandler, nil),
+			})
+			if err == nil {
+				return nil
+			}
+			if kafkaClient.Classify(err, businessErrors...) != kafkaClient.BusinessFailure {
+				return err
+			}
+
+			// The reservations expired, only the failure reply is written and the purchase is rolled back
+			return h.outbox.Enqueue(msgCtx, encodeReply(&purchase, common.ConfirmProductInventoryHandler, err))
+		})
+		if err != nil {
+			h.logger.Errorf("Product.ConfirmProductInventoryWorker: failed after %d attempts: %v", attempts, err)
+			if err = h.dlq.Publish(msgCtx, m, err, attempts, "Product.ConfirmProductInventoryWorker"); err != nil {
+				h.logger.Errorf("Product.ConfirmProductInventoryWorker: PublishDeadLetter", err)
+			}
+		}
+
+		err = r.CommitMessages(msgCtx, m)
+		if err != nil {
+			h.logger.Errorf("Product.ConfirmProductInventoryWorker: CommitMessages", err)
+		}
+
+		span.End()
+	}
+}
+
+// reapWorker periodically releases the reservations which were not confirmed before their expiry
+func (h *eventHandler) reapWorker(ctx context.Context) {
+	if h.cfg.Reservation.ReapInterval == 0 {
+		return
+	}
+
+	batchSize := h.cfg.Reservation.BatchSize
+	if batchSize == 0 {
+		batchSize = command.DefaultReapBatchSize
+	}
+
+	ticker := time.NewTicker(time.Duration(h.cfg.Reservation.ReapInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Drain the expired reservations before waiting for the next tick
+		for {
+			expired, err := h.productSvc.Commands.ReleaseExpiredReservations.Handle(ctx, command.ReleaseExpiredReservations{
+				BatchSize: batchSize,
+			})
+			if err != nil {
+				h.logger.Errorf("Product.ReapWorker: ReleaseExpiredReservations", err)
+				break
+			}
+			if expired < batchSize {
+				break
+			}
+		}
 	}
 }
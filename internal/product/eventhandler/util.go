@@ -1,11 +1,17 @@
 package eventhandler
 
 import (
+	"github.com/scul0405/saga-orchestration/internal/common"
+	"github.com/scul0405/saga-orchestration/internal/pkg/outbox"
 	"github.com/scul0405/saga-orchestration/internal/product/app/command"
+	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
+	"github.com/scul0405/saga-orchestration/pkg/timeconvert"
 	pb "github.com/scul0405/saga-orchestration/proto"
+	"github.com/segmentio/kafka-go"
+	"time"
 )
 
-func decodePb2UpdateProductInventoryCmd(purchase *pb.CreatePurchaseRequest) command.UpdateProductInventory {
+func decodePb2ReserveProductInventoryCmd(purchase *pb.CreatePurchaseRequest) command.ReserveProductInventory {
 	purchasedProduct := make([]command.PurchasedProduct, len(purchase.Purchase.Order.OrderItems))
 	for i, item := range purchase.Purchase.Order.OrderItems {
 		purchasedProduct[i] = command.PurchasedProduct{
@@ -14,23 +20,35 @@ func decodePb2UpdateProductInventoryCmd(purchase *pb.CreatePurchaseRequest) comm
 		}
 	}
 
-	return command.UpdateProductInventory{
-		IdempotencyKey:    purchase.PurchaseId,
+	return command.ReserveProductInventory{
+		PurchaseID:        purchase.PurchaseId,
 		PurchasedProducts: &purchasedProduct,
 	}
 }
 
-func decodePb2RollbackProductInventoryCmd(purchase *pb.CreatePurchaseRequest) command.RollbackProductInventory {
-	purchasedProduct := make([]command.PurchasedProduct, len(purchase.Purchase.Order.OrderItems))
-	for i, item := range purchase.Purchase.Order.OrderItems {
-		purchasedProduct[i] = command.PurchasedProduct{
-			ID:       item.ProductId,
-			Quantity: item.Quantity,
-		}
+// encodeReply builds the reply of a saga step for the given handler, the step failed when err is not nil
+func encodeReply(purchase *pb.CreatePurchaseRequest, handler string, err error) *outbox.Message {
+	reply := pb.CreatePurchaseResponse{
+		PurchaseId: purchase.PurchaseId,
+		Purchase:   purchase.Purchase,
+		Success:    err == nil,
+		Timestamp:  timeconvert.Time2pbTimestamp(time.Now()),
 	}
+	if err != nil {
+		reply.ErrorMessage = err.Error()
+	}
+	payload, contentType, _ := kafkaClient.Encode(kafkaClient.ContentTypeProtobuf, &reply)
 
-	return command.RollbackProductInventory{
-		IdempotencyKey:    purchase.PurchaseId,
-		PurchasedProducts: &purchasedProduct,
+	return &outbox.Message{
+		Topic: common.ReplyTopic,
+		Key:   common.PurchaseKey(purchase.PurchaseId),
+		Value: payload,
+		Headers: []kafka.Header{
+			{
+				Key:   common.HandlerHeader,
+				Value: []byte(handler),
+			},
+			contentType,
+		},
 	}
 }
@@ -2,10 +2,15 @@ package postgres
 
 import (
 	"github.com/scul0405/saga-orchestration/cmd/product/config"
+	"github.com/scul0405/saga-orchestration/internal/pkg/outbox"
+	"github.com/scul0405/saga-orchestration/internal/product/domain/entity"
 	"github.com/scul0405/saga-orchestration/internal/product/infrastructure/db/postgres/model"
 	"gorm.io/gorm"
 )
 
+// legacyIdempotencyTable recorded the inventory updates of the purchases before the reservations
+const legacyIdempotencyTable = "idempotencies"
+
 type Migrator struct {
 	db *gorm.DB
 }
@@ -28,10 +33,68 @@ func (m *Migrator) Migrate(migration config.Migration) error {
 			return err
 		}
 
-		if err := m.db.Migrator().DropTable(&model.Idempotency{}); err != nil {
+		if err := m.db.Migrator().DropTable(&model.Reservation{}, legacyIdempotencyTable); err != nil {
+			return err
+		}
+
+		if err := m.db.Migrator().DropTable(&outbox.OutboxMessage{}); err != nil {
+			return err
+		}
+	}
+
+	if err := m.db.AutoMigrate(&model.Category{}, &model.Product{}, &model.Reservation{}, &outbox.OutboxMessage{}); err != nil {
+		return err
+	}
+
+	if err := m.migrateIdempotencies(); err != nil {
+		return err
+	}
+
+	if err := m.migrateCategoryPaths(); err != nil {
+		return err
+	}
+
+	return m.migrateSearch()
+}
+
+// migrateIdempotencies turns the inventory updates recorded before the reservations into confirmed reservations,
+// since they already took the stock, or released ones when they were rolled back
+func (m *Migrator) migrateIdempotencies() error {
+	if !m.db.Migrator().HasTable(legacyIdempotencyTable) {
+		return nil
+	}
+
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`INSERT INTO reservations (id, product_id, quantity, status, expires_at, updated_at, created_at)
+			SELECT id, product_id, quantity, CASE WHEN rollbacked THEN ? ELSE ? END, created_at, created_at, created_at
+			FROM `+legacyIdempotencyTable+` ON CONFLICT DO NOTHING`,
+			entity.ReservationReleased, entity.ReservationConfirmed).Error; err != nil {
 			return err
 		}
+
+		return tx.Migrator().DropTable(legacyIdempotencyTable)
+	})
+}
+
+// migrateCategoryPaths makes the categories created before the hierarchy root categories,
+// and indexes the paths for the prefix matches of the subtrees
+func (m *Migrator) migrateCategoryPaths() error {
+	if err := m.db.Exec("UPDATE categories SET path = '/' || id || '/' WHERE path = ''").Error; err != nil {
+		return err
+	}
+
+	return m.db.Exec("CREATE INDEX IF NOT EXISTS idx_categories_path ON categories (path text_pattern_ops)").Error
+}
+
+// migrateSearch adds the search_vector generated column of the full-text search, which gorm can not declare,
+// weighting the name before the brand and the description
+func (m *Migrator) migrateSearch() error {
+	if err := m.db.Exec(`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('` + model.SearchConfig + `', coalesce(name, '')), 'A') ||
+		setweight(to_tsvector('` + model.SearchConfig + `', coalesce(brand_name, '')), 'B') ||
+		setweight(to_tsvector('` + model.SearchConfig + `', coalesce(description, '')), 'C')) STORED`).Error; err != nil {
+		return err
 	}
 
-	return m.db.AutoMigrate(&model.Category{}, &model.Product{}, &model.Idempotency{})
+	return m.db.Exec("CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)").Error
 }
@@ -1,9 +1,12 @@
 package model
 
+// Category is a node of the category tree, Path is its materialized path as in entity.Category
 type Category struct {
 	ID          uint64 `gorm:"primaryKey"`
+	ParentID    uint64 `gorm:"not null;default:0;index"`
 	Name        string `gorm:"type:varchar(256);not null"`
 	Description string `gorm:"type:text;not null"`
+	Path        string `gorm:"type:varchar(1024);not null;default:''"`
 	UpdatedAt   int64  `gorm:"autoUpdateTime:milli"`
 	CreatedAt   int64  `gorm:"autoCreateTime:milli"`
 }
@@ -1,22 +1,32 @@
 package model
 
+// SearchConfig is the text search configuration of the full-text search over the products
+const SearchConfig = "english"
+
+// Product is indexed on (created_at, id), the keyset of the catalog pagination.
+// Inventory is the stock on hand, Reserved the part of it held by the pending reservations.
 type Product struct {
-	ID          uint64 `gorm:"primaryKey"`
+	ID          uint64 `gorm:"primaryKey;index:idx_products_created_at_id,priority:2"`
 	CategoryID  uint64
 	Category    Category `gorm:"foreignKey:CategoryID"`
 	Name        string   `gorm:"type:varchar(256);not null"`
 	Description string   `gorm:"type:text;not null"`
 	BrandName   string   `gorm:"type:varchar(256);not null"`
 	Inventory   uint64   `gorm:"not null"`
+	Reserved    uint64   `gorm:"not null;default:0"`
 	Price       uint64   `gorm:"not null"`
 	UpdatedAt   int64    `gorm:"autoUpdateTime:milli"`
-	CreatedAt   int64    `gorm:"autoCreateTime:milli"`
+	CreatedAt   int64    `gorm:"autoCreateTime:milli;index:idx_products_created_at_id,priority:1"`
 }
 
-type Idempotency struct {
-	ID         uint64 `gorm:"primaryKey"`
-	ProductID  uint64 `gorm:"primaryKey"`
-	Quantity   uint64 `gorm:"not null"`
-	Rollbacked bool   `gorm:"not null"`
-	CreatedAt  int64  `gorm:"autoCreateTime:milli"`
+// Reservation holds Quantity of a product for a purchase, the reaper expires it at ExpiresAt (unix milliseconds)
+// unless it was confirmed or released before
+type Reservation struct {
+	ID        uint64 `gorm:"primaryKey"` // id of a purchase
+	ProductID uint64 `gorm:"primaryKey"`
+	Quantity  uint64 `gorm:"not null"`
+	Status    string `gorm:"type:varchar(16);not null;index:idx_reservations_status_expires_at,priority:1"`
+	ExpiresAt int64  `gorm:"not null;index:idx_reservations_status_expires_at,priority:2"`
+	UpdatedAt int64  `gorm:"autoUpdateTime:milli"`
+	CreatedAt int64  `gorm:"autoCreateTime:milli"`
 }
@@ -75,6 +75,60 @@ func (srv *Server) GetProducts(ctx context.Context, req *pb.GetProductsRequest)
 	}, nil
 }
 
+func (srv *Server) ListProducts(ctx context.Context, req *pb.ListProductsRequest) (*pb.ListProductsResponse, error) {
+	sort, ok := productSorts[req.GetSort()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, valueobject.ErrInvalidSort.Error())
+	}
+
+	page, err := srv.productApp.Queries.ListProducts.Handle(ctx, query.ListProducts{
+		CategoryID: req.GetCategoryId(),
+		BrandName:  req.GetBrandName(),
+		MinPrice:   req.GetMinPrice(),
+		MaxPrice:   req.GetMaxPrice(),
+		InStock:    req.GetInStock(),
+		Sort:       sort,
+		Limit:      req.GetLimit(),
+		Cursor:     req.GetCursor(),
+	})
+	if err != nil {
+		if err == valueobject.ErrInvalidCursor || err == valueobject.ErrInvalidSort {
+			return nil, status.Errorf(codes.InvalidArgument, err.Error())
+		}
+
+		return nil, status.Errorf(
+			codes.Internal,
+			fmt.Sprintf("internal error: %v", err),
+		)
+	}
+
+	pbProducts := make([]*pb.Product, len(*page.Products))
+
+	for i, product := range *page.Products {
+		pbProducts[i] = &pb.Product{
+			Id:        product.ID,
+			Category:  product.CategoryID,
+			Name:      product.Name,
+			BrandName: product.BrandName,
+			Price:     product.Price,
+			Inventory: product.Inventory,
+			CreatedAt: product.CreatedAt,
+		}
+	}
+
+	return &pb.ListProductsResponse{
+		Products:   pbProducts,
+		NextCursor: page.NextCursor,
+	}, nil
+}
+
+var productSorts = map[pb.ProductSort]string{
+	pb.ProductSort_NEWEST:     valueobject.SortNewest,
+	pb.ProductSort_OLDEST:     valueobject.SortOldest,
+	pb.ProductSort_PRICE_ASC:  valueobject.SortPriceAsc,
+	pb.ProductSort_PRICE_DESC: valueobject.SortPriceDesc,
+}
+
 func getPBStatus(productStatus valueobject.ProductStatus) pb.Status {
 	if productStatus.Status {
 		return pb.Status_OK
@@ -5,7 +5,10 @@ import (
 	"github.com/scul0405/saga-orchestration/cmd/product/config"
 	"github.com/scul0405/saga-orchestration/internal/product/app"
 	pb "github.com/scul0405/saga-orchestration/proto"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 	"log"
@@ -20,7 +23,7 @@ type Server struct {
 	pb.UnimplementedProductServiceServer
 }
 
-func NewGRPCServer(config config.GRPC, productApp app.ProductApplication) *Server {
+func NewGRPCServer(config config.GRPC, productApp app.ProductApplication, healthSrv *grpchealth.Server) *Server {
 	srv := &Server{
 		Port: config.Port,
 		productApp:  productApp,
@@ -33,6 +36,7 @@ func NewGRPCServer(config config.GRPC, productApp app.ProductApplication) *Serve
 			Timeout:           config.Timeout * time.Second,
 			Time:              config.Time * time.Second,
 		}),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
 		grpc.ChainUnaryInterceptor(
 			grpcrecovery.UnaryServerInterceptor(),
 		),
@@ -40,6 +44,8 @@ func NewGRPCServer(config config.GRPC, productApp app.ProductApplication) *Serve
 
 	pb.RegisterProductServiceServer(srv.grpcServer, srv)
 
+	healthpb.RegisterHealthServer(srv.grpcServer, healthSrv)
+
 	reflection.Register(srv.grpcServer)
 	return srv
 }
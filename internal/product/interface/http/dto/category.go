@@ -1,6 +1,31 @@
 package dto
 
+import "time"
+
 type CreateCategory struct {
-	Name        string `json:"name"`
+	ParentID    uint64 `json:"parent_id"`
+	Name        string `json:"name" binding:"required"`
 	Description string `json:"description"`
-}
\ No newline at end of file
+}
+
+type CreateCategoryResponse struct {
+	CategoryID uint64 `json:"category_id"`
+}
+
+// UpdateCategory moves the category when parent_id is given, 0 being the root
+type UpdateCategory struct {
+	ParentID    *uint64 `json:"parent_id"`
+	Name        string  `json:"name" binding:"required"`
+	Description string  `json:"description"`
+}
+
+type Category struct {
+	ID           uint64    `json:"id"`
+	ParentID     uint64    `json:"parent_id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	Path         string    `json:"path"`
+	ProductCount uint64    `json:"product_count"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
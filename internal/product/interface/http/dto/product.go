@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 type Product struct {
 	ID          uint64 `json:"id"`
 	CategoryID  uint64 `json:"category_id"`
@@ -26,3 +28,50 @@ type UpdateProductDetail struct {
 	Description string `json:"description"`
 	Price       uint64 `json:"price"`
 }
+
+type ListProducts struct {
+	CategoryID uint64 `form:"category_id"`
+	BrandName  string `form:"brand"`
+	MinPrice   uint64 `form:"min_price"`
+	MaxPrice   uint64 `form:"max_price"`
+	InStock    bool   `form:"in_stock"`
+	Sort       string `form:"sort,default=newest" binding:"oneof=newest oldest price_asc price_desc"`
+	Limit      uint64 `form:"limit,default=20" binding:"min=1,max=100"`
+	Cursor     string `form:"cursor"`
+}
+
+type SearchProducts struct {
+	Query      string `form:"q" binding:"required"`
+	CategoryID uint64 `form:"category_id"`
+	MinPrice   uint64 `form:"min_price"`
+	MaxPrice   uint64 `form:"max_price"`
+	Limit      uint64 `form:"limit,default=20" binding:"min=1,max=100"`
+	Offset     uint64 `form:"offset,default=0"`
+}
+
+type ProductSearchResult struct {
+	ID                   uint64  `json:"id"`
+	CategoryID           uint64  `json:"category_id"`
+	Name                 string  `json:"name"`
+	BrandName            string  `json:"brand_name"`
+	Price                uint64  `json:"price"`
+	Inventory            uint64  `json:"inventory"`
+	Rank                 float32 `json:"rank"`
+	NameHighlight        string  `json:"name_highlight"`
+	DescriptionHighlight string  `json:"description_highlight"`
+}
+
+type ProductCatalog struct {
+	ID         uint64    `json:"id"`
+	CategoryID uint64    `json:"category_id"`
+	Name       string    `json:"name"`
+	BrandName  string    `json:"brand_name"`
+	Price      uint64    `json:"price"`
+	Inventory  uint64    `json:"inventory"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ProductPage struct {
+	Products   []ProductCatalog `json:"products"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
@@ -5,11 +5,14 @@ import (
 	"github.com/scul0405/saga-orchestration/internal/product/app"
 	"github.com/scul0405/saga-orchestration/internal/product/app/command"
 	"github.com/scul0405/saga-orchestration/internal/product/app/query"
+	"github.com/scul0405/saga-orchestration/internal/product/domain/entity"
+	"github.com/scul0405/saga-orchestration/internal/product/domain/valueobject"
 	"github.com/scul0405/saga-orchestration/internal/product/infrastructure/grpc"
 	"github.com/scul0405/saga-orchestration/internal/product/interface/http/dto"
 	"gorm.io/gorm"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 var (
@@ -19,6 +22,7 @@ var (
 	ErrNotFound     = "not found"
 	ErrInternal     = "internal error"
 	ErrInvalidToken = "invalid token"
+	ErrInvalidQuery = "invalid query"
 )
 
 type Router struct {
@@ -126,6 +130,126 @@ func (r *Router) GetProduct(c *gin.Context) {
 	})
 }
 
+func (r *Router) ListProducts(c *gin.Context) {
+	var params dto.ListProducts
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidQuery})
+		return
+	}
+
+	r.listProducts(c, query.ListProducts{
+		CategoryID: params.CategoryID,
+		BrandName:  params.BrandName,
+		MinPrice:   params.MinPrice,
+		MaxPrice:   params.MaxPrice,
+		InStock:    params.InStock,
+		Sort:       params.Sort,
+		Limit:      params.Limit,
+		Cursor:     params.Cursor,
+	})
+}
+
+// ListCategoryProducts lists the products of a category and its subcategories, with the filters of ListProducts
+func (r *Router) ListCategoryProducts(c *gin.Context) {
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidID})
+		return
+	}
+
+	var params dto.ListProducts
+	if err = c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidQuery})
+		return
+	}
+
+	r.listProducts(c, query.ListProducts{
+		CategoryID:    categoryID,
+		Subcategories: true,
+		BrandName:     params.BrandName,
+		MinPrice:      params.MinPrice,
+		MaxPrice:      params.MaxPrice,
+		InStock:       params.InStock,
+		Sort:          params.Sort,
+		Limit:         params.Limit,
+		Cursor:        params.Cursor,
+	})
+}
+
+func (r *Router) listProducts(c *gin.Context, listProducts query.ListProducts) {
+	page, err := r.productApp.Queries.ListProducts.Handle(c, listProducts)
+	if err != nil {
+		if err == valueobject.ErrInvalidCursor || err == valueobject.ErrInvalidSort {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
+		return
+	}
+
+	resp := dto.ProductPage{
+		Products:   make([]dto.ProductCatalog, len(*page.Products)),
+		NextCursor: page.NextCursor,
+	}
+	for i, product := range *page.Products {
+		resp.Products[i] = dto.ProductCatalog{
+			ID:         product.ID,
+			CategoryID: product.CategoryID,
+			Name:       product.Name,
+			BrandName:  product.BrandName,
+			Price:      product.Price,
+			Inventory:  product.Inventory,
+			CreatedAt:  time.UnixMilli(product.CreatedAt),
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (r *Router) SearchProducts(c *gin.Context) {
+	var params dto.SearchProducts
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidQuery})
+		return
+	}
+
+	results, err := r.productApp.Queries.SearchProducts.Handle(c, query.SearchProducts{
+		Query:      params.Query,
+		CategoryID: params.CategoryID,
+		MinPrice:   params.MinPrice,
+		MaxPrice:   params.MaxPrice,
+		Limit:      params.Limit,
+		Offset:     params.Offset,
+	})
+	if err != nil {
+		if err == valueobject.ErrInvalidSearch {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
+		return
+	}
+
+	resp := make([]dto.ProductSearchResult, len(*results))
+	for i, result := range *results {
+		resp[i] = dto.ProductSearchResult{
+			ID:                   result.ID,
+			CategoryID:           result.CategoryID,
+			Name:                 result.Name,
+			BrandName:            result.BrandName,
+			Price:                result.Price,
+			Inventory:            result.Inventory,
+			Rank:                 result.Rank,
+			NameHighlight:        result.NameHighlight,
+			DescriptionHighlight: result.DescriptionHighlight,
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
 func (r *Router) CreateCategory(c *gin.Context) {
 	var category dto.CreateCategory
 	if err := c.ShouldBindJSON(&category); err != nil {
@@ -133,16 +257,115 @@ func (r *Router) CreateCategory(c *gin.Context) {
 		return
 	}
 
-	err := r.categoryApp.Commands.CreateCategory.Handle(c, command.CreateCategory{
+	categoryID, err := r.categoryApp.Commands.CreateCategory.Handle(c, command.CreateCategory{
+		ParentID:    category.ParentID,
 		Name:        category.Name,
 		Description: category.Description,
 	})
 	if err != nil {
+		if err == entity.ErrParentNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": OkMessage})
+	c.JSON(http.StatusCreated, dto.CreateCategoryResponse{CategoryID: categoryID})
+}
+
+func (r *Router) GetCategory(c *gin.Context) {
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidID})
+		return
+	}
+
+	category, err := r.categoryApp.Queries.GetCategory.Handle(c, query.GetCategory{CategoryID: categoryID})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": ErrNotFound})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
+		return
+	}
+
+	c.JSON(http.StatusOK, encodeCategory(category))
+}
+
+func (r *Router) ListCategories(c *gin.Context) {
+	categories, err := r.categoryApp.Queries.ListCategories.Handle(c, query.ListCategories{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
+		return
+	}
+
+	resp := make([]dto.Category, len(*categories))
+	for i := range *categories {
+		resp[i] = *encodeCategory(&(*categories)[i])
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (r *Router) UpdateCategory(c *gin.Context) {
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidID})
+		return
+	}
+
+	var category dto.UpdateCategory
+	if err = c.ShouldBindJSON(&category); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidJSON})
+		return
+	}
+
+	err = r.categoryApp.Commands.UpdateCategory.Handle(c, command.UpdateCategory{
+		CategoryID:  categoryID,
+		ParentID:    category.ParentID,
+		Name:        category.Name,
+		Description: category.Description,
+	})
+	if err != nil {
+		switch err {
+		case gorm.ErrRecordNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": ErrNotFound})
+		case entity.ErrParentNotFound, entity.ErrCategoryCycle:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": OkMessage})
+}
+
+func (r *Router) DeleteCategory(c *gin.Context) {
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidID})
+		return
+	}
+
+	err = r.categoryApp.Commands.DeleteCategory.Handle(c, command.DeleteCategory{CategoryID: categoryID})
+	if err != nil {
+		switch err {
+		case gorm.ErrRecordNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": ErrNotFound})
+		case entity.ErrCategoryNotEmpty:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": OkMessage})
 }
 
 func (r *Router) extractCustomerID(c *gin.Context) uint64 {
@@ -154,3 +377,16 @@ func (r *Router) extractCustomerID(c *gin.Context) uint64 {
 
 	return id.(uint64)
 }
+
+func encodeCategory(category *entity.Category) *dto.Category {
+	return &dto.Category{
+		ID:           category.ID,
+		ParentID:     category.ParentID,
+		Name:         category.Name,
+		Description:  category.Description,
+		Path:         category.Path,
+		ProductCount: category.ProductCount,
+		UpdatedAt:    category.UpdatedAt,
+		CreatedAt:    category.CreatedAt,
+	}
+}
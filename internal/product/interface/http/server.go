@@ -3,9 +3,12 @@ package http
 import (
 	"context"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/scul0405/saga-orchestration/cmd/product/config"
+	"github.com/scul0405/saga-orchestration/internal/pkg/health"
 	"github.com/scul0405/saga-orchestration/internal/product/interface/http/middleware"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"net/http"
 )
 
@@ -14,15 +17,17 @@ type Server struct {
 	logger     logger.Logger
 	Engine     *gin.Engine
 	Router     *Router
+	health     *health.Checker
 	httpServer *http.Server
 }
 
-func NewHTTPServer(config config.HTTP, logger logger.Logger, engine *gin.Engine, router *Router) *Server {
+func NewHTTPServer(config config.HTTP, logger logger.Logger, engine *gin.Engine, router *Router, health *health.Checker) *Server {
 	return &Server{
 		config: config,
 		logger: logger,
 		Engine: engine,
 		Router: router,
+		health: health,
 	}
 }
 
@@ -31,6 +36,7 @@ func NewEngine(config config.HTTP) *gin.Engine {
 
 	engine := gin.New()
 	engine.Use(gin.Recovery())
+	engine.Use(otelgin.Middleware("product_service"))
 	engine.Use(gin.Logger()) // TODO: replace with custom logger
 	engine.Use(middleware.CORSMiddleware())
 
@@ -40,18 +46,28 @@ func NewEngine(config config.HTTP) *gin.Engine {
 func (srv *Server) InitRoutes() {
 	mw := middleware.NewJWTAuthMW(srv.Router.authSvc, srv.logger)
 
+	srv.Engine.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	srv.Engine.GET("/healthz", gin.WrapH(health.LivenessHandler()))
+	srv.Engine.GET("/readyz", gin.WrapH(srv.health.ReadinessHandler()))
+
 	apiGroup := srv.Engine.Group("/api/v1/")
 	{
 		productGroup := apiGroup.Group("/products")
 		{
+			productGroup.GET("", srv.Router.ListProducts)
+			productGroup.GET("/search", srv.Router.SearchProducts)
 			productGroup.GET("/:id", srv.Router.GetProduct)
 			productGroup.POST("/", mw.AuthMiddleware(), srv.Router.CreateProduct)
 			productGroup.PUT("/:id", srv.Router.UpdateProductDetail)
 		}
 		categoryGroup := apiGroup.Group("/categories")
-		categoryGroup.Use(mw.AuthMiddleware())
 		{
-			categoryGroup.POST("/", srv.Router.CreateCategory)
+			categoryGroup.GET("", srv.Router.ListCategories)
+			categoryGroup.GET("/:id", srv.Router.GetCategory)
+			categoryGroup.GET("/:id/products", srv.Router.ListCategoryProducts)
+			categoryGroup.POST("/", mw.AuthMiddleware(), srv.Router.CreateCategory)
+			categoryGroup.PUT("/:id", mw.AuthMiddleware(), srv.Router.UpdateCategory)
+			categoryGroup.DELETE("/:id", mw.AuthMiddleware(), srv.Router.DeleteCategory)
 		}
 	}
 }
@@ -8,6 +8,8 @@ import (
 	"github.com/scul0405/saga-orchestration/internal/product/domain/entity"
 	"github.com/scul0405/saga-orchestration/internal/product/infrastructure/db/postgres/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 type categoryRepositoryImpl struct {
@@ -20,6 +22,12 @@ func NewCategoryRepositoryImpl(db *gorm.DB) domain.CategoryRepository {
 	}
 }
 
+// categoryRow is a category with the number of products of its subtree
+type categoryRow struct {
+	model.Category
+	ProductCount uint64
+}
+
 func (r *categoryRepositoryImpl) CheckCategory(ctx context.Context, categoryID uint64) (bool, error) {
 	var count int64
 	if err := r.db.Model(&model.Category{}).Where("id = ?", categoryID).Count(&count).WithContext(ctx).Error; err != nil {
@@ -29,29 +37,167 @@ func (r *categoryRepositoryImpl) CheckCategory(ctx context.Context, categoryID u
 }
 
 func (r *categoryRepositoryImpl) GetCategory(ctx context.Context, categoryID uint64) (*entity.Category, error) {
-	var category entity.Category
-	if err := r.db.Where("id = ?", categoryID).First(&category).WithContext(ctx).Error; err != nil {
+	var row categoryRow
+	if err := r.withProductCount(ctx).Where("id = ?", categoryID).Take(&row).Error; err != nil {
+		return nil, err
+	}
+	return decodeCategory(&row), nil
+}
+
+// ListCategories returns the whole category tree in the order of the paths, each category before its subcategories
+func (r *categoryRepositoryImpl) ListCategories(ctx context.Context) (*[]entity.Category, error) {
+	var rows []categoryRow
+	if err := r.withProductCount(ctx).Order("path").Find(&rows).Error; err != nil {
 		return nil, err
 	}
-	return &category, nil
+
+	categories := make([]entity.Category, len(rows))
+	for i := range rows {
+		categories[i] = *decodeCategory(&rows[i])
+	}
+	return &categories, nil
 }
 
+// CreateCategory places the category under its parent, it returns entity.ErrParentNotFound when the parent does not exist
 func (r *categoryRepositoryImpl) CreateCategory(ctx context.Context, category *entity.Category) error {
-	if err := r.db.Create(category).WithContext(ctx).Error; err != nil {
-		if pgError := err.(*pgconn.PgError); errors.Is(err, pgError) {
-			if pgError.Code == "23505" {
-				return ErrDuplicateEntry
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		parent, err := lockParent(tx, category.ParentID)
+		if err != nil {
+			return err
+		}
+		if err = category.Place(parent); err != nil {
+			return err
+		}
+
+		return tx.Create(&model.Category{
+			ID:          category.ID,
+			ParentID:    category.ParentID,
+			Name:        category.Name,
+			Description: category.Description,
+			Path:        category.Path,
+		}).Error
+	})
+
+	var pgError *pgconn.PgError
+	if errors.As(err, &pgError) && pgError.Code == "23505" {
+		return ErrDuplicateEntry
+	}
+	return err
+}
+
+// UpdateCategory updates the detail of the category and, when move is true, moves it with its subtree under
+// category.ParentID. It returns entity.ErrParentNotFound or entity.ErrCategoryCycle when the category can not be moved.
+func (r *categoryRepositoryImpl) UpdateCategory(ctx context.Context, category *entity.Category, move bool) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		current, err := lockCategory(tx, category.ID, clause.LockingStrengthUpdate)
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"name":        category.Name,
+			"description": category.Description,
+		}
+
+		if move && category.ParentID != current.ParentID {
+			parent, err := lockParent(tx, category.ParentID)
+			if err != nil {
+				return err
+			}
+
+			path := current.Path
+			if err = current.Place(parent); err != nil {
+				return err
 			}
+
+			// Lock the subtree before moving it, so that the statement below sees the subcategories
+			// created under it until then, and the ones created later are placed under the moved paths
+			var subtree []uint64
+			if err = tx.Model(&model.Category{}).Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).
+				Where("path LIKE ?", path+"%").Pluck("id", &subtree).Error; err != nil {
+				return err
+			}
+
+			// Move the subcategories along, replacing the prefix of their paths
+			if err = tx.Exec("UPDATE categories SET path = ? || substr(path, ?) WHERE path LIKE ? AND id <> ?",
+				current.Path, len(path)+1, path+"%", current.ID).Error; err != nil {
+				return err
+			}
+
+			updates["parent_id"] = current.ParentID
+			updates["path"] = current.Path
+		}
+
+		return tx.Model(&model.Category{}).Where("id = ?", category.ID).Updates(updates).Error
+	})
+}
+
+// DeleteCategory deletes a category without subcategories nor products, it returns entity.ErrCategoryNotEmpty otherwise
+func (r *categoryRepositoryImpl) DeleteCategory(ctx context.Context, categoryID uint64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := lockCategory(tx, categoryID, clause.LockingStrengthUpdate); err != nil {
+			return err
+		}
+
+		var children int64
+		if err := tx.Model(&model.Category{}).Where("parent_id = ?", categoryID).Count(&children).Error; err != nil {
+			return err
+		}
+
+		var products int64
+		if err := tx.Model(&model.Product{}).Where("category_id = ?", categoryID).Count(&products).Error; err != nil {
+			return err
+		}
+
+		if children > 0 || products > 0 {
+			return entity.ErrCategoryNotEmpty
 		}
-		return err
+
+		return tx.Delete(&model.Category{}, categoryID).Error
+	})
+}
+
+// withProductCount selects the categories with the number of products of their subtree
+func (r *categoryRepositoryImpl) withProductCount(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&model.Category{}).
+		Select("categories.*, (?) AS product_count", r.db.Model(&model.Product{}).
+			Select("count(*)").
+			Joins("JOIN categories subtree ON subtree.id = products.category_id").
+			Where("subtree.path LIKE categories.path || '%'"))
+}
+
+// lockCategory locks the category until the end of tx, so that its path does not change meanwhile
+func lockCategory(tx *gorm.DB, categoryID uint64, strength string) (*entity.Category, error) {
+	var category model.Category
+	if err := tx.Clauses(clause.Locking{Strength: strength}).
+		Where("id = ?", categoryID).Take(&category).Error; err != nil {
+		return nil, err
+	}
+	return decodeCategory(&categoryRow{Category: category}), nil
+}
+
+// lockParent locks the parent category to place a category under, it returns nil for the root
+func lockParent(tx *gorm.DB, parentID uint64) (*entity.Category, error) {
+	if parentID == 0 {
+		return nil, nil
 	}
 
-	return nil
+	parent, err := lockCategory(tx, parentID, clause.LockingStrengthShare)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entity.ErrParentNotFound
+	}
+	return parent, err
 }
 
-func (r *categoryRepositoryImpl) UpdateCategory(ctx context.Context, categoryID uint64, category *entity.Category) error {
-	return r.db.Model(&model.Category{}).Where("id = ?", categoryID).Updates(&model.Category{
-		Name:        category.Name,
-		Description: category.Description,
-	}).WithContext(ctx).Error
+func decodeCategory(row *categoryRow) *entity.Category {
+	return &entity.Category{
+		ID:           row.ID,
+		ParentID:     row.ParentID,
+		Name:         row.Name,
+		Description:  row.Description,
+		Path:         row.Path,
+		ProductCount: row.ProductCount,
+		UpdatedAt:    time.UnixMilli(row.UpdatedAt),
+		CreatedAt:    time.UnixMilli(row.CreatedAt),
+	}
 }
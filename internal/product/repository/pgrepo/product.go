@@ -5,28 +5,31 @@ import (
 	"database/sql"
 	"errors"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/scul0405/saga-orchestration/internal/pkg/outbox"
 	"github.com/scul0405/saga-orchestration/internal/product/domain/entity"
 	"github.com/scul0405/saga-orchestration/internal/product/domain/valueobject"
 	"github.com/scul0405/saga-orchestration/internal/product/infrastructure/db/postgres/model"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"sort"
+	"time"
 )
 
 type ProductRepository interface {
 	CheckProduct(ctx context.Context, productID uint64, quantity uint64) (*valueobject.ProductStatus, error)
+	CheckProducts(ctx context.Context, purchasedProducts *[]valueobject.PurchasedProduct) (*[]valueobject.ProductStatus, error)
 	GetProductDetail(ctx context.Context, productID uint64) (*valueobject.ProductDetail, error)
 	GetProductInventory(ctx context.Context, productID uint64) (uint64, error)
 	GetProduct(ctx context.Context, productID uint64) (*entity.Product, error)
-	ListProducts(ctx context.Context, limit, offset uint64) (*[]valueobject.ProductCatalog, error)
+	GetProducts(ctx context.Context, productIDs *[]uint64) (*[]entity.Product, error)
+	ListProducts(ctx context.Context, filter *valueobject.ProductFilter) (*[]valueobject.ProductCatalog, error)
+	SearchProducts(ctx context.Context, search *valueobject.ProductSearch) (*[]valueobject.ProductSearchResult, error)
 	CreateProduct(ctx context.Context, product *entity.Product) error
 	UpdateProductDetail(ctx context.Context, productID uint64, product *valueobject.ProductDetail) error
-	UpdateProductInventory(ctx context.Context, idempotencyKey uint64, purchasedProducts *[]valueobject.PurchasedProduct) error
-	RollbackProductInventory(ctx context.Context, idempotencyKey uint64, purchasedProducts *[]valueobject.PurchasedProduct) error
-}
-
-type ProductInventory struct {
-	Inventory uint64
+	ReserveProductInventory(ctx context.Context, purchaseID uint64, purchasedProducts *[]valueobject.PurchasedProduct, expiresAt time.Time, reply *outbox.Message) error
+	ConfirmProductInventory(ctx context.Context, purchaseID uint64, reply *outbox.Message) error
+	ReleaseProductInventory(ctx context.Context, purchaseID uint64, reply *outbox.Message) (*[]entity.Reservation, error)
+	ReleaseExpiredReservations(ctx context.Context, now time.Time, limit int) (*[]entity.Reservation, error)
 }
 
 type productRepositoryImpl struct {
@@ -39,7 +42,7 @@ func NewProductRepository(db *gorm.DB) ProductRepository {
 
 func (r *productRepositoryImpl) CheckProduct(ctx context.Context, productID uint64, quantity uint64) (*valueobject.ProductStatus, error) {
 	var productStatus model.Product
-	if err := r.db.Model(&model.Product{}).Where("id = ?", productID).Select("id", "price", "inventory").First(&productStatus).Error; err != nil {
+	if err := r.db.Model(&model.Product{}).Where("id = ?", productID).Select("id", "price", "inventory", "reserved").First(&productStatus).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return &valueobject.ProductStatus{
 				ID:     productID,
@@ -54,10 +57,40 @@ func (r *productRepositoryImpl) CheckProduct(ctx context.Context, productID uint
 	return &valueobject.ProductStatus{
 		ID:     productStatus.ID,
 		Price:  productStatus.Price,
-		Status: productStatus.Inventory > quantity,
+		Status: available(&productStatus) > quantity,
 	}, nil
 }
 
+// CheckProducts checks the purchased products with a single query, the statuses follow the order of purchasedProducts
+func (r *productRepositoryImpl) CheckProducts(ctx context.Context, purchasedProducts *[]valueobject.PurchasedProduct) (*[]valueobject.ProductStatus, error) {
+	productIDs := make([]uint64, len(*purchasedProducts))
+	for i, p := range *purchasedProducts {
+		productIDs[i] = p.ID
+	}
+
+	var products []model.Product
+	if err := r.db.WithContext(ctx).Model(&model.Product{}).Where("id IN ?", productIDs).Select("id", "price", "inventory", "reserved").Find(&products).Error; err != nil {
+		return nil, err
+	}
+
+	found := make(map[uint64]model.Product, len(products))
+	for _, product := range products {
+		found[product.ID] = product
+	}
+
+	statuses := make([]valueobject.ProductStatus, len(*purchasedProducts))
+	for i, p := range *purchasedProducts {
+		product, ok := found[p.ID]
+		statuses[i] = valueobject.ProductStatus{
+			ID:     p.ID,
+			Price:  product.Price,
+			Status: ok && available(&product) > p.Quantity,
+		}
+	}
+
+	return &statuses, nil
+}
+
 func (r *productRepositoryImpl) GetProductDetail(ctx context.Context, productID uint64) (*valueobject.ProductDetail, error) {
 	var product valueobject.ProductDetail
 	if err := r.db.Where("id = ?", productID).First(&product).Error; err != nil {
@@ -67,11 +100,11 @@ func (r *productRepositoryImpl) GetProductDetail(ctx context.Context, productID
 }
 
 func (r *productRepositoryImpl) GetProductInventory(ctx context.Context, productID uint64) (uint64, error) {
-	var product ProductInventory
-	if err := r.db.Where("id = ?", productID).Select("inventory").First(&product).Error; err != nil {
+	var product model.Product
+	if err := r.db.Model(&model.Product{}).Where("id = ?", productID).Select("inventory", "reserved").First(&product).Error; err != nil {
 		return 0, err
 	}
-	return product.Inventory, nil
+	return available(&product), nil
 }
 
 func (r *productRepositoryImpl) GetProduct(ctx context.Context, productID uint64) (*entity.Product, error) {
@@ -80,6 +113,10 @@ func (r *productRepositoryImpl) GetProduct(ctx context.Context, productID uint64
 		return nil, err
 	}
 
+	return decodeProduct(&product), nil
+}
+
+func decodeProduct(product *model.Product) *entity.Product {
 	return &entity.Product{
 		ID:         product.ID,
 		CategoryID: product.CategoryID,
@@ -89,16 +126,87 @@ func (r *productRepositoryImpl) GetProduct(ctx context.Context, productID uint64
 			BrandName:   product.BrandName,
 			Price:       product.Price,
 		},
-		Inventory: product.Inventory,
-	}, nil
+		Inventory: available(product),
+	}
+}
+
+// GetProducts returns the products with a single query in the order of productIDs,
+// or gorm.ErrRecordNotFound when one of them does not exist
+func (r *productRepositoryImpl) GetProducts(ctx context.Context, productIDs *[]uint64) (*[]entity.Product, error) {
+	var products []model.Product
+	if err := r.db.WithContext(ctx).Where("id IN ?", *productIDs).Find(&products).Error; err != nil {
+		return nil, err
+	}
+
+	found := make(map[uint64]model.Product, len(products))
+	for _, product := range products {
+		found[product.ID] = product
+	}
+
+	result := make([]entity.Product, len(*productIDs))
+	for i, id := range *productIDs {
+		product, ok := found[id]
+		if !ok {
+			return nil, gorm.ErrRecordNotFound
+		}
+		result[i] = *decodeProduct(&product)
+	}
+
+	return &result, nil
+}
+
+// catalogOrders are the ORDER BY clause and the keyset condition after the cursor of each catalog sort
+var catalogOrders = map[string]struct {
+	orderBy string
+	after   string
+}{
+	valueobject.SortNewest:    {"created_at DESC, id DESC", "(created_at, id) < (?, ?)"},
+	valueobject.SortOldest:    {"created_at, id", "(created_at, id) > (?, ?)"},
+	valueobject.SortPriceAsc:  {"price, created_at, id", "(price, created_at, id) > (?, ?, ?)"},
+	valueobject.SortPriceDesc: {"price DESC, created_at DESC, id DESC", "(price, created_at, id) < (?, ?, ?)"},
 }
 
-func (r *productRepositoryImpl) ListProducts(ctx context.Context, limit, offset uint64) (*[]valueobject.ProductCatalog, error) {
-	var products []valueobject.ProductCatalog
-	if err := r.db.Model(&model.Product{}).
-		Select("id", "category_id", "name", "price", "inventory").
-		Offset(int(offset)).Limit(int(limit)).
-		Find(&products).Error; err != nil {
+// ListProducts returns up to filter.Limit products of the catalog after the cursor, with keyset pagination
+func (r *productRepositoryImpl) ListProducts(ctx context.Context, filter *valueobject.ProductFilter) (*[]valueobject.ProductCatalog, error) {
+	order, ok := catalogOrders[filter.Sort]
+	if !ok {
+		return nil, valueobject.ErrInvalidSort
+	}
+
+	db := r.db.WithContext(ctx).Model(&model.Product{}).
+		Select("id", "category_id", "name", "brand_name", "price", "inventory", "reserved", "created_at")
+
+	switch {
+	case filter.CategoryID != 0 && filter.Subcategories:
+		db = db.Where("category_id IN (SELECT id FROM categories WHERE path LIKE (SELECT path || '%' FROM categories WHERE id = ?))",
+			filter.CategoryID)
+	case filter.CategoryID != 0:
+		db = db.Where("category_id = ?", filter.CategoryID)
+	}
+	if filter.BrandName != "" {
+		db = db.Where("brand_name = ?", filter.BrandName)
+	}
+	if filter.MinPrice != 0 {
+		db = db.Where("price >= ?", filter.MinPrice)
+	}
+	if filter.MaxPrice != 0 {
+		db = db.Where("price <= ?", filter.MaxPrice)
+	}
+	if filter.InStock {
+		db = db.Where("inventory > reserved")
+	}
+
+	if after := filter.After; after != nil {
+		switch filter.Sort {
+		case valueobject.SortPriceAsc, valueobject.SortPriceDesc:
+			db = db.Where(order.after, after.Price, after.CreatedAt, after.ID)
+		default:
+			db = db.Where(order.after, after.CreatedAt, after.ID)
+		}
+	}
+
+	var products []model.Product
+	if err := db.Order(order.orderBy).Limit(int(filter.Limit)).Find(&products).Error; err != nil {
 		return nil, err
 	}
 
@@ -108,13 +216,53 @@ func (r *productRepositoryImpl) ListProducts(ctx context.Context, limit, offset
 			ID:         product.ID,
 			CategoryID: product.CategoryID,
 			Name:       product.Name,
+			BrandName:  product.BrandName,
 			Price:      product.Price,
+			Inventory:  available(&product),
+			CreatedAt:  product.CreatedAt,
 		}
 	}
 
 	return &productCatalogs, nil
 }
 
+// SearchProducts returns a page of the products matching search.Query, the most relevant first
+func (r *productRepositoryImpl) SearchProducts(ctx context.Context, search *valueobject.ProductSearch) (*[]valueobject.ProductSearchResult, error) {
+	db := r.db.WithContext(ctx).
+		Table("products, to_tsquery(?, ?) search_query", model.SearchConfig, search.Query).
+		Select(`id, category_id, name, brand_name, price, inventory - reserved AS inventory,
+			ts_rank(search_vector, search_query) AS rank,
+			ts_headline(?, name, search_query, ?) AS name_highlight,
+			ts_headline(?, description, search_query, ?) AS description_highlight`,
+			model.SearchConfig, nameHighlight, model.SearchConfig, descriptionHighlight).
+		Where("search_vector @@ search_query")
+
+	if search.CategoryID != 0 {
+		db = db.Where("category_id = ?", search.CategoryID)
+	}
+	if search.MinPrice != 0 {
+		db = db.Where("price >= ?", search.MinPrice)
+	}
+	if search.MaxPrice != 0 {
+		db = db.Where("price <= ?", search.MaxPrice)
+	}
+
+	var results []valueobject.ProductSearchResult
+	if err := db.Order("rank DESC, id").
+		Offset(int(search.Offset)).Limit(int(search.Limit)).
+		Scan(&results).Error; err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
+
+const (
+	// nameHighlight marks every match of the name, descriptionHighlight only the fragments around the matches
+	nameHighlight        = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+	descriptionHighlight = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5"
+)
+
 func (r *productRepositoryImpl) CreateProduct(ctx context.Context, product *entity.Product) error {
 	if err := r.db.Create(&model.Product{
 		ID:          product.ID,
@@ -147,128 +295,248 @@ func (r *productRepositoryImpl) UpdateProductDetail(ctx context.Context, product
 	return nil
 }
 
-// UpdateProductInventory updates the inventory of purchased products
-func (r *productRepositoryImpl) UpdateProductInventory(ctx context.Context, idempotencyKey uint64, purchasedProducts *[]valueobject.PurchasedProduct) error {
-	// Update each product's inventory in purchased products in a transaction
-	// Note that the idempotency key is purchased id
-	// First, we need to check the idempotency key not exists in the idempotencies table
-	// We start a transaction to update the inventory of each product and insert the idempotency key with product id
-	// and quantity to the idempotencies table
-
-	// Check if the idempotency is exists
-	var count int64
-	if err := r.db.Model(&model.Idempotency{}).Where("id = ?", idempotencyKey).Count(&count).WithContext(ctx).Error; err != nil {
-		return err
-	}
-	if count != 0 {
-		return ErrInvalidIdempotency
-	}
+// reservationsPkey is the primary key of the reservations, the purchase id with the product id
+const reservationsPkey = "reservations_pkey"
 
-	// sort the products by product id to avoid deadlock
-	sort.Slice(*purchasedProducts, func(i, j int) bool {
-		return (*purchasedProducts)[i].ID < (*purchasedProducts)[j].ID
-	})
+// ReserveProductInventory holds the purchased products until expiresAt and writes the reply to the outbox,
+// it returns ErrInvalidIdempotency when the purchase already reserved its products.
+// A product listed several times is reserved once with the total quantity.
+func (r *productRepositoryImpl) ReserveProductInventory(ctx context.Context, purchaseID uint64, purchasedProducts *[]valueobject.PurchasedProduct, expiresAt time.Time, reply *outbox.Message) error {
+	merged := mergePurchasedProducts(*purchasedProducts)
 
-	// Start a transaction
 	// With read committed isolation level and update lock, we can avoid lost update
-	tx := r.db.Begin(&sql.TxOptions{Isolation: sql.LevelReadCommitted})
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		products := make([]model.Product, len(merged))
+		for i, purchasedProduct := range merged {
+			if err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).Model(&model.Product{}).
+				Where("id = ?", purchasedProduct.ID).Select("inventory", "reserved").First(&products[i]).Error; err != nil {
+				return err
+			}
 		}
-	}()
 
-	if tx.Error != nil {
-		return tx.Error
-	}
-
-	// Update each product's inventory
-	for _, purchasedProduct := range *purchasedProducts {
-		var inventory ProductInventory
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&model.Product{}).
-			Where("id = ?", purchasedProduct.ID).Select("inventory").First(&inventory).Error; err != nil {
-			tx.Rollback()
+		// Check if the purchase already reserved its products, the purchase id is the idempotency key.
+		// A concurrent delivery waited on the product locks above, so its reservations are committed and seen here.
+		var count int64
+		if err := tx.Model(&model.Reservation{}).Where("id = ?", purchaseID).Count(&count).Error; err != nil {
 			return err
 		}
+		if count != 0 {
+			return ErrInvalidIdempotency
+		}
+
+		reservations := make([]model.Reservation, len(merged))
+		for i, purchasedProduct := range merged {
+			if available(&products[i]) < purchasedProduct.Quantity {
+				return ErrInsufficientInventory
+			}
+
+			if err := tx.Model(&model.Product{}).Where("id = ?", purchasedProduct.ID).
+				Update("reserved", gorm.Expr("reserved + ?", purchasedProduct.Quantity)).Error; err != nil {
+				return err
+			}
 
-		if inventory.Inventory < purchasedProduct.Quantity {
-			tx.Rollback()
-			return ErrInsufficientInventory
+			reservations[i] = model.Reservation{
+				ID:        purchaseID,
+				ProductID: purchasedProduct.ID,
+				Quantity:  purchasedProduct.Quantity,
+				Status:    entity.ReservationReserved,
+				ExpiresAt: expiresAt.UnixMilli(),
+			}
 		}
 
-		if err := tx.Model(&model.Product{}).Where("id = ?", purchasedProduct.ID).Update("inventory", gorm.Expr("inventory - ?", purchasedProduct.Quantity)).Error; err != nil {
-			tx.Rollback()
-			return err
+		if len(reservations) > 0 {
+			if err := tx.Create(&reservations).Error; err != nil {
+				return err
+			}
 		}
+
+		// The reply is only published if the products are reserved
+		return outbox.Add(tx, reply)
+	}, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+
+	// A concurrent delivery of the command reserved the products first
+	var pgError *pgconn.PgError
+	if errors.As(err, &pgError) && pgError.Code == "23505" && pgError.ConstraintName == reservationsPkey {
+		return ErrInvalidIdempotency
 	}
+	return err
+}
 
-	// Insert the idempotency key to the idempotencies table
-	var idempotencies []model.Idempotency
-	for _, purchasedProduct := range *purchasedProducts {
-		idempotencies = append(idempotencies, model.Idempotency{
-			ID:         idempotencyKey,
-			ProductID:  purchasedProduct.ID,
-			Quantity:   purchasedProduct.Quantity,
-			Rollbacked: false,
-		})
+// mergePurchasedProducts sums the quantities of the products listed several times,
+// the products are sorted by id so that they are locked in the same order by every transaction to avoid deadlock
+func mergePurchasedProducts(purchasedProducts []valueobject.PurchasedProduct) []valueobject.PurchasedProduct {
+	quantities := make(map[uint64]uint64, len(purchasedProducts))
+	merged := make([]valueobject.PurchasedProduct, 0, len(purchasedProducts))
+	for _, purchasedProduct := range purchasedProducts {
+		if _, ok := quantities[purchasedProduct.ID]; !ok {
+			merged = append(merged, valueobject.PurchasedProduct{ID: purchasedProduct.ID})
+		}
+		quantities[purchasedProduct.ID] += purchasedProduct.Quantity
 	}
-	if err := tx.Create(&idempotencies).WithContext(ctx).Error; err != nil {
-		tx.Rollback()
-		return err
+
+	for i := range merged {
+		merged[i].Quantity = quantities[merged[i].ID]
 	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].ID < merged[j].ID
+	})
 
-	return tx.Commit().Error
+	return merged
 }
 
-// RollbackProductInventory rollbacks the inventory of purchased products
-func (r *productRepositoryImpl) RollbackProductInventory(ctx context.Context, idempotencyKey uint64, purchasedProducts *[]valueobject.PurchasedProduct) error {
-	// Get all idempotencies
-	var idempotencies []model.Idempotency
-	if err := r.db.Model(&model.Idempotency{}).Select("product_id", "quantity", "rollbacked").
-		Where("id = ?", idempotencyKey).Order("product_id").
-		Find(&idempotencies).WithContext(ctx).Error; err != nil {
-		return err
-	}
+// ConfirmProductInventory takes the stock held by the reservations of the purchase and writes the reply to the outbox.
+// It returns entity.ErrReservationExpired when they were released or expired before, and gorm.ErrRecordNotFound
+// when the purchase reserved nothing.
+func (r *productRepositoryImpl) ConfirmProductInventory(ctx context.Context, purchaseID uint64, reply *outbox.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		reservations, err := lockReservations(tx, purchaseID)
+		if err != nil {
+			return err
+		}
+		if len(reservations) == 0 {
+			return gorm.ErrRecordNotFound
+		}
 
-	// Start a transaction
-	// With read committed isolation level and update lock, we can avoid lost update
-	tx := r.db.Begin(&sql.TxOptions{Isolation: sql.LevelReadCommitted})
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+		confirmed := 0
+		for _, reservation := range reservations {
+			switch reservation.Status {
+			case entity.ReservationConfirmed:
+				confirmed++
+			case entity.ReservationReserved:
+			default:
+				return entity.ErrReservationExpired
+			}
+		}
+		if confirmed == len(reservations) {
+			// Redelivered command, the reply was written with the confirmation
+			return nil
 		}
-	}()
 
-	if tx.Error != nil {
-		return tx.Error
-	}
+		for _, reservation := range reservations {
+			if err = tx.Model(&model.Product{}).Where("id = ?", reservation.ProductID).Updates(map[string]interface{}{
+				"inventory": gorm.Expr("inventory - ?", reservation.Quantity),
+				"reserved":  gorm.Expr("reserved - ?", reservation.Quantity),
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err = tx.Model(&model.Reservation{}).Where("id = ?", purchaseID).
+			Update("status", entity.ReservationConfirmed).Error; err != nil {
+			return err
+		}
+
+		return outbox.Add(tx, reply)
+	}, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+}
 
-	// Rollback each product's inventory
-	for _, idempotency := range idempotencies {
-		var inventory ProductInventory
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&model.Product{}).
-			Where("id = ?", idempotency.ProductID).Select("inventory").
-			First(&inventory).Error; err != nil {
-			tx.Rollback()
+// ReleaseProductInventory gives back the stock of the purchase, whether its reservations are pending or confirmed,
+// and writes the reply to the outbox. It returns the released reservations, none when they were released
+// or expired before.
+func (r *productRepositoryImpl) ReleaseProductInventory(ctx context.Context, purchaseID uint64, reply *outbox.Message) (*[]entity.Reservation, error) {
+	var released []entity.Reservation
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		reservations, err := lockReservations(tx, purchaseID)
+		if err != nil {
 			return err
 		}
 
-		if idempotency.Rollbacked {
-			continue
+		for _, reservation := range reservations {
+			switch reservation.Status {
+			case entity.ReservationReserved:
+				err = tx.Model(&model.Product{}).Where("id = ?", reservation.ProductID).
+					Update("reserved", gorm.Expr("reserved - ?", reservation.Quantity)).Error
+			case entity.ReservationConfirmed:
+				// The stock was taken, the products are restocked
+				err = tx.Model(&model.Product{}).Where("id = ?", reservation.ProductID).
+					Update("inventory", gorm.Expr("inventory + ?", reservation.Quantity)).Error
+			default:
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			released = append(released, *decodeReservation(&reservation))
 		}
 
-		if err := tx.Model(&model.Product{}).Where("id = ?", idempotency.ProductID).
-			Update("inventory", gorm.Expr("inventory + ?", idempotency.Quantity)).Error; err != nil {
-			tx.Rollback()
+		if len(released) > 0 {
+			if err = tx.Model(&model.Reservation{}).
+				Where("id = ? AND status IN ?", purchaseID, []string{entity.ReservationReserved, entity.ReservationConfirmed}).
+				Update("status", entity.ReservationReleased).Error; err != nil {
+				return err
+			}
+		}
+
+		return outbox.Add(tx, reply)
+	}, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return nil, err
+	}
+
+	return &released, nil
+}
+
+// ReleaseExpiredReservations expires up to limit pending reservations whose deadline passed at now,
+// and gives their stock back. The reservations locked by a confirmation or a release are skipped.
+func (r *productRepositoryImpl) ReleaseExpiredReservations(ctx context.Context, now time.Time, limit int) (*[]entity.Reservation, error) {
+	var expired []entity.Reservation
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Ordered by product so that the products are locked in the same order as the other inventory updates
+		var reservations []model.Reservation
+		if err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked}).
+			Where("status = ? AND expires_at <= ?", entity.ReservationReserved, now.UnixMilli()).
+			Order("product_id, id").Limit(limit).
+			Find(&reservations).Error; err != nil {
 			return err
 		}
+		if len(reservations) == 0 {
+			return nil
+		}
+
+		keys := make([][]interface{}, len(reservations))
+		for i, reservation := range reservations {
+			if err := tx.Model(&model.Product{}).Where("id = ?", reservation.ProductID).
+				Update("reserved", gorm.Expr("reserved - ?", reservation.Quantity)).Error; err != nil {
+				return err
+			}
+
+			keys[i] = []interface{}{reservation.ID, reservation.ProductID}
+			expired = append(expired, *decodeReservation(&reservation))
+		}
+
+		return tx.Model(&model.Reservation{}).Where("(id, product_id) IN ?", keys).
+			Update("status", entity.ReservationExpired).Error
+	}, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return nil, err
 	}
 
-	// Mark the idempotency as rollbacked
-	if err := tx.Model(&model.Idempotency{}).Where("id = ?", idempotencyKey).Update("rollbacked", true).Error; err != nil {
-		tx.Rollback()
-		return err
+	return &expired, nil
+}
+
+// lockReservations locks the reservations of a purchase until the end of tx, in the order of the products
+func lockReservations(tx *gorm.DB, purchaseID uint64) ([]model.Reservation, error) {
+	var reservations []model.Reservation
+	if err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).
+		Where("id = ?", purchaseID).Order("product_id").
+		Find(&reservations).Error; err != nil {
+		return nil, err
 	}
+	return reservations, nil
+}
+
+func decodeReservation(reservation *model.Reservation) *entity.Reservation {
+	return &entity.Reservation{
+		ID:        reservation.ID,
+		ProductID: reservation.ProductID,
+		Quantity:  reservation.Quantity,
+		Status:    reservation.Status,
+		ExpiresAt: time.UnixMilli(reservation.ExpiresAt),
+	}
+}
 
-	return tx.Commit().Error
+// available is the stock of a product which is not held by a reservation
+func available(product *model.Product) uint64 {
+	return product.Inventory - product.Reserved
 }
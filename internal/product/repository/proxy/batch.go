@@ -0,0 +1,144 @@
+package proxy
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/pkg/strjoin"
+	"sort"
+	"strconv"
+)
+
+// batchLookup looks the ids up through the same layers as the single lookups, with one round trip per layer
+// for the whole batch: the local cache, the cuckoo filter, redis, then the database for the misses only,
+// holding the mutex of each missing key. The ids unknown to the cuckoo filter or to load are absent from the result.
+func batchLookup[T any](
+	ctx context.Context,
+	r *productRepositoryImpl,
+	cache, prefix string,
+	ids []uint64,
+	load func(ids []uint64) (map[uint64]T, error)) (map[uint64]T, error) {
+
+	result := make(map[uint64]T, len(ids))
+
+	var misses []uint64
+	seen := make(map[uint64]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+
+		var value T
+		ok, err := r.lc.Get(batchKey(prefix, id), &value)
+		if ok && err == nil {
+			cacheLookups.WithLabelValues(cache, lookupLocalHit).Inc()
+			result[id] = value
+			continue
+		}
+		misses = append(misses, id)
+	}
+	if len(misses) == 0 {
+		return result, nil
+	}
+
+	members := make([]interface{}, len(misses))
+	for i, id := range misses {
+		members[i] = id
+	}
+	exist, err := r.rc.CFMExist(ctx, cuckooFilter, members...)
+	r.logger.Error(err)
+	if err == nil {
+		known := misses[:0]
+		for i, id := range misses {
+			if !exist[i] {
+				cacheLookups.WithLabelValues(cache, lookupFiltered).Inc()
+				continue
+			}
+			known = append(known, id)
+		}
+		misses = known
+	}
+
+	misses = redisLookup(ctx, r, cache, prefix, misses, result)
+	if len(misses) == 0 {
+		return result, nil
+	}
+
+	// lock to prevent lost update, in the order of the ids so that concurrent batches do not deadlock
+	sort.Slice(misses, func(i, j int) bool { return misses[i] < misses[j] })
+	for _, id := range misses {
+		mu := r.rc.GetMutex(strjoin.Join(mutexKey, batchKey(prefix, id)))
+		if err = mu.Lock(); err != nil {
+			return nil, err
+		}
+		defer mu.Unlock()
+	}
+
+	// Get again to prevent new update
+	misses = redisLookup(ctx, r, cache, prefix, misses, result)
+	if len(misses) == 0 {
+		return result, nil
+	}
+
+	cacheLookups.WithLabelValues(cache, lookupMiss).Add(float64(len(misses)))
+	loaded, err := load(misses)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(loaded))
+	values := make([]interface{}, 0, len(loaded))
+	for id, value := range loaded {
+		key := batchKey(prefix, id)
+		if err = r.lc.Set(key, value); err != nil {
+			r.logger.Error("Product: failed to set batch lookup to local cache", err)
+		}
+
+		result[id] = value
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	if len(keys) > 0 {
+		r.logger.Error(r.rc.SetMany(ctx, keys, values))
+	}
+
+	return result, nil
+}
+
+// redisLookup adds the ids found in redis to result and returns the missing ones
+func redisLookup[T any](ctx context.Context, r *productRepositoryImpl, cache, prefix string, ids []uint64, result map[uint64]T) []uint64 {
+	if len(ids) == 0 {
+		return ids
+	}
+
+	keys := make([]string, len(ids))
+	found := make([]T, len(ids))
+	values := make([]interface{}, len(ids))
+	for i, id := range ids {
+		keys[i] = batchKey(prefix, id)
+		values[i] = &found[i]
+	}
+
+	ok, err := r.rc.GetMany(ctx, keys, values)
+	r.logger.Error(err)
+	if err != nil {
+		return ids
+	}
+
+	var misses []uint64
+	for i, id := range ids {
+		if !ok[i] {
+			misses = append(misses, id)
+			continue
+		}
+
+		cacheLookups.WithLabelValues(cache, lookupRedisHit).Inc()
+		r.logger.Error(r.lc.Set(keys[i], found[i]))
+		result[id] = found[i]
+	}
+
+	return misses
+}
+
+func batchKey(prefix string, id uint64) string {
+	return strjoin.Join(prefix, strconv.FormatUint(id, 10))
+}
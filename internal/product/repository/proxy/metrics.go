@@ -0,0 +1,20 @@
+package proxy
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	lookupLocalHit = "local"
+	lookupRedisHit = "redis"
+	lookupFiltered = "filtered"
+	lookupMiss     = "miss"
+)
+
+// cacheLookups counts the product lookups by where they were answered: the local cache, redis,
+// the cuckoo filter for unknown products, or the database on a miss
+var cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "product_cache_lookups_total",
+	Help: "Product cache lookups by cache and result (local, redis, filtered or miss).",
+}, []string{"cache", "result"})
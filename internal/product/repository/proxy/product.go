@@ -3,6 +3,7 @@ package proxy
 import (
 	"context"
 	"github.com/scul0405/saga-orchestration/internal/pkg/cache"
+	"github.com/scul0405/saga-orchestration/internal/pkg/outbox"
 	"github.com/scul0405/saga-orchestration/internal/product/domain"
 	"github.com/scul0405/saga-orchestration/internal/product/domain/entity"
 	"github.com/scul0405/saga-orchestration/internal/product/domain/valueobject"
@@ -11,6 +12,7 @@ import (
 	"github.com/scul0405/saga-orchestration/pkg/strjoin"
 	"gorm.io/gorm"
 	"strconv"
+	"time"
 )
 
 type productRepositoryImpl struct {
@@ -60,12 +62,14 @@ func (r *productRepositoryImpl) CheckProduct(ctx context.Context, productID uint
 	key := strjoin.Join(checkProductKey, strconv.FormatUint(productID, 10))
 	ok, err := r.lc.Get(key, status)
 	if ok && err == nil {
+		cacheLookups.WithLabelValues("check_product", lookupLocalHit).Inc()
 		return status, nil
 	}
 
 	exist, err := r.rc.CFExist(ctx, cuckooFilter, productID)
 	r.logger.Error(err)
 	if !exist && err == nil {
+		cacheLookups.WithLabelValues("check_product", lookupFiltered).Inc()
 		return &valueobject.ProductStatus{
 			ID:     productID,
 			Price:  0,
@@ -76,6 +80,7 @@ func (r *productRepositoryImpl) CheckProduct(ctx context.Context, productID uint
 	ok, err = r.rc.Get(ctx, key, status)
 	r.logger.Error(err)
 	if ok && err == nil {
+		cacheLookups.WithLabelValues("check_product", lookupRedisHit).Inc()
 		r.logger.Error(r.lc.Set(key, status))
 		return status, nil
 	}
@@ -91,10 +96,12 @@ func (r *productRepositoryImpl) CheckProduct(ctx context.Context, productID uint
 	ok, err = r.rc.Get(ctx, key, status)
 	r.logger.Error(err)
 	if ok && err == nil {
+		cacheLookups.WithLabelValues("check_product", lookupRedisHit).Inc()
 		r.logger.Error(r.lc.Set(key, status))
 		return status, nil
 	}
 
+	cacheLookups.WithLabelValues("check_product", lookupMiss).Inc()
 	status, err = r.pgRepo.CheckProduct(ctx, productID, quantity)
 	if err != nil {
 		return nil, err
@@ -108,23 +115,71 @@ func (r *productRepositoryImpl) CheckProduct(ctx context.Context, productID uint
 	return status, nil
 }
 
+// CheckProducts checks the purchased products in batch, the statuses follow the order of purchasedProducts.
+// As with CheckProduct, the cached status of a product is shared by every quantity.
+// A product listed several times is checked with its total quantity.
+func (r *productRepositoryImpl) CheckProducts(ctx context.Context, purchasedProducts *[]valueobject.PurchasedProduct) (*[]valueobject.ProductStatus, error) {
+	productIDs := make([]uint64, len(*purchasedProducts))
+	quantities := make(map[uint64]uint64, len(*purchasedProducts))
+	for i, p := range *purchasedProducts {
+		productIDs[i] = p.ID
+		quantities[p.ID] += p.Quantity
+	}
+
+	found, err := batchLookup(ctx, r, "check_product", checkProductKey, productIDs, func(ids []uint64) (map[uint64]valueobject.ProductStatus, error) {
+		misses := make([]valueobject.PurchasedProduct, len(ids))
+		for i, id := range ids {
+			misses[i] = valueobject.PurchasedProduct{ID: id, Quantity: quantities[id]}
+		}
+
+		statuses, err := r.pgRepo.CheckProducts(ctx, &misses)
+		if err != nil {
+			return nil, err
+		}
+
+		loaded := make(map[uint64]valueobject.ProductStatus, len(*statuses))
+		for _, status := range *statuses {
+			loaded[status.ID] = status
+		}
+		return loaded, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]valueobject.ProductStatus, len(productIDs))
+	for i, id := range productIDs {
+		status, ok := found[id]
+		if !ok {
+			// The product is unknown to the cuckoo filter
+			status = valueobject.ProductStatus{ID: id}
+		}
+		statuses[i] = status
+	}
+
+	return &statuses, nil
+}
+
 func (r *productRepositoryImpl) GetProductDetail(ctx context.Context, productID uint64) (*valueobject.ProductDetail, error) {
 	prodDetail := &valueobject.ProductDetail{}
 	key := strjoin.Join(getProductDetailKey, strconv.FormatUint(productID, 10))
 	ok, err := r.lc.Get(key, prodDetail)
 	if ok && err == nil {
+		cacheLookups.WithLabelValues("get_product_detail", lookupLocalHit).Inc()
 		return prodDetail, nil
 	}
 
 	exist, err := r.rc.CFExist(ctx, cuckooFilter, productID)
 	r.logger.Error(err)
 	if !exist && err == nil {
+		cacheLookups.WithLabelValues("get_product_detail", lookupFiltered).Inc()
 		return nil, gorm.ErrRecordNotFound
 	}
 
 	ok, err = r.rc.Get(ctx, key, prodDetail)
 	r.logger.Error(err)
 	if ok && err == nil {
+		cacheLookups.WithLabelValues("get_product_detail", lookupRedisHit).Inc()
 		r.logger.Error(r.lc.Set(key, prodDetail))
 		return prodDetail, nil
 	}
@@ -140,10 +195,12 @@ func (r *productRepositoryImpl) GetProductDetail(ctx context.Context, productID
 	ok, err = r.rc.Get(ctx, key, prodDetail)
 	r.logger.Error(err)
 	if ok && err == nil {
+		cacheLookups.WithLabelValues("get_product_detail", lookupRedisHit).Inc()
 		r.logger.Error(r.lc.Set(key, prodDetail))
 		return prodDetail, nil
 	}
 
+	cacheLookups.WithLabelValues("get_product_detail", lookupMiss).Inc()
 	prodDetail, err = r.pgRepo.GetProductDetail(ctx, productID)
 	if err != nil {
 		return nil, err
@@ -162,18 +219,21 @@ func (r *productRepositoryImpl) GetProductInventory(ctx context.Context, product
 	key := strjoin.Join(getProductInventoryKey, strconv.FormatUint(productID, 10))
 	ok, err := r.lc.Get(key, &prodInventory)
 	if ok && err == nil {
+		cacheLookups.WithLabelValues("get_product_inventory", lookupLocalHit).Inc()
 		return prodInventory, nil
 	}
 
 	exist, err := r.rc.CFExist(ctx, cuckooFilter, productID)
 	r.logger.Error(err)
 	if !exist && err == nil {
+		cacheLookups.WithLabelValues("get_product_inventory", lookupFiltered).Inc()
 		return 0, gorm.ErrRecordNotFound
 	}
 
 	ok, err = r.rc.Get(ctx, key, prodInventory)
 	r.logger.Error(err)
 	if ok && err == nil {
+		cacheLookups.WithLabelValues("get_product_inventory", lookupRedisHit).Inc()
 		r.logger.Error(r.lc.Set(key, prodInventory))
 		return prodInventory, nil
 	}
@@ -189,10 +249,12 @@ func (r *productRepositoryImpl) GetProductInventory(ctx context.Context, product
 	ok, err = r.rc.Get(ctx, key, prodInventory)
 	r.logger.Error(err)
 	if ok && err == nil {
+		cacheLookups.WithLabelValues("get_product_inventory", lookupRedisHit).Inc()
 		r.logger.Error(r.lc.Set(key, prodInventory))
 		return prodInventory, nil
 	}
 
+	cacheLookups.WithLabelValues("get_product_inventory", lookupMiss).Inc()
 	prodInventory, err = r.pgRepo.GetProductInventory(ctx, productID)
 	if err != nil {
 		return 0, err
@@ -211,18 +273,21 @@ func (r *productRepositoryImpl) GetProduct(ctx context.Context, productID uint64
 	key := strjoin.Join(getProductKey, strconv.FormatUint(productID, 10))
 	ok, err := r.lc.Get(key, product)
 	if ok && err == nil {
+		cacheLookups.WithLabelValues("get_product", lookupLocalHit).Inc()
 		return product, nil
 	}
 
 	exist, err := r.rc.CFExist(ctx, cuckooFilter, productID)
 	r.logger.Error(err)
 	if !exist && err == nil {
+		cacheLookups.WithLabelValues("get_product", lookupFiltered).Inc()
 		return nil, gorm.ErrRecordNotFound
 	}
 
 	ok, err = r.rc.Get(ctx, key, product)
 	r.logger.Error(err)
 	if ok && err == nil {
+		cacheLookups.WithLabelValues("get_product", lookupRedisHit).Inc()
 		r.logger.Error(r.lc.Set(key, product))
 		return product, nil
 	}
@@ -238,10 +303,12 @@ func (r *productRepositoryImpl) GetProduct(ctx context.Context, productID uint64
 	ok, err = r.rc.Get(ctx, key, product)
 	r.logger.Error(err)
 	if ok && err == nil {
+		cacheLookups.WithLabelValues("get_product", lookupRedisHit).Inc()
 		r.logger.Error(r.lc.Set(key, product))
 		return product, nil
 	}
 
+	cacheLookups.WithLabelValues("get_product", lookupMiss).Inc()
 	product, err = r.pgRepo.GetProduct(ctx, productID)
 	if err != nil {
 		return nil, err
@@ -255,8 +322,43 @@ func (r *productRepositoryImpl) GetProduct(ctx context.Context, productID uint64
 	return product, nil
 }
 
-func (r *productRepositoryImpl) ListProducts(ctx context.Context, limit, offset uint64) (*[]valueobject.ProductCatalog, error) {
-	return r.pgRepo.ListProducts(ctx, limit, offset)
+// GetProducts returns the products in batch in the order of productIDs,
+// or gorm.ErrRecordNotFound when one of them does not exist
+func (r *productRepositoryImpl) GetProducts(ctx context.Context, productIDs *[]uint64) (*[]entity.Product, error) {
+	found, err := batchLookup(ctx, r, "get_product", getProductKey, *productIDs, func(ids []uint64) (map[uint64]entity.Product, error) {
+		products, err := r.pgRepo.GetProducts(ctx, &ids)
+		if err != nil {
+			return nil, err
+		}
+
+		loaded := make(map[uint64]entity.Product, len(*products))
+		for _, product := range *products {
+			loaded[product.ID] = product
+		}
+		return loaded, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]entity.Product, len(*productIDs))
+	for i, id := range *productIDs {
+		product, ok := found[id]
+		if !ok {
+			return nil, gorm.ErrRecordNotFound
+		}
+		products[i] = product
+	}
+
+	return &products, nil
+}
+
+func (r *productRepositoryImpl) ListProducts(ctx context.Context, filter *valueobject.ProductFilter) (*[]valueobject.ProductCatalog, error) {
+	return r.pgRepo.ListProducts(ctx, filter)
+}
+
+func (r *productRepositoryImpl) SearchProducts(ctx context.Context, search *valueobject.ProductSearch) (*[]valueobject.ProductSearchResult, error) {
+	return r.pgRepo.SearchProducts(ctx, search)
 }
 
 func (r *productRepositoryImpl) CreateProduct(ctx context.Context, product *entity.Product) error {
@@ -282,8 +384,8 @@ func (r *productRepositoryImpl) UpdateProductDetail(ctx context.Context, product
 	return nil
 }
 
-func (r *productRepositoryImpl) UpdateProductInventory(ctx context.Context, idempotencyKey uint64, purchasedProducts *[]valueobject.PurchasedProduct) error {
-	err := r.pgRepo.UpdateProductInventory(ctx, idempotencyKey, purchasedProducts)
+func (r *productRepositoryImpl) ReserveProductInventory(ctx context.Context, purchaseID uint64, purchasedProducts *[]valueobject.PurchasedProduct, expiresAt time.Time, reply *outbox.Message) error {
+	err := r.pgRepo.ReserveProductInventory(ctx, purchaseID, purchasedProducts, expiresAt, reply)
 	if err != nil {
 		return err
 	}
@@ -302,22 +404,41 @@ func (r *productRepositoryImpl) UpdateProductInventory(ctx context.Context, idem
 	return nil
 }
 
-func (r *productRepositoryImpl) RollbackProductInventory(ctx context.Context, idempotencyKey uint64, purchasedProducts *[]valueobject.PurchasedProduct) error {
-	err := r.pgRepo.RollbackProductInventory(ctx, idempotencyKey, purchasedProducts)
+// ConfirmProductInventory leaves the cached inventories as they are, the reserved stock was not available already
+func (r *productRepositoryImpl) ConfirmProductInventory(ctx context.Context, purchaseID uint64, reply *outbox.Message) error {
+	return r.pgRepo.ConfirmProductInventory(ctx, purchaseID, reply)
+}
+
+func (r *productRepositoryImpl) ReleaseProductInventory(ctx context.Context, purchaseID uint64, reply *outbox.Message) (*[]entity.Reservation, error) {
+	released, err := r.pgRepo.ReleaseProductInventory(ctx, purchaseID, reply)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	payloads := make([]cache.RedisIncrbyXPayload, len(*purchasedProducts))
-	for i, purchasedProduct := range *purchasedProducts {
+	r.restock(ctx, released)
+	return released, nil
+}
+
+func (r *productRepositoryImpl) ReleaseExpiredReservations(ctx context.Context, now time.Time, limit int) (*[]entity.Reservation, error) {
+	expired, err := r.pgRepo.ReleaseExpiredReservations(ctx, now, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	r.restock(ctx, expired)
+	return expired, nil
+}
+
+// restock adds the stock given back by the reservations to the cached inventories
+func (r *productRepositoryImpl) restock(ctx context.Context, reservations *[]entity.Reservation) {
+	payloads := make([]cache.RedisIncrbyXPayload, len(*reservations))
+	for i, reservation := range *reservations {
 		payloads[i] = cache.RedisIncrbyXPayload{
-			Key:   strjoin.Join(getProductInventoryKey, strconv.FormatUint(purchasedProduct.ID, 10)),
-			Value: int64(purchasedProduct.Quantity),
+			Key:   strjoin.Join(getProductInventoryKey, strconv.FormatUint(reservation.ProductID, 10)),
+			Value: int64(reservation.Quantity),
 		}
 	}
 	if len(payloads) > 0 {
 		r.logger.Error(r.rc.ExecIncrbyXPipeline(ctx, &payloads))
 	}
-
-	return nil
 }
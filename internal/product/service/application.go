@@ -7,20 +7,25 @@ import (
 	"github.com/scul0405/saga-orchestration/internal/product/domain"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
 	"github.com/scul0405/saga-orchestration/pkg/sonyflake"
+	"time"
 )
 
-func NewProductService(sf sonyflake.IDGenerator, logger logger.Logger, productRepo domain.ProductRepository) app.ProductApplication {
+// NewProductService builds the product application, the inventory is reserved for reservationTTL
+func NewProductService(sf sonyflake.IDGenerator, logger logger.Logger, productRepo domain.ProductRepository, reservationTTL time.Duration) app.ProductApplication {
 	return app.ProductApplication{
 		Commands: app.ProductCommands{
-			CreateProduct:            command.NewCreateProductHandler(sf, logger, productRepo),
-			UpdateProductDetail:      command.NewUpdateProductDetailHandler(logger, productRepo),
-			UpdateProductInventory:   command.NewUpdateProductInventoryHandler(logger, productRepo),
-			RollbackProductInventory: command.NewRollbackProductInventoryHandler(logger, productRepo),
+			CreateProduct:              command.NewCreateProductHandler(sf, logger, productRepo),
+			UpdateProductDetail:        command.NewUpdateProductDetailHandler(logger, productRepo),
+			ReserveProductInventory:    command.NewReserveProductInventoryHandler(logger, productRepo, reservationTTL),
+			ConfirmProductInventory:    command.NewConfirmProductInventoryHandler(logger, productRepo),
+			ReleaseProductInventory:    command.NewReleaseProductInventoryHandler(logger, productRepo),
+			ReleaseExpiredReservations: command.NewReleaseExpiredReservationsHandler(logger, productRepo),
 		},
 		Queries: app.ProductQueries{
-			CheckProducts: query.NewCheckProductsHandler(logger, productRepo),
-			GetProducts:   query.NewGetProductsHandler(logger, productRepo),
-			ListProducts:  query.NewListProductsHandler(logger, productRepo),
+			CheckProducts:  query.NewCheckProductsHandler(logger, productRepo),
+			GetProducts:    query.NewGetProductsHandler(logger, productRepo),
+			ListProducts:   query.NewListProductsHandler(logger, productRepo),
+			SearchProducts: query.NewSearchProductsHandler(logger, productRepo),
 		},
 	}
 }
@@ -29,6 +34,12 @@ func NewCategoryService(sf sonyflake.IDGenerator, logger logger.Logger, category
 	return app.CategoryApplication{
 		Commands: app.CategoryCommands{
 			CreateCategory: command.NewCreateCategoryHandler(sf, logger, categoryRepo),
+			UpdateCategory: command.NewUpdateCategoryHandler(logger, categoryRepo),
+			DeleteCategory: command.NewDeleteCategoryHandler(logger, categoryRepo),
+		},
+		Queries: app.CategoryQueries{
+			GetCategory:    query.NewGetCategoryHandler(logger, categoryRepo),
+			ListCategories: query.NewListCategoriesHandler(logger, categoryRepo),
 		},
 	}
 }
\ No newline at end of file
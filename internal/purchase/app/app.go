@@ -11,9 +11,12 @@ type Application struct {
 }
 
 type Commands struct {
-	CreatePurchase command.CreatePurchaseHandler
+	CreatePurchase       command.CreatePurchaseHandler
+	RecordPurchaseResult command.RecordPurchaseResultHandler
 }
 
 type Queries struct {
 	CheckProducts query.CheckProductsHandler
+	GetPurchase   query.GetPurchaseHandler
+	ListPurchases query.ListPurchasesHandler
 }
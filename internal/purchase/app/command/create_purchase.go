@@ -2,7 +2,12 @@ package command
 
 import (
 	"context"
+	"fmt"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/aggregate"
 	"github.com/scul0405/saga-orchestration/internal/purchase/domain/entity"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/event"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/valueobject"
 	"github.com/scul0405/saga-orchestration/internal/purchase/eventhandler"
 	"github.com/scul0405/saga-orchestration/internal/purchase/infrastructure/grpc"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
@@ -33,39 +38,62 @@ type Payment struct {
 	Amount       uint64
 }
 
-type CreatePurchaseHandler CommandHandler[CreatePurchase]
+// CreatePurchaseHandler returns the ID of the created purchase
+type CreatePurchaseHandler CommandHandlerWithResult[CreatePurchase, uint64]
 
 type createPurchaseHandler struct {
-	sf         sonyflake.IDGenerator
-	logger     logger.Logger
-	productSvc grpc.ProductService
-	evPub      eventhandler.PurchaseEventHandler
+	sf           sonyflake.IDGenerator
+	logger       logger.Logger
+	productSvc   grpc.ProductService
+	purchaseRepo domain.PurchaseRepository
+	evPub        eventhandler.PurchaseEventHandler
 }
 
 func NewCreatePurchaseHandler(
 	sf sonyflake.IDGenerator, logger logger.Logger,
 	productSvc grpc.ProductService,
+	purchaseRepo domain.PurchaseRepository,
 	evPub eventhandler.PurchaseEventHandler) CreatePurchaseHandler {
 	return &createPurchaseHandler{
-		sf:         sf,
-		logger:     logger,
-		productSvc: productSvc,
-		evPub:      evPub,
+		sf:           sf,
+		logger:       logger,
+		productSvc:   productSvc,
+		purchaseRepo: purchaseRepo,
+		evPub:        evPub,
 	}
 }
 
-func (h *createPurchaseHandler) Handle(ctx context.Context, cmd CreatePurchase) error {
+func (h *createPurchaseHandler) Handle(ctx context.Context, cmd CreatePurchase) (uint64, error) {
 	orderItems := make([]entity.OrderItem, len(*cmd.Order.OrderItems))
 	for i, item := range *cmd.Order.OrderItems {
 		orderItems[i] = entity.OrderItem{
 			ID:       item.ID,
 			Quantity: item.Quantity,
+			Price:    item.Price,
 		}
 	}
 
 	purchaseID, err := h.sf.NextID()
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	err = h.purchaseRepo.CreatePurchase(ctx, &aggregate.Purchase{
+		ID: purchaseID,
+		Order: &entity.Order{
+			CustomerID: cmd.Order.CustomerID,
+			OrderItems: &orderItems,
+		},
+		Payment: &valueobject.Payment{
+			CurrencyCode: cmd.Payment.CurrencyCode,
+			Amount:       cmd.Payment.Amount,
+		},
+		Status: &valueobject.PurchaseStatus{
+			Status: event.StatusPending,
+		},
+	})
+	if err != nil {
+		return 0, err
 	}
 
 	pbPurchaseOrderItem := make([]*pb.PurchaseOrderItem, len(*cmd.Order.OrderItems))
@@ -73,6 +101,7 @@ func (h *createPurchaseHandler) Handle(ctx context.Context, cmd CreatePurchase)
 		pbPurchaseOrderItem[i] = &pb.PurchaseOrderItem{
 			ProductId: item.ID,
 			Quantity:  item.Quantity,
+			Price:     item.Price,
 		}
 	}
 
@@ -91,5 +120,19 @@ func (h *createPurchaseHandler) Handle(ctx context.Context, cmd CreatePurchase)
 		Timestamp: timestamppb.New(time.Now()),
 	}
 
-	return h.evPub.ProduceCreatePurchase(ctx, purchase)
+	if err = h.evPub.ProduceCreatePurchase(ctx, purchase); err != nil {
+		// The saga never started, close the purchase so it does not stay pending
+		_, resultErr := h.purchaseRepo.AddPurchaseResult(ctx, &event.PurchaseResult{
+			PurchaseID: purchaseID,
+			Status:     event.StatusFailed,
+			Reason:     fmt.Sprintf("publish purchase: %v", err),
+			Timestamp:  time.Now(),
+		})
+		if resultErr != nil {
+			h.logger.Errorf("CreatePurchase: AddPurchaseResult", resultErr)
+		}
+		return 0, err
+	}
+
+	return purchaseID, nil
 }
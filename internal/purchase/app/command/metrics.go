@@ -0,0 +1,14 @@
+package command
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// stepDuration measures, from the timestamps of the purchase results, the time a purchase took
+// to reach each step and status since its previous result, or since its creation for the first one
+var stepDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "purchase_step_duration_seconds",
+	Help:    "Time between a purchase result and the previous result of the purchase, by the step and status reached.",
+	Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+}, []string{"step", "status"})
@@ -0,0 +1,51 @@
+package command
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/event"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"time"
+)
+
+type RecordPurchaseResult struct {
+	PurchaseID uint64
+	Step       string
+	Status     string
+	Reason     string
+	Timestamp  time.Time
+}
+
+type RecordPurchaseResultHandler CommandHandler[RecordPurchaseResult]
+
+type recordPurchaseResultHandler struct {
+	logger       logger.Logger
+	purchaseRepo domain.PurchaseRepository
+}
+
+func NewRecordPurchaseResultHandler(logger logger.Logger, purchaseRepo domain.PurchaseRepository) RecordPurchaseResultHandler {
+	return &recordPurchaseResultHandler{
+		logger:       logger,
+		purchaseRepo: purchaseRepo,
+	}
+}
+
+func (h *recordPurchaseResultHandler) Handle(ctx context.Context, cmd RecordPurchaseResult) error {
+	previous, err := h.purchaseRepo.AddPurchaseResult(ctx, &event.PurchaseResult{
+		PurchaseID: cmd.PurchaseID,
+		Step:       cmd.Step,
+		Status:     cmd.Status,
+		Reason:     cmd.Reason,
+		Timestamp:  cmd.Timestamp,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Redelivered and out of order results are not measured
+	if !previous.IsZero() {
+		stepDuration.WithLabelValues(cmd.Step, cmd.Status).Observe(cmd.Timestamp.Sub(previous).Seconds())
+	}
+
+	return nil
+}
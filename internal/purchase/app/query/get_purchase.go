@@ -0,0 +1,42 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/aggregate"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"gorm.io/gorm"
+)
+
+var ErrPurchaseNotFound = errors.New("purchase not found")
+
+type GetPurchase struct {
+	PurchaseID uint64
+}
+
+type GetPurchaseHandler QueryHandler[GetPurchase, *aggregate.Purchase]
+
+type getPurchaseHandler struct {
+	logger       logger.Logger
+	purchaseRepo domain.PurchaseRepository
+}
+
+func NewGetPurchaseHandler(logger logger.Logger, purchaseRepo domain.PurchaseRepository) GetPurchaseHandler {
+	return &getPurchaseHandler{
+		logger:       logger,
+		purchaseRepo: purchaseRepo,
+	}
+}
+
+func (h *getPurchaseHandler) Handle(ctx context.Context, query GetPurchase) (*aggregate.Purchase, error) {
+	purchase, err := h.purchaseRepo.GetPurchase(ctx, query.PurchaseID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPurchaseNotFound
+		}
+		return nil, err
+	}
+
+	return purchase, nil
+}
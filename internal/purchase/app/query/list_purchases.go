@@ -0,0 +1,32 @@
+package query
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/aggregate"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+)
+
+type ListPurchases struct {
+	CustomerID uint64
+	Limit      int
+	Offset     int
+}
+
+type ListPurchasesHandler QueryHandler[ListPurchases, *[]aggregate.Purchase]
+
+type listPurchasesHandler struct {
+	logger       logger.Logger
+	purchaseRepo domain.PurchaseRepository
+}
+
+func NewListPurchasesHandler(logger logger.Logger, purchaseRepo domain.PurchaseRepository) ListPurchasesHandler {
+	return &listPurchasesHandler{
+		logger:       logger,
+		purchaseRepo: purchaseRepo,
+	}
+}
+
+func (h *listPurchasesHandler) Handle(ctx context.Context, query ListPurchases) (*[]aggregate.Purchase, error) {
+	return h.purchaseRepo.ListPurchases(ctx, query.CustomerID, query.Limit, query.Offset)
+}
@@ -3,6 +3,7 @@ package aggregate
 import (
 	"github.com/scul0405/saga-orchestration/internal/purchase/domain/entity"
 	"github.com/scul0405/saga-orchestration/internal/purchase/domain/valueobject"
+	"time"
 )
 
 // Purchase aggregate
@@ -10,4 +11,9 @@ type Purchase struct {
 	ID      uint64
 	Order   *entity.Order
 	Payment *valueobject.Payment
+	// Status is the latest step result of the purchase saga
+	Status *valueobject.PurchaseStatus
+	// Timeline is the step by step history of the purchase saga, it is only loaded for a single purchase
+	Timeline  *[]valueobject.PurchaseStatus
+	CreatedAt time.Time
 }
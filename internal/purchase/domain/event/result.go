@@ -3,15 +3,21 @@ package event
 import "time"
 
 var (
-	StepUpdateProductInventory = "UPDATE_PRODUCT_INVENTORY"
-	StepCreateOrder            = "CREATE_ORDER"
-	StepCreatePayment          = "CREATE_PAYMENT"
+	StepUpdateProductInventory  = "UPDATE_PRODUCT_INVENTORY"
+	StepCreateOrder             = "CREATE_ORDER"
+	StepCreatePayment           = "CREATE_PAYMENT"
+	StepConfirmProductInventory = "CONFIRM_PRODUCT_INVENTORY"
+	StepConfirmOrder            = "CONFIRM_ORDER"
 
-	StatusExecute        = "EXUCUTE"
+	// StatusPending is the status of a purchase until the orchestrator publishes its first result
+	StatusPending        = "PENDING"
+	StatusExecute        = "EXECUTE"
 	StatusSucess         = "SUCCESS"
 	StatusFailed         = "FAILED"
-	StatusRollbacked     = "ROLLBACKED"
-	StatusRollbackFailed = "ROLLBACK_FAIL"
+	StatusRollback       = "ROLLBACK"
+	StatusRollbackFailed = "ROLLBACK_FAILED"
+	StatusCompensated    = "COMPENSATED"
+	StatusResolved       = "RESOLVED"
 )
 
 // PurchaseResult event
@@ -19,5 +25,20 @@ type PurchaseResult struct {
 	PurchaseID uint64
 	Step       string
 	Status     string
+	Reason     string
 	Timestamp  time.Time
 }
+
+// IsFinal reports whether no result follows this one: the last step succeeded,
+// every compensation succeeded, a compensation failed, an operator resolved the saga, or the saga never started.
+func (r *PurchaseResult) IsFinal() bool {
+	switch r.Status {
+	case StatusSucess:
+		return r.Step == StepConfirmOrder
+	case StatusFailed:
+		return r.Step == ""
+	case StatusRollbackFailed, StatusCompensated, StatusResolved:
+		return true
+	}
+	return false
+}
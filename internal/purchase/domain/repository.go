@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/aggregate"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/event"
+	"time"
+)
+
+type PurchaseRepository interface {
+	GetPurchase(ctx context.Context, id uint64) (*aggregate.Purchase, error)
+	ListPurchases(ctx context.Context, customerID uint64, limit, offset int) (*[]aggregate.Purchase, error)
+	CreatePurchase(ctx context.Context, purchase *aggregate.Purchase) error
+	AddPurchaseResult(ctx context.Context, result *event.PurchaseResult) (time.Time, error)
+}
@@ -0,0 +1,11 @@
+package valueobject
+
+import "time"
+
+// PurchaseStatus value object
+type PurchaseStatus struct {
+	Step      string
+	Status    string
+	Reason    string
+	Timestamp time.Time
+}
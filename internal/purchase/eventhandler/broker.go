@@ -0,0 +1,87 @@
+package eventhandler
+
+import (
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/event"
+	"sync"
+)
+
+// subscriptionBuffer is the number of results kept for a slow subscriber before it is closed
+const subscriptionBuffer = 16
+
+// PurchaseResultBroker fans the purchase results consumed from Kafka out to the streams watching a purchase
+type PurchaseResultBroker interface {
+	Publish(result *event.PurchaseResult)
+	// Subscribe returns the results published for the purchase from now on,
+	// the returned function must be called to release the subscription.
+	// The channel is closed when the subscriber does not keep up, some results are then missing from it.
+	Subscribe(purchaseID uint64) (<-chan *event.PurchaseResult, func())
+	// CloseAll closes every subscription, it is called when some results may not have been published
+	CloseAll()
+}
+
+type purchaseResultBroker struct {
+	mu          sync.Mutex
+	subscribers map[uint64]map[chan *event.PurchaseResult]struct{}
+}
+
+func NewPurchaseResultBroker() PurchaseResultBroker {
+	return &purchaseResultBroker{
+		subscribers: make(map[uint64]map[chan *event.PurchaseResult]struct{}),
+	}
+}
+
+func (b *purchaseResultBroker) Publish(result *event.PurchaseResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[result.PurchaseID] {
+		select {
+		case ch <- result:
+		default:
+			// The stream is not keeping up, it is closed rather than missing a result silently:
+			// the client reconnects and replays the purchase timeline from the database
+			b.remove(result.PurchaseID, ch)
+			close(ch)
+		}
+	}
+}
+
+func (b *purchaseResultBroker) Subscribe(purchaseID uint64) (<-chan *event.PurchaseResult, func()) {
+	ch := make(chan *event.PurchaseResult, subscriptionBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[purchaseID] == nil {
+		b.subscribers[purchaseID] = make(map[chan *event.PurchaseResult]struct{})
+	}
+	b.subscribers[purchaseID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			b.remove(purchaseID, ch)
+		})
+	}
+}
+
+func (b *purchaseResultBroker) CloseAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, subscribers := range b.subscribers {
+		for ch := range subscribers {
+			close(ch)
+		}
+	}
+	b.subscribers = make(map[uint64]map[chan *event.PurchaseResult]struct{})
+}
+
+func (b *purchaseResultBroker) remove(purchaseID uint64, ch chan *event.PurchaseResult) {
+	delete(b.subscribers[purchaseID], ch)
+	if len(b.subscribers[purchaseID]) == 0 {
+		delete(b.subscribers, purchaseID)
+	}
+}
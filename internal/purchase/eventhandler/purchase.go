@@ -2,7 +2,6 @@ package eventhandler
 
 import (
 	"context"
-	"encoding/json"
 	"github.com/scul0405/saga-orchestration/internal/common"
 	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
 	pb "github.com/scul0405/saga-orchestration/proto"
@@ -24,11 +23,16 @@ func NewPurchaseEventHandler(producer kafkaClient.Producer) PurchaseEventHandler
 }
 
 func (h *purchaseEventHandler) ProduceCreatePurchase(ctx context.Context, purchase *pb.CreatePurchaseRequest) error {
-	purchaseByte, _ := json.Marshal(purchase)
+	purchaseByte, contentType, err := kafkaClient.Encode(kafkaClient.ContentTypeProtobuf, purchase)
+	if err != nil {
+		return err
+	}
+
 	msg := kafka.Message{
-		Topic: common.PurchaseTopic,
-		Key:   []byte("create_purchase"),
-		Value: purchaseByte,
+		Topic:   common.PurchaseTopic,
+		Key:     common.PurchaseKey(purchase.PurchaseId),
+		Value:   purchaseByte,
+		Headers: []kafka.Header{contentType},
 	}
 
 	return h.producer.PublishMessage(ctx, msg)
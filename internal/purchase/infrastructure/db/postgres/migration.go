@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"github.com/scul0405/saga-orchestration/cmd/purchase/config"
+	"github.com/scul0405/saga-orchestration/internal/purchase/infrastructure/db/postgres/model"
+	"gorm.io/gorm"
+)
+
+type Migrator struct {
+	db *gorm.DB
+}
+
+func NewMigrator(db *gorm.DB) *Migrator {
+	return &Migrator{db: db}
+}
+
+func (m *Migrator) Migrate(migration config.Migration) error {
+	if !migration.Enable {
+		return nil
+	}
+
+	if migration.Recreate {
+		if err := m.db.Migrator().DropTable(&model.Purchase{}, &model.PurchaseStep{}); err != nil {
+			return err
+		}
+	}
+
+	return m.db.AutoMigrate(&model.Purchase{}, &model.PurchaseStep{})
+}
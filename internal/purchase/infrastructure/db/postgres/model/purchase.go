@@ -0,0 +1,26 @@
+package model
+
+type Purchase struct {
+	ID           uint64 `gorm:"primaryKey"`
+	CustomerID   uint64 `gorm:"not null;index"`
+	OrderItems   []byte `gorm:"type:jsonb;not null"`
+	CurrencyCode string `gorm:"not null"`
+	Amount       uint64 `gorm:"not null"`
+	Step         string
+	Status       string `gorm:"not null"`
+	Reason       string
+	ResultAt     int64 `gorm:"not null;default:0"`
+	UpdatedAt    int64 `gorm:"autoUpdateTime:milli"`
+	CreatedAt    int64 `gorm:"autoCreateTime:milli;index"`
+}
+
+// PurchaseStep is an entry of the purchase timeline, one per purchase result
+type PurchaseStep struct {
+	ID         uint64 `gorm:"primaryKey;autoIncrement"`
+	PurchaseID uint64 `gorm:"not null;uniqueIndex:idx_purchase_step_result"`
+	Step       string `gorm:"not null;uniqueIndex:idx_purchase_step_result"`
+	Status     string `gorm:"not null;uniqueIndex:idx_purchase_step_result"`
+	Reason     string
+	Timestamp  int64 `gorm:"not null;uniqueIndex:idx_purchase_step_result"`
+	CreatedAt  int64 `gorm:"autoCreateTime:milli"`
+}
@@ -0,0 +1,173 @@
+package eventconsumer
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/cmd/purchase/config"
+	"github.com/scul0405/saga-orchestration/internal/common"
+	"github.com/scul0405/saga-orchestration/internal/purchase/app"
+	"github.com/scul0405/saga-orchestration/internal/purchase/app/command"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/event"
+	"github.com/scul0405/saga-orchestration/internal/purchase/eventhandler"
+	kafkaClient "github.com/scul0405/saga-orchestration/pkg/kafka"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
+	pb "github.com/scul0405/saga-orchestration/proto"
+	"github.com/segmentio/kafka-go"
+	"sync"
+	"time"
+)
+
+var (
+	poolSize = 16
+)
+
+type EventHandler interface {
+	Run(ctx context.Context)
+}
+
+type eventHandler struct {
+	cfg         *config.Config
+	logger      logger.Logger
+	consumer    kafkaClient.ConsumerGroup
+	dlq         kafkaClient.DeadLetterPublisher
+	broker      eventhandler.PurchaseResultBroker
+	purchaseSvc app.Application
+}
+
+func NewEventHandler(
+	cfg *config.Config,
+	logger logger.Logger,
+	consumer kafkaClient.ConsumerGroup,
+	dlq kafkaClient.DeadLetterPublisher,
+	broker eventhandler.PurchaseResultBroker,
+	purchaseSvc app.Application) EventHandler {
+	return &eventHandler{
+		cfg:         cfg,
+		logger:      logger,
+		consumer:    consumer,
+		dlq:         dlq,
+		broker:      broker,
+		purchaseSvc: purchaseSvc,
+	}
+}
+
+func (h *eventHandler) Run(ctx context.Context) {
+	go h.consumer.ConsumeTopic(ctx, poolSize, common.PurchaseResultGroupID, common.PurchaseResultTopic, h.purchaseResultWorker)
+	go h.purchaseResultStreamWorker(ctx)
+}
+
+func (h *eventHandler) purchaseResultWorker(ctx context.Context, r kafkaClient.MessageReader, wg *sync.WaitGroup, workerID int) {
+	defer wg.Done()
+
+	policy := h.cfg.Kafka.Retry.Policy(common.PurchaseResultTopic)
+
+	for {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			h.logger.Errorf("Purchase.PurchaseResultWorker: FetchMessage", err)
+			return
+		}
+
+		msgCtx, span := kafkaClient.StartConsumerSpan(ctx, m, "Purchase.PurchaseResultWorker")
+
+		var result pb.PurchaseResult
+		if err = kafkaClient.Decode(m, &result); err != nil {
+			h.logger.Errorf("Purchase.PurchaseResultWorker: UnmarshalProto", err)
+			if err = h.dlq.Publish(msgCtx, m, err, 1, "Purchase.PurchaseResultWorker"); err != nil {
+				h.logger.Errorf("Purchase.PurchaseResultWorker: PublishDeadLetter", err)
+			}
+			if err = r.CommitMessages(msgCtx, m); err != nil {
+				h.logger.Errorf("Purchase.PurchaseResultWorker: CommitMessages", err)
+			}
+			span.End()
+			continue
+		}
+		h.logger.Infof("Purchase.PurchaseResultWorker: %v, message at topic/partition/offset %v/%v/%v: %s = %s\n", workerID, m.Topic, m.Partition, m.Offset, string(m.Key), string(m.Value))
+
+		attempts, err := policy.Do(msgCtx, func() error {
+			return h.purchaseSvc.Commands.RecordPurchaseResult.Handle(msgCtx, decodePb2RecordPurchaseResultCmd(&result))
+		})
+		if err != nil {
+			h.logger.Errorf("Purchase.PurchaseResultWorker: failed after %d attempts: %v", attempts, err)
+			if err = h.dlq.Publish(msgCtx, m, err, attempts, "Purchase.PurchaseResultWorker"); err != nil {
+				h.logger.Errorf("Purchase.PurchaseResultWorker: PublishDeadLetter", err)
+			}
+		}
+
+		err = r.CommitMessages(msgCtx, m)
+		if err != nil {
+			h.logger.Errorf("Purchase.PurchaseResultWorker: CommitMessages", err)
+		}
+
+		span.End()
+	}
+}
+
+// purchaseResultStreamWorker forwards the purchase results to the streams opened on this instance.
+// The streams of a purchase can be opened on any instance, so each instance reads every result, from the end of the topic
+// and outside of any consumer group: the results produced before the instance started are not replayed.
+// Undecodable results are dead lettered by the purchaseResultWorker.
+// The tail is restarted with a backoff until ctx is done, the streams opened before a failure are closed
+// since they may miss the results produced meanwhile: the clients reconnect and replay the timeline.
+func (h *eventHandler) purchaseResultStreamWorker(ctx context.Context) {
+	policy := h.cfg.Kafka.Retry.Policy(common.PurchaseResultTopic)
+	delay := policy.InitialDelay
+
+	for {
+		started := time.Now()
+		err := kafkaClient.TailTopic(ctx, h.cfg.Kafka.Brokers, common.PurchaseResultTopic, func(m kafka.Message) {
+			_, span := kafkaClient.StartConsumerSpan(ctx, m, "Purchase.PurchaseResultStreamWorker")
+			defer span.End()
+
+			var result pb.PurchaseResult
+			if err := kafkaClient.Decode(m, &result); err == nil {
+				h.broker.Publish(decodePb2PurchaseResult(&result))
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		h.logger.Errorf("Purchase.PurchaseResultStreamWorker: TailTopic", err)
+		h.broker.CloseAll()
+
+		// A tail which ran for a while starts the backoff over
+		if time.Since(started) > policy.MaxDelay {
+			delay = policy.InitialDelay
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+	}
+}
+
+func decodePb2PurchaseResult(result *pb.PurchaseResult) *event.PurchaseResult {
+	cmd := decodePb2RecordPurchaseResultCmd(result)
+
+	return &event.PurchaseResult{
+		PurchaseID: cmd.PurchaseID,
+		Step:       cmd.Step,
+		Status:     cmd.Status,
+		Reason:     cmd.Reason,
+		Timestamp:  cmd.Timestamp,
+	}
+}
+
+func decodePb2RecordPurchaseResultCmd(result *pb.PurchaseResult) command.RecordPurchaseResult {
+	// Steps without a PurchaseStep value are only known by their name
+	step := result.StepName
+	if step == "" {
+		step = result.Step.String()
+	}
+
+	return command.RecordPurchaseResult{
+		PurchaseID: result.PurchaseId,
+		Step:       step,
+		Status:     result.Status.String(),
+		Reason:     result.Reason,
+		Timestamp:  result.Timestamp.AsTime(),
+	}
+}
@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 type Purchase struct {
 	OrderItems *[]OrderItem `json:"order_items"`
 	Payment    *Payment     `json:"payment"`
@@ -13,3 +15,36 @@ type OrderItem struct {
 type Payment struct {
 	CurrencyCode string `json:"currency_code"`
 }
+
+type CreatePurchaseResponse struct {
+	PurchaseID uint64 `json:"purchase_id"`
+}
+
+type ListPurchases struct {
+	Limit  int `form:"limit,default=20" binding:"min=1,max=100"`
+	Offset int `form:"offset,default=0" binding:"min=0"`
+}
+
+type PurchaseDetail struct {
+	PurchaseID uint64            `json:"purchase_id"`
+	OrderItems []OrderItem       `json:"order_items"`
+	Payment    PurchasePayment   `json:"payment"`
+	Step       string            `json:"step"`
+	Status     string            `json:"status"`
+	Reason     string            `json:"reason,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	Timeline   *[]PurchaseStatus `json:"timeline,omitempty"`
+}
+
+type PurchasePayment struct {
+	CurrencyCode string `json:"currency_code"`
+	Amount       uint64 `json:"amount"`
+}
+
+type PurchaseStatus struct {
+	Step      string    `json:"step"`
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
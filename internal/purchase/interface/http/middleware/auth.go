@@ -5,6 +5,7 @@ import (
 	"github.com/scul0405/saga-orchestration/internal/purchase/infrastructure/grpc"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
 	"net/http"
+	"strings"
 )
 
 const (
@@ -31,26 +32,41 @@ func (m *JWTAuthMW) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		accessToken := bearerToken[7:]
-		if accessToken == "" {
-			c.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
+		m.authenticate(c, bearerToken[7:])
+	}
+}
 
-		authResponse, err := m.authSvc.Auth(c.Request.Context(), accessToken)
-		if err != nil {
-			m.logger.Errorf("auth middleware: %v", err)
-			c.AbortWithStatus(http.StatusUnauthorized)
-			return
+// StreamAuthMiddleware also accepts the access token from the access_token query parameter
+func (m *JWTAuthMW) StreamAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accessToken := c.Query("access_token")
+		if bearerToken := c.GetHeader("Authorization"); bearerToken != "" {
+			accessToken = strings.TrimPrefix(bearerToken, "Bearer ")
 		}
 
-		if authResponse.Expired {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": ErrTokenExpired})
-			c.Abort()
-			return
-		}
+		m.authenticate(c, accessToken)
+	}
+}
+
+func (m *JWTAuthMW) authenticate(c *gin.Context, accessToken string) {
+	if accessToken == "" {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	authResponse, err := m.authSvc.Auth(c.Request.Context(), accessToken)
+	if err != nil {
+		m.logger.Errorf("auth middleware: %v", err)
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
 
-		c.Set("customer_id", authResponse.CustomerID)
-		c.Next()
+	if authResponse.Expired {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": ErrTokenExpired})
+		c.Abort()
+		return
 	}
+
+	c.Set("customer_id", authResponse.CustomerID)
+	c.Next()
 }
@@ -6,9 +6,13 @@ import (
 	"github.com/scul0405/saga-orchestration/internal/purchase/app"
 	"github.com/scul0405/saga-orchestration/internal/purchase/app/command"
 	"github.com/scul0405/saga-orchestration/internal/purchase/app/query"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/aggregate"
+	"github.com/scul0405/saga-orchestration/internal/purchase/eventhandler"
 	"github.com/scul0405/saga-orchestration/internal/purchase/infrastructure/grpc"
 	"github.com/scul0405/saga-orchestration/internal/purchase/interface/http/dto"
+	"github.com/scul0405/saga-orchestration/pkg/logger"
 	"net/http"
+	"strconv"
 )
 
 var (
@@ -20,17 +24,23 @@ var (
 	ErrInvalidToken     = "invalid token"
 	ErrProductNotFound  = "product not found"
 	ErrProductNotEnough = "product not enough"
+	ErrPurchaseNotFound = "purchase not found"
+	ErrInvalidQuery     = "invalid query"
 )
 
 type Router struct {
 	app     app.Application
 	authSvc grpc.AuthService
+	broker  eventhandler.PurchaseResultBroker
+	logger  logger.Logger
 }
 
-func NewRouter(app app.Application, authSvc grpc.AuthService) *Router {
+func NewRouter(app app.Application, authSvc grpc.AuthService, broker eventhandler.PurchaseResultBroker, logger logger.Logger) *Router {
 	return &Router{
 		app:     app,
 		authSvc: authSvc,
+		broker:  broker,
+		logger:  logger,
 	}
 }
 
@@ -91,16 +101,80 @@ func (r *Router) CreatePurchase(c *gin.Context) {
 	for i, item := range *purchase.OrderItems {
 		orderItemsCmd[i] = command.OrderItem{
 			ID:       item.ProductID,
+			Price:    (*productStatuses)[i].Price,
 			Quantity: item.Quantity,
 		}
 	}
 	purchaseCmd.Order.OrderItems = &orderItemsCmd
 
-	err = r.app.Commands.CreatePurchase.Handle(c, purchaseCmd)
+	purchaseID, err := r.app.Commands.CreatePurchase.Handle(c, purchaseCmd)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
 		return
 	}
+
+	c.JSON(http.StatusCreated, dto.CreatePurchaseResponse{PurchaseID: purchaseID})
+}
+
+func (r *Router) GetPurchase(c *gin.Context) {
+	customerID := r.extractCustomerID(c)
+	if customerID == 0 {
+		return
+	}
+
+	purchaseID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidID})
+		return
+	}
+
+	purchase, err := r.app.Queries.GetPurchase.Handle(c, query.GetPurchase{PurchaseID: purchaseID})
+	if err != nil {
+		if errors.Is(err, query.ErrPurchaseNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": ErrPurchaseNotFound})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
+		return
+	}
+
+	if purchase.Order.CustomerID != customerID {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrForbidden})
+		return
+	}
+
+	c.JSON(http.StatusOK, encodePurchaseDetail(purchase))
+}
+
+func (r *Router) ListPurchases(c *gin.Context) {
+	customerID := r.extractCustomerID(c)
+	if customerID == 0 {
+		return
+	}
+
+	var params dto.ListPurchases
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidQuery})
+		return
+	}
+
+	purchases, err := r.app.Queries.ListPurchases.Handle(c, query.ListPurchases{
+		CustomerID: customerID,
+		Limit:      params.Limit,
+		Offset:     params.Offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
+		return
+	}
+
+	resp := make([]dto.PurchaseDetail, len(*purchases))
+	for i := range *purchases {
+		resp[i] = *encodePurchaseDetail(&(*purchases)[i])
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
 
 func (r *Router) extractCustomerID(c *gin.Context) uint64 {
@@ -112,3 +186,42 @@ func (r *Router) extractCustomerID(c *gin.Context) uint64 {
 
 	return id.(uint64)
 }
+
+func encodePurchaseDetail(purchase *aggregate.Purchase) *dto.PurchaseDetail {
+	orderItems := make([]dto.OrderItem, len(*purchase.Order.OrderItems))
+	for i, item := range *purchase.Order.OrderItems {
+		orderItems[i] = dto.OrderItem{
+			ProductID: item.ID,
+			Quantity:  item.Quantity,
+		}
+	}
+
+	detail := &dto.PurchaseDetail{
+		PurchaseID: purchase.ID,
+		OrderItems: orderItems,
+		Payment: dto.PurchasePayment{
+			CurrencyCode: purchase.Payment.CurrencyCode,
+			Amount:       purchase.Payment.Amount,
+		},
+		Step:      purchase.Status.Step,
+		Status:    purchase.Status.Status,
+		Reason:    purchase.Status.Reason,
+		CreatedAt: purchase.CreatedAt,
+		UpdatedAt: purchase.Status.Timestamp,
+	}
+
+	if purchase.Timeline != nil {
+		timeline := make([]dto.PurchaseStatus, len(*purchase.Timeline))
+		for i, status := range *purchase.Timeline {
+			timeline[i] = dto.PurchaseStatus{
+				Step:      status.Step,
+				Status:    status.Status,
+				Reason:    status.Reason,
+				Timestamp: status.Timestamp,
+			}
+		}
+		detail.Timeline = &timeline
+	}
+
+	return detail
+}
@@ -3,9 +3,12 @@ package http
 import (
 	"context"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/scul0405/saga-orchestration/cmd/purchase/config"
+	"github.com/scul0405/saga-orchestration/internal/pkg/health"
 	"github.com/scul0405/saga-orchestration/internal/purchase/interface/http/middleware"
 	"github.com/scul0405/saga-orchestration/pkg/logger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"net/http"
 )
 
@@ -14,15 +17,17 @@ type Server struct {
 	logger     logger.Logger
 	Engine     *gin.Engine
 	Router     *Router
+	health     *health.Checker
 	httpServer *http.Server
 }
 
-func NewHTTPServer(config config.HTTP, logger logger.Logger, engine *gin.Engine, router *Router) *Server {
+func NewHTTPServer(config config.HTTP, logger logger.Logger, engine *gin.Engine, router *Router, health *health.Checker) *Server {
 	return &Server{
 		config: config,
 		logger: logger,
 		Engine: engine,
 		Router: router,
+		health: health,
 	}
 }
 
@@ -31,7 +36,9 @@ func NewEngine(config config.HTTP) *gin.Engine {
 
 	engine := gin.New()
 	engine.Use(gin.Recovery())
-	engine.Use(gin.Logger()) // TODO: replace with custom logger
+	engine.Use(otelgin.Middleware("purchase_service"))
+	// The stream routes take the access token as a query parameter, it must not reach the access logs
+	engine.Use(middleware.Logger())
 	engine.Use(middleware.CORSMiddleware())
 
 	return engine
@@ -40,12 +47,26 @@ func NewEngine(config config.HTTP) *gin.Engine {
 func (srv *Server) InitRoutes() {
 	mw := middleware.NewJWTAuthMW(srv.Router.authSvc, srv.logger)
 
+	srv.Engine.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	srv.Engine.GET("/healthz", gin.WrapH(health.LivenessHandler()))
+	srv.Engine.GET("/readyz", gin.WrapH(srv.health.ReadinessHandler()))
+
 	apiGroup := srv.Engine.Group("/api/v1/")
 	{
 		paymentGroup := apiGroup.Group("/purchases")
 		paymentGroup.Use(mw.AuthMiddleware())
 		{
 			paymentGroup.POST("/", srv.Router.CreatePurchase)
+			paymentGroup.GET("/", srv.Router.ListPurchases)
+			paymentGroup.GET("/:id", srv.Router.GetPurchase)
+		}
+
+		// Browsers can not set headers on EventSource and WebSocket requests, the token can be given as a query parameter
+		streamGroup := apiGroup.Group("/purchases")
+		streamGroup.Use(mw.StreamAuthMiddleware())
+		{
+			streamGroup.GET("/:id/events", srv.Router.StreamPurchaseEvents)
+			streamGroup.GET("/:id/ws", srv.Router.StreamPurchaseWebSocket)
 		}
 	}
 }
@@ -0,0 +1,245 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/scul0405/saga-orchestration/internal/purchase/app/query"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/aggregate"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/event"
+	"github.com/scul0405/saga-orchestration/internal/purchase/interface/http/dto"
+	"github.com/tmaxmax/go-sse"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	purchaseResultEvent = "purchase-result"
+	streamHeartbeat     = 15 * time.Second
+	wsWriteTimeout      = 10 * time.Second
+)
+
+// ErrStreamLagged ends a stream which did not keep up with the results, the client reconnects to replay the timeline
+var ErrStreamLagged = errors.New("stream lagged behind the purchase results")
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Same policy as the CORS middleware, the stream is authorized by the access token
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// purchaseStream is the transport pushing the results of a purchase to the client
+type purchaseStream interface {
+	send(status *dto.PurchaseStatus) error
+	heartbeat() error
+}
+
+// StreamPurchaseEvents pushes the results of a purchase as server-sent events until the saga is finished
+func (r *Router) StreamPurchaseEvents(c *gin.Context) {
+	purchase, results, unsubscribe := r.openPurchaseStream(c)
+	if purchase == nil {
+		return
+	}
+	defer unsubscribe()
+
+	session, err := sse.Upgrade(c.Writer, c.Request)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
+		return
+	}
+
+	err = r.streamPurchase(c.Request.Context(), purchase, results, &sseStream{session: session})
+	if errors.Is(err, ErrStreamLagged) {
+		r.logger.Warnf("Purchase.StreamPurchaseEvents: %v", err)
+		return
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		r.logger.Errorf("Purchase.StreamPurchaseEvents: %v", err)
+	}
+}
+
+// StreamPurchaseWebSocket pushes the results of a purchase over a WebSocket until the saga is finished
+func (r *Router) StreamPurchaseWebSocket(c *gin.Context) {
+	purchase, results, unsubscribe := r.openPurchaseStream(c)
+	if purchase == nil {
+		return
+	}
+	defer unsubscribe()
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader already replied with an error
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	// Reading is required to process the control frames, the stream ends when the client goes away
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	err = r.streamPurchase(ctx, purchase, results, &wsStream{conn: conn})
+	if errors.Is(err, ErrStreamLagged) {
+		closeMsg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, err.Error())
+		_ = conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(wsWriteTimeout))
+		return
+	}
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			r.logger.Errorf("Purchase.StreamPurchaseWebSocket: %v", err)
+		}
+		return
+	}
+
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "purchase finished")
+	_ = conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(wsWriteTimeout))
+}
+
+// openPurchaseStream checks the purchase belongs to the customer and subscribes to its results.
+// It replies with the error and returns a nil purchase when the stream can not be opened.
+func (r *Router) openPurchaseStream(c *gin.Context) (*aggregate.Purchase, <-chan *event.PurchaseResult, func()) {
+	customerID := r.extractCustomerID(c)
+	if customerID == 0 {
+		return nil, nil, nil
+	}
+
+	purchaseID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidID})
+		return nil, nil, nil
+	}
+
+	// Subscribe before loading the timeline so no result is missed in between
+	results, unsubscribe := r.broker.Subscribe(purchaseID)
+
+	purchase, err := r.app.Queries.GetPurchase.Handle(c, query.GetPurchase{PurchaseID: purchaseID})
+	if err != nil {
+		unsubscribe()
+		if errors.Is(err, query.ErrPurchaseNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": ErrPurchaseNotFound})
+			return nil, nil, nil
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": ErrInternal})
+		return nil, nil, nil
+	}
+
+	if purchase.Order.CustomerID != customerID {
+		unsubscribe()
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrForbidden})
+		return nil, nil, nil
+	}
+
+	return purchase, results, unsubscribe
+}
+
+// streamPurchase replays the timeline of the purchase then pushes the new results.
+// It returns nil once the final result of the saga was sent.
+func (r *Router) streamPurchase(ctx context.Context, purchase *aggregate.Purchase, results <-chan *event.PurchaseResult, stream purchaseStream) error {
+	sent := make(map[string]struct{})
+	push := func(result *event.PurchaseResult) (bool, error) {
+		key := fmt.Sprintf("%s/%s/%d", result.Step, result.Status, result.Timestamp.UnixMilli())
+		if _, ok := sent[key]; ok {
+			return false, nil
+		}
+		sent[key] = struct{}{}
+
+		err := stream.send(&dto.PurchaseStatus{
+			Step:      result.Step,
+			Status:    result.Status,
+			Reason:    result.Reason,
+			Timestamp: result.Timestamp,
+		})
+		return result.IsFinal(), err
+	}
+
+	for _, status := range *purchase.Timeline {
+		final, err := push(&event.PurchaseResult{
+			PurchaseID: purchase.ID,
+			Step:       status.Step,
+			Status:     status.Status,
+			Reason:     status.Reason,
+			Timestamp:  status.Timestamp,
+		})
+		if err != nil || final {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(streamHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := stream.heartbeat(); err != nil {
+				return err
+			}
+		case result, ok := <-results:
+			if !ok {
+				return ErrStreamLagged
+			}
+			final, err := push(result)
+			if err != nil || final {
+				return err
+			}
+		}
+	}
+}
+
+type sseStream struct {
+	session *sse.Session
+}
+
+func (s *sseStream) send(status *dto.PurchaseStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	msg := &sse.Message{Type: sse.Type(purchaseResultEvent)}
+	msg.AppendData(string(data))
+	if err = s.session.Send(msg); err != nil {
+		return err
+	}
+	return s.session.Flush()
+}
+
+func (s *sseStream) heartbeat() error {
+	msg := &sse.Message{}
+	msg.AppendComment("heartbeat")
+	if err := s.session.Send(msg); err != nil {
+		return err
+	}
+	return s.session.Flush()
+}
+
+type wsStream struct {
+	conn *websocket.Conn
+}
+
+func (s *wsStream) send(status *dto.PurchaseStatus) error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+		return err
+	}
+	return s.conn.WriteJSON(status)
+}
+
+func (s *wsStream) heartbeat() error {
+	return s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+}
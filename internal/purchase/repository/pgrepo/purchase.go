@@ -0,0 +1,190 @@
+package pgrepo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/aggregate"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/entity"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/event"
+	"github.com/scul0405/saga-orchestration/internal/purchase/domain/valueobject"
+	"github.com/scul0405/saga-orchestration/internal/purchase/infrastructure/db/postgres/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type PurchaseRepository interface {
+	GetPurchase(ctx context.Context, id uint64) (*aggregate.Purchase, error)
+	ListPurchases(ctx context.Context, customerID uint64, limit, offset int) (*[]aggregate.Purchase, error)
+	CreatePurchase(ctx context.Context, purchase *aggregate.Purchase) error
+	AddPurchaseResult(ctx context.Context, result *event.PurchaseResult) (time.Time, error)
+}
+
+type purchaseRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewPurchaseRepository(db *gorm.DB) PurchaseRepository {
+	return &purchaseRepositoryImpl{db: db}
+}
+
+// GetPurchase returns the purchase with its timeline
+func (r *purchaseRepositoryImpl) GetPurchase(ctx context.Context, id uint64) (*aggregate.Purchase, error) {
+	var purchase model.Purchase
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&purchase).Error; err != nil {
+		return nil, err
+	}
+
+	var steps []model.PurchaseStep
+	if err := r.db.WithContext(ctx).Where("purchase_id = ?", id).Order("timestamp, id").Find(&steps).Error; err != nil {
+		return nil, err
+	}
+
+	result, err := decodePurchase(&purchase)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := make([]valueobject.PurchaseStatus, len(steps))
+	for i, step := range steps {
+		timeline[i] = valueobject.PurchaseStatus{
+			Step:      step.Step,
+			Status:    step.Status,
+			Reason:    step.Reason,
+			Timestamp: time.UnixMilli(step.Timestamp),
+		}
+	}
+	result.Timeline = &timeline
+
+	return result, nil
+}
+
+// ListPurchases returns the purchases of the customer, newest first
+func (r *purchaseRepositoryImpl) ListPurchases(ctx context.Context, customerID uint64, limit, offset int) (*[]aggregate.Purchase, error) {
+	var purchases []model.Purchase
+	if err := r.db.WithContext(ctx).Where("customer_id = ?", customerID).
+		Order("created_at DESC, id DESC").Limit(limit).Offset(offset).
+		Find(&purchases).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]aggregate.Purchase, len(purchases))
+	for i := range purchases {
+		purchase, err := decodePurchase(&purchases[i])
+		if err != nil {
+			return nil, err
+		}
+		result[i] = *purchase
+	}
+
+	return &result, nil
+}
+
+func (r *purchaseRepositoryImpl) CreatePurchase(ctx context.Context, purchase *aggregate.Purchase) error {
+	orderItems, err := json.Marshal(purchase.Order.OrderItems)
+	if err != nil {
+		return err
+	}
+
+	return r.db.WithContext(ctx).Create(&model.Purchase{
+		ID:           purchase.ID,
+		CustomerID:   purchase.Order.CustomerID,
+		OrderItems:   orderItems,
+		CurrencyCode: purchase.Payment.CurrencyCode,
+		Amount:       purchase.Payment.Amount,
+		Step:         purchase.Status.Step,
+		Status:       purchase.Status.Status,
+		Reason:       purchase.Status.Reason,
+	}).Error
+}
+
+// AddPurchaseResult appends the result to the purchase timeline and moves the purchase to it,
+// unless a newer result was already applied. Redelivered results are only recorded once.
+// It returns the time of the result the purchase moved from, or of its creation for the first result;
+// the time is zero when the result was already recorded or is older than the current one.
+func (r *purchaseRepositoryImpl) AddPurchaseResult(ctx context.Context, result *event.PurchaseResult) (time.Time, error) {
+	timestamp := result.Timestamp.UnixMilli()
+	var previous time.Time
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.PurchaseStep{
+			PurchaseID: result.PurchaseID,
+			Step:       result.Step,
+			Status:     result.Status,
+			Reason:     result.Reason,
+			Timestamp:  timestamp,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+
+		var purchase model.Purchase
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("result_at", "created_at").
+			Where("id = ?", result.PurchaseID).
+			Take(&purchase).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if purchase.ResultAt > timestamp {
+			return nil
+		}
+
+		previous = time.UnixMilli(purchase.ResultAt)
+		if purchase.ResultAt == 0 {
+			previous = time.UnixMilli(purchase.CreatedAt)
+		}
+
+		return tx.Model(&model.Purchase{}).
+			Where("id = ?", result.PurchaseID).
+			Updates(map[string]interface{}{
+				"step":      result.Step,
+				"status":    result.Status,
+				"reason":    result.Reason,
+				"result_at": timestamp,
+			}).Error
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return previous, nil
+}
+
+func decodePurchase(purchase *model.Purchase) (*aggregate.Purchase, error) {
+	var orderItems []entity.OrderItem
+	if err := json.Unmarshal(purchase.OrderItems, &orderItems); err != nil {
+		return nil, err
+	}
+
+	status := &valueobject.PurchaseStatus{
+		Step:      purchase.Step,
+		Status:    purchase.Status,
+		Reason:    purchase.Reason,
+		Timestamp: time.UnixMilli(purchase.UpdatedAt),
+	}
+	if purchase.ResultAt != 0 {
+		status.Timestamp = time.UnixMilli(purchase.ResultAt)
+	}
+
+	return &aggregate.Purchase{
+		ID: purchase.ID,
+		Order: &entity.Order{
+			CustomerID: purchase.CustomerID,
+			OrderItems: &orderItems,
+		},
+		Payment: &valueobject.Payment{
+			CurrencyCode: purchase.CurrencyCode,
+			Amount:       purchase.Amount,
+		},
+		Status:    status,
+		CreatedAt: time.UnixMilli(purchase.CreatedAt),
+	}, nil
+}
@@ -24,3 +24,11 @@ type Logger struct {
 	Encoding          string
 	Level             string
 }
+
+// Tracing configures the export of the traces with OTLP over gRPC, no trace is exported when Endpoint is empty.
+// SampleRatio is the ratio of the traces started by the service which are sampled.
+type Tracing struct {
+	Endpoint    string
+	Insecure    bool
+	SampleRatio float64
+}
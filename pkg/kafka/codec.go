@@ -0,0 +1,62 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Encode marshals m with the codec of the content type.
+// The returned header must be sent with the value so consumers can decode it.
+func Encode(contentType string, m proto.Message) ([]byte, kafka.Header, error) {
+	var value []byte
+	var err error
+
+	switch contentType {
+	case ContentTypeProtobuf:
+		value, err = proto.Marshal(m)
+	case ContentTypeJSON:
+		value, err = protojson.Marshal(m)
+	default:
+		return nil, kafka.Header{}, fmt.Errorf("encode: unsupported content type %q", contentType)
+	}
+	if err != nil {
+		return nil, kafka.Header{}, err
+	}
+
+	return value, kafka.Header{Key: ContentTypeHeader, Value: []byte(contentType)}, nil
+}
+
+// Decode unmarshals the value of msg into m according to its content type header.
+// Messages without the header were produced before the codec and are decoded as plain JSON.
+// A message which can not be decoded is a poison message.
+func Decode(msg kafka.Message, m proto.Message) error {
+	var err error
+	switch contentType := ContentType(msg); contentType {
+	case ContentTypeProtobuf:
+		err = proto.Unmarshal(msg.Value, m)
+	case ContentTypeJSON:
+		err = protojson.Unmarshal(msg.Value, m)
+	case "":
+		err = json.Unmarshal(msg.Value, m)
+	default:
+		err = fmt.Errorf("decode: unsupported content type %q", contentType)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPoisonMessage, err)
+	}
+	return nil
+}
+
+// ContentType returns the content type header of msg, or an empty string when the message has none
+func ContentType(msg kafka.Message) string {
+	for _, header := range msg.Headers {
+		if header.Key == ContentTypeHeader {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
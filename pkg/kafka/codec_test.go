@@ -0,0 +1,45 @@
+package kafka
+
+import (
+	"encoding/json"
+	pb "github.com/scul0405/saga-orchestration/proto"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"testing"
+)
+
+func TestCodec(t *testing.T) {
+	t.Parallel()
+
+	reply := &pb.CreatePurchaseResponse{
+		PurchaseId:   1,
+		Success:      false,
+		ErrorMessage: "insufficient inventory",
+		Timestamp:    timestamppb.Now(),
+	}
+
+	for _, contentType := range []string{ContentTypeProtobuf, ContentTypeJSON} {
+		value, header, err := Encode(contentType, reply)
+		require.NoError(t, err)
+		require.Equal(t, ContentTypeHeader, header.Key)
+		require.Equal(t, contentType, string(header.Value))
+
+		var decoded pb.CreatePurchaseResponse
+		require.NoError(t, Decode(kafka.Message{Value: value, Headers: []kafka.Header{header}}, &decoded))
+		require.True(t, proto.Equal(reply, &decoded), contentType)
+	}
+
+	// Messages produced before the codec have no content type and are plain JSON
+	legacy, err := json.Marshal(reply)
+	require.NoError(t, err)
+
+	var decoded pb.CreatePurchaseResponse
+	require.NoError(t, Decode(kafka.Message{Value: legacy}, &decoded))
+	require.True(t, proto.Equal(reply, &decoded))
+
+	_, _, err = Encode("text/plain", reply)
+	require.Error(t, err)
+	require.Error(t, Decode(kafka.Message{Value: legacy, Headers: []kafka.Header{{Key: ContentTypeHeader, Value: []byte("text/plain")}}}, &decoded))
+}
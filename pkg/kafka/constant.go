@@ -15,6 +15,26 @@ const (
 
 	writerReadTimeout  = 1 * time.Second
 	writerWriteTimeout = 1 * time.Second
+)
+
+const (
+	// DeadLetterQueueTopic receives the messages which could not be processed
+	DeadLetterQueueTopic = "dead-letter-queue"
+
+	// Headers added to a dead letter next to the headers of the original message
+	DeadLetterSourceTopicHeader     = "dlq-source-topic"
+	DeadLetterSourcePartitionHeader = "dlq-source-partition"
+	DeadLetterSourceOffsetHeader    = "dlq-source-offset"
+	DeadLetterErrorHeader           = "dlq-error"
+	DeadLetterAttemptsHeader        = "dlq-attempts"
+	DeadLetterWorkerHeader          = "dlq-worker"
+	DeadLetterFailedAtHeader        = "dlq-failed-at"
+)
+
+const (
+	// ContentTypeHeader tells consumers how the value of a message is encoded
+	ContentTypeHeader = "content-type"
 
-	deadLetterQueueTopic = "dead-letter-queue"
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeJSON     = "application/json"
 )
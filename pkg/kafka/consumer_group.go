@@ -7,8 +7,9 @@ import (
 	"sync"
 )
 
-// Worker kafka consumer worker fetch and process messages from reader
-type Worker func(ctx context.Context, r *kafka.Reader, wg *sync.WaitGroup, workerID int)
+// Worker kafka consumer worker fetch and process messages from reader.
+// The messages sharing a key are all given to the same worker, in order.
+type Worker func(ctx context.Context, r MessageReader, wg *sync.WaitGroup, workerID int)
 
 type ConsumerGroup interface {
 	ConsumeTopic(ctx context.Context, poolSize int, groupID string, topic string, worker Worker)
@@ -35,8 +36,10 @@ func (c *consumerGroup) GetNewKafkaWriter() *kafka.Writer {
 
 func (c *consumerGroup) ConsumeTopic(ctx context.Context, poolSize int, groupID string, topic string, worker Worker) {
 	r := c.GetNewKafkaReader(c.Brokers, topic, groupID)
+	readers.add(r)
 
 	defer func() {
+		readers.remove(r)
 		if err := r.Close(); err != nil {
 			c.log.Warnf("consumerGroup.r.Close: %v", err)
 		}
@@ -44,10 +47,13 @@ func (c *consumerGroup) ConsumeTopic(ctx context.Context, poolSize int, groupID
 
 	c.log.Infof("(Starting consumer groupID): GroupID %s, topic: %+v, poolSize: %v", groupID, topic, poolSize)
 
+	d := newKeyedDispatcher(r, poolSize)
+	go d.run(ctx)
+
 	wg := &sync.WaitGroup{}
-	for i := 0; i <= poolSize; i++ {
+	for i := 0; i < poolSize; i++ {
 		wg.Add(1)
-		go worker(ctx, r, wg, i)
+		go worker(ctx, d.reader(i), wg, i)
 	}
 	wg.Wait()
 }
@@ -0,0 +1,131 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeadLetter is a message moved to the dead letter queue with the reason it could not be processed
+type DeadLetter struct {
+	Message         kafka.Message
+	SourceTopic     string
+	SourcePartition int
+	SourceOffset    int64
+	Error           string
+	Attempts        uint
+	Worker          string
+	FailedAt        time.Time
+}
+
+// DeadLetterPublisher moves messages which could not be processed to the dead letter queue
+type DeadLetterPublisher interface {
+	Publish(ctx context.Context, msg kafka.Message, cause error, attempts uint, worker string) error
+}
+
+type deadLetterPublisher struct {
+	producer Producer
+	topic    string
+}
+
+// NewDeadLetterPublisher create new dead letter publisher writing to DeadLetterQueueTopic
+func NewDeadLetterPublisher(producer Producer) DeadLetterPublisher {
+	return &deadLetterPublisher{producer: producer, topic: DeadLetterQueueTopic}
+}
+
+func (p *deadLetterPublisher) Publish(ctx context.Context, msg kafka.Message, cause error, attempts uint, worker string) error {
+	return p.producer.PublishMessage(ctx, EncodeDeadLetter(p.topic, &DeadLetter{
+		Message:         msg,
+		SourceTopic:     msg.Topic,
+		SourcePartition: msg.Partition,
+		SourceOffset:    msg.Offset,
+		Error:           cause.Error(),
+		Attempts:        attempts,
+		Worker:          worker,
+		FailedAt:        time.Now(),
+	}))
+}
+
+// EncodeDeadLetter wraps the original message: key, value and headers are kept as is
+// and the failure details are appended as headers
+func EncodeDeadLetter(topic string, dl *DeadLetter) kafka.Message {
+	headers := make([]kafka.Header, 0, len(dl.Message.Headers)+7)
+	for _, header := range dl.Message.Headers {
+		if !isDeadLetterHeader(header.Key) {
+			headers = append(headers, header)
+		}
+	}
+
+	headers = append(headers,
+		kafka.Header{Key: DeadLetterSourceTopicHeader, Value: []byte(dl.SourceTopic)},
+		kafka.Header{Key: DeadLetterSourcePartitionHeader, Value: []byte(strconv.Itoa(dl.SourcePartition))},
+		kafka.Header{Key: DeadLetterSourceOffsetHeader, Value: []byte(strconv.FormatInt(dl.SourceOffset, 10))},
+		kafka.Header{Key: DeadLetterErrorHeader, Value: []byte(dl.Error)},
+		kafka.Header{Key: DeadLetterAttemptsHeader, Value: []byte(strconv.FormatUint(uint64(dl.Attempts), 10))},
+		kafka.Header{Key: DeadLetterWorkerHeader, Value: []byte(dl.Worker)},
+		kafka.Header{Key: DeadLetterFailedAtHeader, Value: []byte(dl.FailedAt.UTC().Format(time.RFC3339Nano))},
+	)
+
+	return kafka.Message{
+		Topic:   topic,
+		Key:     dl.Message.Key,
+		Value:   dl.Message.Value,
+		Headers: headers,
+	}
+}
+
+// DecodeDeadLetter reads the failure details of a message consumed from the dead letter queue.
+// The returned Message is the original message addressed to its source topic.
+func DecodeDeadLetter(msg kafka.Message) (*DeadLetter, error) {
+	dl := &DeadLetter{}
+
+	headers := make([]kafka.Header, 0, len(msg.Headers))
+	for _, header := range msg.Headers {
+		value := string(header.Value)
+
+		var err error
+		switch header.Key {
+		case DeadLetterSourceTopicHeader:
+			dl.SourceTopic = value
+		case DeadLetterSourcePartitionHeader:
+			dl.SourcePartition, err = strconv.Atoi(value)
+		case DeadLetterSourceOffsetHeader:
+			dl.SourceOffset, err = strconv.ParseInt(value, 10, 64)
+		case DeadLetterErrorHeader:
+			dl.Error = value
+		case DeadLetterAttemptsHeader:
+			var attempts uint64
+			attempts, err = strconv.ParseUint(value, 10, 64)
+			dl.Attempts = uint(attempts)
+		case DeadLetterWorkerHeader:
+			dl.Worker = value
+		case DeadLetterFailedAtHeader:
+			dl.FailedAt, err = time.Parse(time.RFC3339Nano, value)
+		default:
+			headers = append(headers, header)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decode dead letter header %s: %w", header.Key, err)
+		}
+	}
+
+	if dl.SourceTopic == "" {
+		return nil, fmt.Errorf("decode dead letter: missing %s header", DeadLetterSourceTopicHeader)
+	}
+
+	dl.Message = kafka.Message{
+		Topic:   dl.SourceTopic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+
+	return dl, nil
+}
+
+func isDeadLetterHeader(key string) bool {
+	return strings.HasPrefix(key, "dlq-")
+}
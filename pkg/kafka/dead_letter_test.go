@@ -0,0 +1,69 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type fakeProducer struct {
+	msgs []kafka.Message
+}
+
+func (p *fakeProducer) PublishMessage(_ context.Context, msgs ...kafka.Message) error {
+	p.msgs = append(p.msgs, msgs...)
+	return nil
+}
+
+func (p *fakeProducer) Close() error {
+	return nil
+}
+
+func TestDeadLetter(t *testing.T) {
+	t.Parallel()
+
+	producer := &fakeProducer{}
+	publisher := NewDeadLetterPublisher(producer)
+
+	original := kafka.Message{
+		Topic:     "reply",
+		Partition: 2,
+		Offset:    42,
+		Key:       []byte("1"),
+		Value:     []byte(`{"purchase_id":1}`),
+		Headers:   []kafka.Header{{Key: "handler", Value: []byte("create-order-handler")}},
+	}
+
+	err := publisher.Publish(context.Background(), original, errors.New("boom"), 10, "Orchestrator.ReplyWorker")
+	require.NoError(t, err)
+	require.Len(t, producer.msgs, 1)
+
+	published := producer.msgs[0]
+	require.Equal(t, DeadLetterQueueTopic, published.Topic)
+	require.Equal(t, original.Key, published.Key)
+	require.Equal(t, original.Value, published.Value)
+
+	dl, err := DecodeDeadLetter(published)
+	require.NoError(t, err)
+	require.Equal(t, "reply", dl.SourceTopic)
+	require.Equal(t, 2, dl.SourcePartition)
+	require.Equal(t, int64(42), dl.SourceOffset)
+	require.Equal(t, "boom", dl.Error)
+	require.Equal(t, uint(10), dl.Attempts)
+	require.Equal(t, "Orchestrator.ReplyWorker", dl.Worker)
+	require.False(t, dl.FailedAt.IsZero())
+
+	// the original message is restored with its own headers only
+	require.Equal(t, "reply", dl.Message.Topic)
+	require.Equal(t, original.Headers, dl.Message.Headers)
+	require.Equal(t, original.Value, dl.Message.Value)
+
+	// a dead letter which fails again does not stack the failure headers
+	again := EncodeDeadLetter(DeadLetterQueueTopic, dl)
+	require.Len(t, again.Headers, len(published.Headers))
+
+	_, err = DecodeDeadLetter(original)
+	require.Error(t, err)
+}
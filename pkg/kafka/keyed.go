@@ -0,0 +1,198 @@
+package kafka
+
+import (
+	"context"
+	"github.com/segmentio/kafka-go"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// MessageReader is the part of kafka.Reader used by the workers
+type MessageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// keyedDispatcher fetches the messages of a reader and hands them to its lanes by key.
+// Each lane is read by a single worker, so the messages of a key are processed one at a time and in order.
+type keyedDispatcher struct {
+	r       MessageReader
+	lanes   []chan kafka.Message
+	next    uint32
+	err     error
+	commits *commitTracker
+	// commitMu keeps the commits of a partition in order
+	commitMu sync.Mutex
+}
+
+func newKeyedDispatcher(r MessageReader, size int) *keyedDispatcher {
+	d := &keyedDispatcher{
+		r:       r,
+		lanes:   make([]chan kafka.Message, size),
+		commits: newCommitTracker(),
+	}
+	for i := range d.lanes {
+		d.lanes[i] = make(chan kafka.Message)
+	}
+
+	return d
+}
+
+// run dispatches the messages until fetching fails, the lanes are then closed
+func (d *keyedDispatcher) run(ctx context.Context) {
+	defer func() {
+		for _, lane := range d.lanes {
+			close(lane)
+		}
+	}()
+
+	for {
+		m, err := d.r.FetchMessage(ctx)
+		if err != nil {
+			d.err = err
+			return
+		}
+		d.commits.fetched(m)
+
+		select {
+		case d.lanes[d.laneOf(m)] <- m:
+		case <-ctx.Done():
+			d.err = ctx.Err()
+			return
+		}
+	}
+}
+
+// laneOf hashes the key of the message, messages without a key are spread over the lanes
+func (d *keyedDispatcher) laneOf(m kafka.Message) int {
+	if len(m.Key) == 0 {
+		return int(atomic.AddUint32(&d.next, 1) % uint32(len(d.lanes)))
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write(m.Key)
+	return int(h.Sum32() % uint32(len(d.lanes)))
+}
+
+func (d *keyedDispatcher) reader(lane int) MessageReader {
+	return &laneReader{d: d, lane: d.lanes[lane]}
+}
+
+// commit marks the messages processed and commits every partition up to its oldest message still in progress
+func (d *keyedDispatcher) commit(ctx context.Context, msgs ...kafka.Message) error {
+	d.commitMu.Lock()
+	defer d.commitMu.Unlock()
+
+	committable := d.commits.processed(msgs...)
+	if len(committable) == 0 {
+		return nil
+	}
+
+	return d.r.CommitMessages(ctx, committable...)
+}
+
+// laneReader is the reader given to the worker of a lane
+type laneReader struct {
+	d    *keyedDispatcher
+	lane <-chan kafka.Message
+}
+
+func (l *laneReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case m, ok := <-l.lane:
+		if !ok {
+			return kafka.Message{}, l.d.err
+		}
+		return m, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func (l *laneReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	return l.d.commit(ctx, msgs...)
+}
+
+// commitTracker follows the fetched offsets of each partition. As the lanes complete messages out of order,
+// an offset is only committable once every message fetched before it in the partition is processed.
+type commitTracker struct {
+	mu         sync.Mutex
+	partitions map[partitionKey]*partitionOffsets
+}
+
+type partitionKey struct {
+	topic     string
+	partition int
+}
+
+type partitionOffsets struct {
+	// pending are the fetched offsets not committed yet, in fetch order, done the processed ones among them
+	pending []int64
+	done    map[int64]bool
+}
+
+// isPending tells if the offset was fetched and is not committed yet, the pending offsets are increasing
+func (p *partitionOffsets) isPending(offset int64) bool {
+	i := sort.Search(len(p.pending), func(i int) bool { return p.pending[i] >= offset })
+	return i < len(p.pending) && p.pending[i] == offset
+}
+
+func newCommitTracker() *commitTracker {
+	return &commitTracker{partitions: make(map[partitionKey]*partitionOffsets)}
+}
+
+func (t *commitTracker) fetched(m kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := partitionKey{topic: m.Topic, partition: m.Partition}
+	p, ok := t.partitions[key]
+	// The reader went back to an older offset after a rebalance, the partition is tracked again from there
+	if !ok || (len(p.pending) > 0 && m.Offset <= p.pending[len(p.pending)-1]) {
+		p = &partitionOffsets{done: make(map[int64]bool)}
+		t.partitions[key] = p
+	}
+
+	p.pending = append(p.pending, m.Offset)
+}
+
+// processed marks the messages processed and returns, for each partition which moved forward,
+// the last message which can be committed. The messages fetched before the partition was tracked again
+// after a rebalance are not pending anymore, they are ignored.
+func (t *commitTracker) processed(msgs ...kafka.Message) []kafka.Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	moved := make(map[partitionKey]bool)
+	for _, m := range msgs {
+		key := partitionKey{topic: m.Topic, partition: m.Partition}
+		if p, ok := t.partitions[key]; ok && p.isPending(m.Offset) {
+			p.done[m.Offset] = true
+			moved[key] = true
+		}
+	}
+
+	var committable []kafka.Message
+	for key := range moved {
+		p := t.partitions[key]
+
+		last := int64(-1)
+		for len(p.pending) > 0 && p.done[p.pending[0]] {
+			last = p.pending[0]
+			delete(p.done, last)
+			p.pending = p.pending[1:]
+		}
+
+		if last >= 0 {
+			committable = append(committable, kafka.Message{Topic: key.topic, Partition: key.partition, Offset: last})
+		}
+		// Nothing is in progress, the partition may be assigned to another reader: it is tracked again on its next fetch
+		if len(p.pending) == 0 {
+			delete(t.partitions, key)
+		}
+	}
+
+	return committable
+}
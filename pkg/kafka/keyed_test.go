@@ -0,0 +1,151 @@
+package kafka
+
+import (
+	"context"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"io"
+	"sync"
+	"testing"
+)
+
+type fakeReader struct {
+	mu        sync.Mutex
+	msgs      []kafka.Message
+	committed []kafka.Message
+}
+
+func (r *fakeReader) FetchMessage(_ context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.msgs) == 0 {
+		return kafka.Message{}, io.EOF
+	}
+	m := r.msgs[0]
+	r.msgs = r.msgs[1:]
+	return m, nil
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func TestKeyedDispatcher(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	keys := []string{"1", "2", "3", "1", "2", "1", "4", "3"}
+
+	reader := &fakeReader{}
+	for i, key := range keys {
+		reader.msgs = append(reader.msgs, kafka.Message{Topic: "reply", Offset: int64(i), Key: []byte(key)})
+	}
+
+	d := newKeyedDispatcher(reader, 3)
+	go d.run(ctx)
+
+	var mu sync.Mutex
+	received := make(map[string][]int64)
+	lanes := make(map[string]int)
+
+	wg := &sync.WaitGroup{}
+	for i := range d.lanes {
+		wg.Add(1)
+		go func(lane int) {
+			defer wg.Done()
+
+			r := d.reader(lane)
+			for {
+				m, err := r.FetchMessage(ctx)
+				if err != nil {
+					require.ErrorIs(t, err, io.EOF)
+					return
+				}
+
+				mu.Lock()
+				key := string(m.Key)
+				if l, ok := lanes[key]; ok {
+					require.Equal(t, l, lane, "key %s handled by two lanes", key)
+				}
+				lanes[key] = lane
+				received[key] = append(received[key], m.Offset)
+				mu.Unlock()
+
+				require.NoError(t, r.CommitMessages(ctx, m))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(t, []int64{0, 3, 5}, received["1"])
+	require.Equal(t, []int64{1, 4}, received["2"])
+	require.Equal(t, []int64{2, 7}, received["3"])
+	require.Equal(t, []int64{6}, received["4"])
+
+	// every message is processed, so the partition is committed up to the last one
+	last := reader.committed[len(reader.committed)-1]
+	require.Equal(t, int64(7), last.Offset)
+}
+
+func TestCommitTracker(t *testing.T) {
+	t.Parallel()
+
+	msg := func(partition int, offset int64) kafka.Message {
+		return kafka.Message{Topic: "reply", Partition: partition, Offset: offset}
+	}
+
+	tracker := newCommitTracker()
+	for _, m := range []kafka.Message{msg(0, 10), msg(0, 11), msg(0, 12), msg(1, 5)} {
+		tracker.fetched(m)
+	}
+
+	// offset 10 is still in progress, nothing can be committed on partition 0
+	require.Empty(t, tracker.processed(msg(0, 12)))
+	require.Empty(t, tracker.processed(msg(0, 11)))
+	require.Equal(t, []kafka.Message{msg(0, 12)}, tracker.processed(msg(0, 10)))
+
+	require.Equal(t, []kafka.Message{msg(1, 5)}, tracker.processed(msg(1, 5)))
+
+	// after a rebalance the reader starts again from the committed offset
+	tracker.fetched(msg(0, 13))
+	tracker.fetched(msg(0, 13))
+	require.Equal(t, []kafka.Message{msg(0, 13)}, tracker.processed(msg(0, 13)))
+
+	// messages which are not tracked are ignored
+	require.Empty(t, tracker.processed(msg(2, 1)))
+	require.Empty(t, tracker.partitions)
+}
+
+func TestCommitTrackerReassignedPartition(t *testing.T) {
+	t.Parallel()
+
+	msg := func(offset int64) kafka.Message {
+		return kafka.Message{Topic: "reply", Partition: 0, Offset: offset}
+	}
+
+	tracker := newCommitTracker()
+	for _, offset := range []int64{20, 21, 22} {
+		tracker.fetched(msg(offset))
+	}
+
+	// the partition comes back from its committed offset while 20 to 22 are still in progress
+	tracker.fetched(msg(18))
+	tracker.fetched(msg(19))
+
+	// the messages fetched before are not pending anymore, they are neither kept nor committed
+	require.Empty(t, tracker.processed(msg(21), msg(22)))
+	require.Empty(t, tracker.partitions[partitionKey{topic: "reply"}].done)
+
+	require.Empty(t, tracker.processed(msg(19)))
+	require.Equal(t, []kafka.Message{msg(19)}, tracker.processed(msg(18)))
+	require.Empty(t, tracker.partitions)
+
+	// a late message of a partition which is not tracked anymore is ignored
+	require.Empty(t, tracker.processed(msg(20)))
+	require.Empty(t, tracker.partitions)
+}
@@ -0,0 +1,63 @@
+package kafka
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+	"sync"
+)
+
+var consumerLagDesc = prometheus.NewDesc(
+	"kafka_consumer_lag",
+	"Messages of the topic not consumed yet by the consumer group, as seen by the readers of this instance.",
+	[]string{"group", "topic"}, nil,
+)
+
+// readerCollector reports the lag of the readers of the running consumer groups
+type readerCollector struct {
+	mu      sync.Mutex
+	readers map[*kafka.Reader]struct{}
+}
+
+var readers = &readerCollector{readers: make(map[*kafka.Reader]struct{})}
+
+func init() {
+	prometheus.MustRegister(readers)
+}
+
+func (c *readerCollector) add(r *kafka.Reader) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.readers[r] = struct{}{}
+}
+
+func (c *readerCollector) remove(r *kafka.Reader) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.readers, r)
+}
+
+func (c *readerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- consumerLagDesc
+}
+
+func (c *readerCollector) Collect(ch chan<- prometheus.Metric) {
+	type groupTopic struct {
+		group string
+		topic string
+	}
+
+	c.mu.Lock()
+	lags := make(map[groupTopic]int64)
+	for r := range c.readers {
+		// Stats also resets the counters of the reader, only its gauges are reported
+		stats := r.Stats()
+		lags[groupTopic{group: r.Config().GroupID, topic: stats.Topic}] += stats.Lag
+	}
+	c.mu.Unlock()
+
+	for key, lag := range lags {
+		ch <- prometheus.MustNewConstMetric(consumerLagDesc, prometheus.GaugeValue, float64(lag), key.group, key.topic)
+	}
+}
@@ -22,7 +22,19 @@ func NewProducer(log logger.Logger, brokers []string) Producer {
 	return &producer{log: log, brokers: brokers, w: NewKafkaWriter(brokers)}
 }
 
-func (p *producer) PublishMessage(ctx context.Context, msgs ...kafka.Message) error {
+// PublishMessage writes the messages with the trace context of ctx.
+// Messages which already carry a trace context, e.g. relayed from an outbox, keep it.
+func (p *producer) PublishMessage(ctx context.Context, msgs ...kafka.Message) (err error) {
+	ctx, span := startProducerSpan(ctx, msgs)
+	defer func() { endSpan(span, err) }()
+
+	for i := range msgs {
+		if !hasTraceContext(&msgs[i]) {
+			msgs[i].Headers = append([]kafka.Header(nil), msgs[i].Headers...)
+			InjectTraceContext(ctx, &msgs[i].Headers)
+		}
+	}
+
 	if err := p.w.WriteMessages(ctx, msgs...); err != nil {
 		return err
 	}
@@ -1,6 +1,9 @@
 package kafka
 
-import "github.com/segmentio/kafka-go"
+import (
+	"context"
+	"github.com/segmentio/kafka-go"
+)
 
 func NewKafkaReader(kafkaURL []string, topic, groupID string) *kafka.Reader {
 	return kafka.NewReader(kafka.ReaderConfig{
@@ -19,3 +22,138 @@ func NewKafkaReader(kafkaURL []string, topic, groupID string) *kafka.Reader {
 		},
 	})
 }
+
+// NewKafkaPartitionReader create a reader of a single partition which does not belong to any consumer group
+func NewKafkaPartitionReader(kafkaURL []string, topic string, partition int) *kafka.Reader {
+	return kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     kafkaURL,
+		Topic:       topic,
+		Partition:   partition,
+		MinBytes:    1,
+		MaxBytes:    maxBytes,
+		MaxWait:     maxWait,
+		MaxAttempts: maxAttempts,
+		Dialer: &kafka.Dialer{
+			Timeout: dialTimeout,
+		},
+	})
+}
+
+// ReadTopic reads every message currently stored in the topic, partition by partition,
+// without committing any offset. It stops at the end of each partition.
+func ReadTopic(ctx context.Context, kafkaURL []string, topic string, handle func(m kafka.Message) error) error {
+	conn, err := kafka.DialContext(ctx, "tcp", kafkaURL[0])
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions(topic)
+	if err != nil {
+		return err
+	}
+
+	for _, partition := range partitions {
+		first, last, err := readPartitionOffsets(ctx, kafkaURL[0], topic, partition.ID)
+		if err != nil {
+			return err
+		}
+		if first >= last {
+			continue
+		}
+
+		if err = readPartition(ctx, kafkaURL, topic, partition.ID, first, last, handle); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// TailTopic reads the messages produced to the topic from now on, with one reader per partition which does not
+// belong to any consumer group, so nothing is replayed nor committed. It returns once ctx is done or a partition fails.
+func TailTopic(ctx context.Context, kafkaURL []string, topic string, handle func(m kafka.Message)) error {
+	conn, err := kafka.DialContext(ctx, "tcp", kafkaURL[0])
+	if err != nil {
+		return err
+	}
+	partitions, err := conn.ReadPartitions(topic)
+	conn.Close()
+	if err != nil {
+		return err
+	}
+
+	// A failing partition stops the others, so the caller can tail the whole topic again
+	tailCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(partitions))
+	for _, partition := range partitions {
+		go func(partition int) {
+			errs <- tailPartition(tailCtx, kafkaURL, topic, partition, handle)
+		}(partition.ID)
+	}
+
+	for range partitions {
+		if partitionErr := <-errs; partitionErr != nil && err == nil {
+			err = partitionErr
+			cancel()
+		}
+	}
+	return err
+}
+
+func tailPartition(ctx context.Context, kafkaURL []string, topic string, partition int, handle func(m kafka.Message)) error {
+	r := NewKafkaPartitionReader(kafkaURL, topic, partition)
+	defer r.Close()
+
+	if err := r.SetOffset(kafka.LastOffset); err != nil {
+		return err
+	}
+
+	for {
+		m, err := r.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		handle(m)
+	}
+}
+
+func readPartitionOffsets(ctx context.Context, addr, topic string, partition int) (int64, int64, error) {
+	conn, err := kafka.DialLeader(ctx, "tcp", addr, topic, partition)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+
+	return conn.ReadOffsets()
+}
+
+func readPartition(ctx context.Context, kafkaURL []string, topic string, partition int, first, last int64, handle func(m kafka.Message) error) error {
+	r := NewKafkaPartitionReader(kafkaURL, topic, partition)
+	defer r.Close()
+
+	if err := r.SetOffset(first); err != nil {
+		return err
+	}
+
+	for {
+		m, err := r.ReadMessage(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err = handle(m); err != nil {
+			return err
+		}
+
+		if m.Offset >= last-1 {
+			return nil
+		}
+	}
+}
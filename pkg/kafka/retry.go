@@ -0,0 +1,114 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"github.com/avast/retry-go"
+	"time"
+)
+
+// ErrPoisonMessage marks the messages which can never be processed, e.g. a value which can not be decoded.
+// They are moved to the dead letter queue without being retried.
+var ErrPoisonMessage = errors.New("poison message")
+
+// ErrorClass tells how a worker handles the failure of a message
+type ErrorClass int
+
+const (
+	// Retryable failures are transient, e.g. a database or broker outage, the message is retried
+	Retryable ErrorClass = iota
+	// BusinessFailure is a failure retrying can not fix, e.g. an insufficient inventory, it is replied at once
+	BusinessFailure
+	// PoisonMessage is a message which can never be processed, it is moved to the dead letter queue
+	PoisonMessage
+)
+
+// Classify returns the class of err, business lists the errors of the worker which are business failures
+func Classify(err error, business ...error) ErrorClass {
+	if errors.Is(err, ErrPoisonMessage) {
+		return PoisonMessage
+	}
+
+	for _, target := range business {
+		if errors.Is(err, target) {
+			return BusinessFailure
+		}
+	}
+
+	return Retryable
+}
+
+// DefaultRetryPolicy is used for the fields a service leaves empty in its configuration
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:     10,
+	InitialDelay: 200 * time.Millisecond,
+	MaxDelay:     10 * time.Second,
+	MaxJitter:    200 * time.Millisecond,
+}
+
+// RetryPolicy retries the retryable failures with an exponential backoff: the delay starts at InitialDelay
+// and doubles after each attempt up to MaxDelay. A random jitter up to MaxJitter is added to the delay
+// so the workers retrying at the same time spread their attempts.
+type RetryPolicy struct {
+	Attempts     uint
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	MaxJitter    time.Duration
+}
+
+// RetryConfig is the retry policy of the consumed topics, Topics overrides the Default policy for some topics
+type RetryConfig struct {
+	Default RetryPolicy
+	Topics  map[string]RetryPolicy
+}
+
+// Policy returns the retry policy of topic
+func (c RetryConfig) Policy(topic string) RetryPolicy {
+	return c.Topics[topic].orElse(c.Default).orElse(DefaultRetryPolicy)
+}
+
+// orElse fills the empty fields of p with the ones of fallback
+func (p RetryPolicy) orElse(fallback RetryPolicy) RetryPolicy {
+	if p.Attempts == 0 {
+		p.Attempts = fallback.Attempts
+	}
+	if p.InitialDelay == 0 {
+		p.InitialDelay = fallback.InitialDelay
+	}
+	if p.MaxDelay == 0 {
+		p.MaxDelay = fallback.MaxDelay
+	}
+	if p.MaxJitter == 0 {
+		p.MaxJitter = fallback.MaxJitter
+	}
+	return p
+}
+
+// Do calls fn until it succeeds, fails with an error which is not retryable or the attempts are exhausted.
+// It returns the number of attempts made with the last error.
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) (uint, error) {
+	var attempts uint
+
+	delayType := retry.BackOffDelay
+	if p.MaxJitter > 0 {
+		delayType = retry.CombineDelay(retry.BackOffDelay, retry.RandomDelay)
+	}
+
+	err := retry.Do(func() error {
+		attempts++
+		return fn()
+	},
+		retry.Attempts(p.Attempts),
+		retry.Delay(p.InitialDelay),
+		retry.MaxDelay(p.MaxDelay),
+		retry.MaxJitter(p.MaxJitter),
+		retry.DelayType(delayType),
+		retry.RetryIf(func(err error) bool {
+			return Classify(err) == Retryable
+		}),
+		retry.LastErrorOnly(true),
+		retry.Context(ctx),
+	)
+
+	return attempts, err
+}
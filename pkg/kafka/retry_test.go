@@ -0,0 +1,76 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pb "github.com/scul0405/saga-orchestration/proto"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	errInsufficientInventory := errors.New("insufficient inventory")
+
+	require.Equal(t, Retryable, Classify(errors.New("connection refused"), errInsufficientInventory))
+	require.Equal(t, BusinessFailure, Classify(fmt.Errorf("update inventory: %w", errInsufficientInventory), errInsufficientInventory))
+	require.Equal(t, PoisonMessage, Classify(fmt.Errorf("%w: unknown handler", ErrPoisonMessage), errInsufficientInventory))
+
+	err := Decode(kafka.Message{Value: []byte("{")}, &pb.CreatePurchaseResponse{})
+	require.Equal(t, PoisonMessage, Classify(err))
+}
+
+func TestRetryConfigPolicy(t *testing.T) {
+	t.Parallel()
+
+	cfg := RetryConfig{
+		Default: RetryPolicy{Attempts: 5, InitialDelay: time.Second},
+		Topics: map[string]RetryPolicy{
+			"rollback-order": {Attempts: 20},
+		},
+	}
+
+	require.Equal(t, RetryPolicy{
+		Attempts:     5,
+		InitialDelay: time.Second,
+		MaxDelay:     DefaultRetryPolicy.MaxDelay,
+		MaxJitter:    DefaultRetryPolicy.MaxJitter,
+	}, cfg.Policy("create-order"))
+
+	require.Equal(t, uint(20), cfg.Policy("rollback-order").Attempts)
+	require.Equal(t, time.Second, cfg.Policy("rollback-order").InitialDelay)
+
+	require.Equal(t, DefaultRetryPolicy, RetryConfig{}.Policy("create-order"))
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	policy := RetryPolicy{Attempts: 4, InitialDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond, MaxJitter: time.Millisecond}
+
+	calls := 0
+	attempts, err := policy.Do(ctx, func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint(3), attempts)
+
+	errTimeout := errors.New("timeout")
+	attempts, err = policy.Do(ctx, func() error { return errTimeout })
+	require.ErrorIs(t, err, errTimeout)
+	require.Equal(t, uint(4), attempts)
+
+	// poison messages are not retried
+	attempts, err = policy.Do(ctx, func() error { return fmt.Errorf("%w: unknown handler", ErrPoisonMessage) })
+	require.ErrorIs(t, err, ErrPoisonMessage)
+	require.Equal(t, uint(1), attempts)
+}
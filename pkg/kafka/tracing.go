@@ -0,0 +1,102 @@
+package kafka
+
+import (
+	"context"
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/scul0405/saga-orchestration/pkg/kafka"
+
+// headerCarrier reads and writes the trace context in the headers of a message
+type headerCarrier struct {
+	headers *[]kafka.Header
+}
+
+func (c headerCarrier) Get(key string) string {
+	for _, header := range *c.headers {
+		if header.Key == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+func (c headerCarrier) Set(key, value string) {
+	for i, header := range *c.headers {
+		if header.Key == key {
+			(*c.headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.headers = append(*c.headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, len(*c.headers))
+	for i, header := range *c.headers {
+		keys[i] = header.Key
+	}
+	return keys
+}
+
+// InjectTraceContext writes the trace context of ctx into the headers
+func InjectTraceContext(ctx context.Context, headers *[]kafka.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier{headers: headers})
+}
+
+// ExtractTraceContext returns ctx with the trace context carried by the headers of m
+func ExtractTraceContext(ctx context.Context, m kafka.Message) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, headerCarrier{headers: &m.Headers})
+}
+
+func hasTraceContext(m *kafka.Message) bool {
+	return trace.SpanContextFromContext(ExtractTraceContext(context.Background(), *m)).IsValid()
+}
+
+// StartConsumerSpan starts the span of the processing of m by the worker,
+// as a child of the span which published the message
+func StartConsumerSpan(ctx context.Context, m kafka.Message, worker string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ExtractTraceContext(ctx, m), worker,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingDestinationName(m.Topic),
+			semconv.MessagingKafkaDestinationPartition(m.Partition),
+			semconv.MessagingKafkaMessageOffset(int(m.Offset)),
+			semconv.MessagingKafkaMessageKey(string(m.Key)),
+		),
+	)
+}
+
+func startProducerSpan(ctx context.Context, msgs []kafka.Message) (context.Context, trace.Span) {
+	topics := make([]string, 0, 1)
+	seen := make(map[string]bool)
+	for _, m := range msgs {
+		if !seen[m.Topic] {
+			seen[m.Topic] = true
+			topics = append(topics, m.Topic)
+		}
+	}
+
+	return otel.Tracer(tracerName).Start(ctx, "Kafka.PublishMessage",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			attribute.StringSlice("messaging.destination.names", topics),
+			semconv.MessagingBatchMessageCount(len(msgs)),
+		),
+	)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
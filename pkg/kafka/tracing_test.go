@@ -0,0 +1,42 @@
+package kafka
+
+import (
+	"context"
+	"github.com/scul0405/saga-orchestration/pkg/tracing"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+func TestTraceContextPropagation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := tracing.NewTracerProviderWith("test_service", 1, sdktrace.WithSyncer(exporter))
+	defer func() {
+		_ = tp.Shutdown(context.Background())
+	}()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "CreatePurchase")
+
+	m := kafka.Message{Topic: "purchase", Key: []byte("1")}
+	require.False(t, hasTraceContext(&m))
+
+	InjectTraceContext(ctx, &m.Headers)
+	require.True(t, hasTraceContext(&m))
+	parent.End()
+
+	_, span := StartConsumerSpan(context.Background(), m, "Orchestrator.StartSagaWorker")
+	span.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	consumer := spans[1]
+	require.Equal(t, "Orchestrator.StartSagaWorker", consumer.Name)
+	require.Equal(t, trace.SpanKindConsumer, consumer.SpanKind)
+	require.Equal(t, parent.SpanContext().TraceID(), consumer.SpanContext.TraceID())
+	require.Equal(t, parent.SpanContext().SpanID(), consumer.Parent.SpanID())
+}